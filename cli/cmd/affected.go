@@ -0,0 +1,176 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/glennsarti/sentinel-parser/features"
+	"github.com/glennsarti/sentinel-parser/filetypes"
+	"github.com/glennsarti/sentinel-utils/lib/compatibility"
+	"github.com/glennsarti/sentinel-utils/lib/dependencies"
+	cwalker "github.com/glennsarti/sentinel-utils/lib/walkers/sentinel_config"
+	"github.com/spf13/cobra"
+)
+
+type affectedOutput struct {
+	Policies []string `json:"policies"`
+	Tests    []string `json:"tests"`
+}
+
+var affectedCmd = &cobra.Command{
+	Use:   "affected [changed paths...]",
+	Short: "List the policies and tests affected by changed files",
+	Long: `Computes which policies and test cases are affected by a list of changed files, by following the dependencies in the Sentinel configuration.
+If no paths are given then they are read from standard input, one per line, for example from "git diff --name-only".
+Relative paths are relative to the root of the git repository which contains the policy set, or the current working directory outside of a repository.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cmdUi := NewCommandUi(cmd)
+
+		if affectedFormat != "text" && affectedFormat != "json" {
+			cmdUi.Error(fmt.Sprintf("Invalid output format %s.", affectedFormat))
+			os.Exit(1)
+		}
+
+		fsys, rootPath := openRootFileSystem(cmdUi)
+		rootPath, err := filepath.Abs(rootPath)
+		if err != nil {
+			cmdUi.Error(err.Error())
+			os.Exit(1)
+		}
+		actualSentinelVersion, _ := validSentinelVersion(cmdUi, fsys, rootPath, affectedSentinelVersion)
+
+		changed := args
+		if len(changed) == 0 {
+			scanner := bufio.NewScanner(cmd.InOrStdin())
+			for scanner.Scan() {
+				if line := strings.TrimSpace(scanner.Text()); line != "" {
+					changed = append(changed, line)
+				}
+			}
+			if err := scanner.Err(); err != nil {
+				cmdUi.Error(fmt.Sprintf("Failed to read changed paths: %s", err))
+				os.Exit(1)
+			}
+		}
+
+		// Output paths relative to the policy set
+		baseDir := rootPath
+		if i, err := fsys.Stat(rootPath); err == nil && !i.IsDir() {
+			baseDir = filepath.Dir(rootPath)
+		}
+
+		changedBase, err := changedPathsBaseDir(baseDir)
+		if err != nil {
+			cmdUi.Error(err.Error())
+			os.Exit(1)
+		}
+		for idx, item := range changed {
+			if !filepath.IsAbs(item) {
+				item = filepath.Join(changedBase, item)
+			}
+			changed[idx] = filepath.Clean(item)
+			if rel, err := filepath.Rel(baseDir, changed[idx]); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+				cmdUi.Warn(fmt.Sprintf("The changed path %s is not in the policy set %s", changed[idx], baseDir))
+			}
+		}
+
+		pf := newParsingFactory(fsys)
+		walker := cwalker.NewSentinelConfigWalker(fsys, rootPath, actualSentinelVersion, pf)
+		if walker == nil {
+			cmdUi.Error("Failed to create walker")
+			os.Exit(1)
+		}
+
+		ctx, cancel := commandContext(0)
+		graph, err := dependencies.Build(ctx, walker, pf)
+		cancel()
+		if err != nil {
+			cmdUi.Error(err.Error())
+			os.Exit(1)
+		}
+
+		result := affectedOutput{
+			Policies: make([]string, 0),
+			Tests:    make([]string, 0),
+		}
+		for _, node := range graph.Affected(changed) {
+			p := node.Path
+			if rel, err := filepath.Rel(baseDir, node.Path); err == nil {
+				p = rel
+			}
+			switch node.Type {
+			case filetypes.PolicyFileType:
+				result.Policies = append(result.Policies, p)
+			case filetypes.ConfigTestFileType:
+				result.Tests = append(result.Tests, p)
+			}
+		}
+
+		if affectedFormat == "json" {
+			content, err := json.MarshalIndent(result, "", "  ")
+			if err != nil {
+				cmdUi.Error(err.Error())
+				os.Exit(1)
+			}
+			cmdUi.Output(string(content))
+		} else {
+			for _, p := range result.Policies {
+				cmdUi.Output(p)
+			}
+			for _, p := range result.Tests {
+				cmdUi.Output(p)
+			}
+		}
+		os.Exit(0)
+	},
+}
+
+var affectedSentinelVersion string
+var affectedFormat string
+var affectedBaseDir string
+
+// The directory which relative changed paths are relative to. Paths from git are relative
+// to the root of the repository, so that is used unless a directory is given.
+func changedPathsBaseDir(policySetDir string) (string, error) {
+	if affectedBaseDir != "" {
+		return filepath.Abs(affectedBaseDir)
+	}
+	for dir := policySetDir; ; dir = filepath.Dir(dir) {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return dir, nil
+		}
+		if filepath.Dir(dir) == dir {
+			break
+		}
+	}
+	return os.Getwd()
+}
+
+func init() {
+	rootCmd.AddCommand(affectedCmd)
+
+	affectedCmd.Flags().StringVarP(&affectedSentinelVersion, "sentinel-version", "s",
+		features.LatestSentinelVersion,
+		fmt.Sprintf("The Sentinel version to use when parsing. Default is the latest version (%s). "+
+			"Use \"auto\" to read the version from a %s file", features.SentinelVersions[0], compatibility.VersionFileName),
+	)
+
+	affectedCmd.Flags().StringVarP(&usePath, "path", "p",
+		"",
+		"The path to the policy set. Default is the current working directory",
+	)
+
+	affectedCmd.Flags().StringVarP(&affectedFormat, "format", "f",
+		"text",
+		"The output format. Either text (newline-separated paths) or json",
+	)
+
+	affectedCmd.Flags().StringVar(&affectedBaseDir, "base-dir",
+		"",
+		"The directory which relative changed paths are relative to. Default is the root of the git repository",
+	)
+}
@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/glennsarti/sentinel-utils/cli/ui"
+	"github.com/glennsarti/sentinel-utils/lib/lintcache"
+	"github.com/spf13/cobra"
+)
+
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manage the lint result cache",
+	Long:  `Manage the lint result cache which is created by "lint --cache-dir".`,
+}
+
+var cacheStatsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show the number and size of the cached lint results",
+	Run: func(cmd *cobra.Command, args []string) {
+		cmdUi := NewCommandUi(cmd)
+		cache := openLintCache(cmdUi, cacheDir)
+
+		stats, err := cache.Stats()
+		if err != nil {
+			cmdUi.Error(fmt.Sprintf("Failed to read the cache: %s", err))
+			os.Exit(1)
+		}
+		cmdUi.Output(fmt.Sprintf("Cache directory: %s", stats.Dir))
+		cmdUi.Output(fmt.Sprintf("Entries: %d", stats.Entries))
+		cmdUi.Output(fmt.Sprintf("Size: %d bytes", stats.Size))
+		os.Exit(0)
+	},
+}
+
+var cacheCleanCmd = &cobra.Command{
+	Use:   "clean",
+	Short: "Remove all of the cached lint results",
+	Run: func(cmd *cobra.Command, args []string) {
+		cmdUi := NewCommandUi(cmd)
+		cache := openLintCache(cmdUi, cacheDir)
+
+		removed, err := cache.Clean()
+		if err != nil {
+			cmdUi.Error(fmt.Sprintf("Failed to clean the cache: %s", err))
+			os.Exit(1)
+		}
+		cmdUi.Output(fmt.Sprintf("Removed %d entries from %s", removed, cacheDir))
+		os.Exit(0)
+	},
+}
+
+var cacheDir string
+
+// Opens the lint result cache. Exits on failure.
+func openLintCache(cmdUi ui.Ui, dir string) *lintcache.DiskCache {
+	if dir == "" {
+		cmdUi.Error("A cache directory is required.")
+		os.Exit(1)
+	}
+	cache, err := lintcache.NewDiskCache(dir)
+	if err != nil {
+		cmdUi.Error(err.Error())
+		os.Exit(1)
+	}
+	return cache
+}
+
+func init() {
+	rootCmd.AddCommand(cacheCmd)
+	cacheCmd.AddCommand(cacheStatsCmd)
+	cacheCmd.AddCommand(cacheCleanCmd)
+
+	cacheCmd.PersistentFlags().StringVar(&cacheDir, "cache-dir",
+		"",
+		"The directory containing the lint result cache",
+	)
+}
@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/glennsarti/sentinel-parser/features"
+	"github.com/glennsarti/sentinel-utils/cli/ui"
+	"github.com/glennsarti/sentinel-utils/lib/compatibility"
+	"github.com/glennsarti/sentinel-utils/lib/filesystem"
+	defaultfs "github.com/glennsarti/sentinel-utils/lib/filesystem/os"
+	"github.com/glennsarti/sentinel-utils/lib/parsing"
+	cachingParsing "github.com/glennsarti/sentinel-utils/lib/parsing/caching"
+	defaultParsing "github.com/glennsarti/sentinel-utils/lib/parsing/default"
+)
+
+// Opens the file system at the root path for the policies. Exits on failure.
+func openRootFileSystem(cmdUi ui.Ui) (filesystem.FS, string) {
+	// Validate the root path for the policies
+	rootPath := usePath
+	if rootPath == "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			cmdUi.Error(err.Error())
+			os.Exit(1)
+		}
+		rootPath = wd
+	}
+	fsys, err := defaultfs.NewOSFileSystem(rootPath)
+	if err != nil {
+		cmdUi.Error(fmt.Sprintf("Failed to open file system: %s", err))
+		os.Exit(1)
+	}
+	if _, err := fsys.Stat(rootPath); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			cmdUi.Error(fmt.Sprintf("The path %s does not exist", rootPath))
+		} else {
+			cmdUi.Error(fmt.Sprintf("Could not read the path %s: %s", rootPath, err))
+		}
+		os.Exit(1)
+	}
+
+	return fsys, rootPath
+}
+
+// Creates the parsing factory for a command. Files are parsed more than once during
+// a run, so the results are cached.
+func newParsingFactory(fsys filesystem.FS) parsing.Factory {
+	return cachingParsing.NewCachingParsingFactory(fsys, defaultParsing.NewDefaultParsingFactory(fsys))
+}
+
+// Validates the requested Sentinel version, or detects it from the policy set when it is
+// "auto". Exits on failure. The source describes where a detected version came from, and is
+// empty otherwise.
+func validSentinelVersion(cmdUi ui.Ui, fsys filesystem.FS, rootPath, requested string) (string, string) {
+	if requested == compatibility.AutoVersion {
+		detected, err := compatibility.DetectVersion(fsys, rootPath)
+		if err != nil {
+			cmdUi.Error(fmt.Sprintf("Could not detect the sentinel version: %s", err))
+			os.Exit(1)
+		}
+		if detected.Source == "" {
+			return detected.Version, fmt.Sprintf("the latest version, as there is no %s file", compatibility.VersionFileName)
+		}
+		return detected.Version, fmt.Sprintf("from %s", detected.Source)
+	}
+
+	if ok, val := features.ValidateSentinelVersion(requested); ok {
+		return val, ""
+	}
+	cmdUi.Error(fmt.Sprintf("Invalid sentinel version %s.", requested))
+	os.Exit(1)
+	return "", ""
+}
+
+// Creates the context for a command, which is cancelled on Ctrl-C or when the timeout
+// passes. A zero timeout means no timeout.
+func commandContext(timeout time.Duration) (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	if timeout <= 0 {
+		return ctx, stop
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	return ctx, func() {
+		cancel()
+		stop()
+	}
+}
@@ -0,0 +1,333 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/glennsarti/sentinel-parser/features"
+	"github.com/glennsarti/sentinel-utils/cli/ui"
+	"github.com/glennsarti/sentinel-utils/lib/compatibility"
+	"github.com/glennsarti/sentinel-utils/lib/configuration"
+	"github.com/glennsarti/sentinel-utils/lib/filesystem"
+	cwalker "github.com/glennsarti/sentinel-utils/lib/walkers/sentinel_config"
+	"github.com/spf13/cobra"
+)
+
+const (
+	configFormatHCL  = "hcl"
+	configFormatJSON = "json"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Work with the Sentinel configuration of a policy set",
+}
+
+var configResolveCmd = &cobra.Command{
+	Use:   "resolve",
+	Short: "Show the configuration after the override files are applied",
+	Long: `Applies every override file to the Sentinel configuration file, in the same order as Sentinel, and shows the effective configuration.
+Use --annotate to show the file and line each block and attribute came from.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cmdUi := NewCommandUi(cmd)
+
+		if configFormat != configFormatHCL && configFormat != configFormatJSON {
+			cmdUi.Error(fmt.Sprintf("The format %q is not supported. Use %s or %s.", configFormat, configFormatHCL, configFormatJSON))
+			os.Exit(1)
+		}
+
+		fsys, rootPath := openRootFileSystem(cmdUi)
+		sv, _ := validSentinelVersion(cmdUi, fsys, rootPath, configSentinelVersion)
+
+		pf := newParsingFactory(fsys)
+		walker := cwalker.NewSentinelConfigWalker(fsys, rootPath, sv, pf)
+		if walker == nil {
+			cmdUi.Error("Failed to create walker")
+			os.Exit(1)
+		}
+
+		ctx, cancel := commandContext(0)
+		resolved, err := configuration.Resolve(ctx, walker, pf)
+		cancel()
+		if err != nil {
+			cmdUi.Error(err.Error())
+			os.Exit(1)
+		}
+		for _, d := range resolved.Diagnostics {
+			cmdUi.Warn(d.Error())
+		}
+
+		out, err := writeConfiguration(resolved, configFormat, configuration.WriteOptions{
+			Annotate:   configAnnotate,
+			BaseDir:    filepath.Dir(resolved.PrimaryPath),
+			FileSystem: fsys,
+		})
+		if err != nil {
+			cmdUi.Error(fmt.Sprintf("Failed to write the configuration: %s", err))
+			os.Exit(1)
+		}
+		cmdUi.Output(out)
+		os.Exit(0)
+	},
+}
+
+var configExplainCmd = &cobra.Command{
+	Use:   "explain <name>",
+	Short: "Show which files set a block or attribute in the configuration",
+	Long: `Shows which file set a block or attribute after the override files are applied, and the values it replaced.
+Names are the block type and labels followed by the attribute, for example policy.<name>.enforcement_level or import.plugin.<name>.source.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		cmdUi := NewCommandUi(cmd)
+
+		fsys, rootPath := openRootFileSystem(cmdUi)
+		sv, _ := validSentinelVersion(cmdUi, fsys, rootPath, configSentinelVersion)
+
+		pf := newParsingFactory(fsys)
+		walker := cwalker.NewSentinelConfigWalker(fsys, rootPath, sv, pf)
+		if walker == nil {
+			cmdUi.Error("Failed to create walker")
+			os.Exit(1)
+		}
+
+		ctx, cancel := commandContext(0)
+		resolved, err := configuration.Resolve(ctx, walker, pf)
+		cancel()
+		if err != nil {
+			cmdUi.Error(err.Error())
+			os.Exit(1)
+		}
+
+		origin, ok := resolved.Provenance.Lookup(args[0])
+		if !ok {
+			cmdUi.Error(fmt.Sprintf("There is no block or attribute named %q in the configuration", args[0]))
+			os.Exit(1)
+		}
+
+		baseDir := filepath.Dir(resolved.PrimaryPath)
+		effective := origin.Effective()
+		if origin.IsBlock {
+			cmdUi.Output(origin.Key)
+			cmdUi.Output(fmt.Sprintf("  Defined in %s", configuration.Location(origin.Definitions[0].Range, baseDir)))
+			for _, def := range origin.Definitions[1:] {
+				cmdUi.Output(fmt.Sprintf("  Overridden in %s", configuration.Location(def.Range, baseDir)))
+			}
+			os.Exit(0)
+		}
+
+		cmdUi.Output(fmt.Sprintf("%s = %s", origin.Key, configuration.FormatValue(effective.Value)))
+		cmdUi.Output(fmt.Sprintf("  Set in %s", configuration.Location(effective.Range, baseDir)))
+		if replaced := origin.Replaced(); len(replaced) > 0 {
+			cmdUi.Output("  Replaced:")
+			for _, def := range replaced {
+				cmdUi.Output(fmt.Sprintf("    %s from %s", configuration.FormatValue(def.Value), configuration.Location(def.Range, baseDir)))
+			}
+		}
+		os.Exit(0)
+	},
+}
+
+var configConvertCmd = &cobra.Command{
+	Use:   "convert [file ...]",
+	Short: "Convert configuration files between the HCL and JSON syntax",
+	Long: `Converts Sentinel configuration, override and test files between the HCL and JSON syntax. Each converted file is written next to the original, with the new extension, and the original is kept. Comments can not be converted.
+Without any files, every configuration file in the policy set which uses the other syntax is converted.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cmdUi := NewCommandUi(cmd)
+
+		from := ""
+		switch convertTo {
+		case configuration.JSONSyntax:
+			from = configuration.HCLSyntax
+		case configuration.HCLSyntax:
+			from = configuration.JSONSyntax
+		default:
+			cmdUi.Error(fmt.Sprintf("The syntax to convert to must be set with --to, as %s or %s.", configuration.HCLSyntax, configuration.JSONSyntax))
+			os.Exit(1)
+		}
+
+		fsys, rootPath := openRootFileSystem(cmdUi)
+		sv, _ := validSentinelVersion(cmdUi, fsys, rootPath, configSentinelVersion)
+
+		files := make([]string, len(args))
+		for idx, arg := range args {
+			files[idx] = arg
+			if !filepath.IsAbs(arg) {
+				files[idx] = fsys.PathJoin(rootPath, arg)
+			}
+		}
+		if len(files) == 0 {
+			found, err := configuration.FindConfigFiles(fsys, rootPath, from)
+			if err != nil {
+				cmdUi.Error(fmt.Sprintf("Failed to find the configuration files: %s", err))
+				os.Exit(1)
+			}
+			if len(found) == 0 {
+				cmdUi.Info(fmt.Sprintf("No %s configuration files were found", from))
+				os.Exit(0)
+			}
+			files = found
+		}
+
+		exitCode := 0
+		for _, file := range files {
+			if err := convertConfigFile(cmdUi, fsys, file, sv); err != nil {
+				cmdUi.Error(fmt.Sprintf("Failed to convert %s: %s", file, err))
+				exitCode = 1
+			}
+		}
+		os.Exit(exitCode)
+	},
+}
+
+func convertConfigFile(cmdUi ui.Ui, fsys filesystem.FS, file, sv string) error {
+	src, err := fsys.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	converted, err := configuration.Convert(file, src, convertTo, sv)
+	if err != nil {
+		return err
+	}
+
+	target := configuration.ConvertedPath(file, convertTo)
+	if convertDryRun {
+		cmdUi.Output(fmt.Sprintf("# %s", target))
+		cmdUi.Output(strings.TrimSuffix(string(converted), "\n"))
+		return nil
+	}
+	if _, err := os.Stat(target); err == nil {
+		return fmt.Errorf("%s already exists", target)
+	}
+	info, err := os.Stat(file)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(target, converted, info.Mode().Perm()); err != nil {
+		return err
+	}
+	cmdUi.Info(fmt.Sprintf("Converted %s to %s", file, target))
+	return nil
+}
+
+var configGenerateCmd = &cobra.Command{
+	Use:   "generate",
+	Short: "Generate a Sentinel configuration file for a directory of policies",
+	Long: `Creates a sentinel.hcl file for the Sentinel files in a directory which does not have a configuration yet.
+Files which are imported by other files are added as modules, and everything else is added as a policy with the default enforcement level.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cmdUi := NewCommandUi(cmd)
+
+		fsys, rootPath := openRootFileSystem(cmdUi)
+		sv, _ := validSentinelVersion(cmdUi, fsys, rootPath, configSentinelVersion)
+
+		target := fsys.PathJoin(rootPath, "sentinel.hcl")
+		if !generateDryRun {
+			for _, name := range []string{"sentinel.hcl", "sentinel.json"} {
+				if _, err := fsys.Stat(fsys.PathJoin(rootPath, name)); err == nil {
+					cmdUi.Error(fmt.Sprintf("The policy set already has a configuration file %s", fsys.PathJoin(rootPath, name)))
+					os.Exit(1)
+				}
+			}
+		}
+
+		ctx, cancel := commandContext(0)
+		generated, err := configuration.Generate(ctx, fsys, rootPath, newParsingFactory(fsys), configuration.GenerateOptions{
+			SentinelVersion:  sv,
+			EnforcementLevel: generateEnforcementLevel,
+		})
+		cancel()
+		if err != nil {
+			cmdUi.Error(err.Error())
+			os.Exit(1)
+		}
+		for _, warning := range generated.Warnings {
+			cmdUi.Warn(warning)
+		}
+
+		if generateDryRun {
+			cmdUi.Output(strings.TrimSuffix(string(generated.Content), "\n"))
+			os.Exit(0)
+		}
+		if err := os.WriteFile(target, generated.Content, 0o644); err != nil {
+			cmdUi.Error(fmt.Sprintf("Failed to write %s: %s", target, err))
+			os.Exit(1)
+		}
+		cmdUi.Info(fmt.Sprintf("Generated %s with %d policies and %d modules", target, len(generated.Policies), len(generated.Modules)))
+		os.Exit(0)
+	},
+}
+
+// Writes the configuration in the requested format, without the trailing new line
+func writeConfiguration(resolved *configuration.Resolved, format string, opts configuration.WriteOptions) (string, error) {
+	var out bytes.Buffer
+	var err error
+	if format == configFormatJSON {
+		err = configuration.WriteJSON(&out, resolved.File, opts)
+	} else {
+		err = configuration.WriteHCL(&out, resolved.File, opts)
+	}
+	return strings.TrimSuffix(out.String(), "\n"), err
+}
+
+var configSentinelVersion string
+var configFormat string
+var configAnnotate bool
+var convertTo string
+var convertDryRun bool
+var generateEnforcementLevel string
+var generateDryRun bool
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configResolveCmd)
+	configCmd.AddCommand(configExplainCmd)
+	configCmd.AddCommand(configConvertCmd)
+	configCmd.AddCommand(configGenerateCmd)
+
+	for _, cmd := range []*cobra.Command{configResolveCmd, configExplainCmd, configConvertCmd, configGenerateCmd} {
+		cmd.Flags().StringVarP(&configSentinelVersion, "sentinel-version", "s",
+			compatibility.AutoVersion,
+			fmt.Sprintf("The Sentinel version to use. Default is to read it from a %s file, or use the latest version (%s)",
+				compatibility.VersionFileName, features.SentinelVersions[0]),
+		)
+
+		cmd.Flags().StringVarP(&usePath, "path", "p",
+			"",
+			"The path to the policy set. Default is the current working directory",
+		)
+	}
+
+	configResolveCmd.Flags().StringVar(&configFormat, "format",
+		configFormatHCL,
+		"The format to write the configuration in, hcl or json",
+	)
+
+	configResolveCmd.Flags().BoolVar(&configAnnotate, "annotate",
+		false,
+		"Show the file and line each block and attribute came from",
+	)
+
+	configConvertCmd.Flags().StringVar(&convertTo, "to",
+		"",
+		"The syntax to convert to, hcl or json",
+	)
+
+	configConvertCmd.Flags().BoolVar(&convertDryRun, "dry-run",
+		false,
+		"Show the converted files instead of writing them",
+	)
+
+	configGenerateCmd.Flags().StringVar(&generateEnforcementLevel, "enforcement-level",
+		configuration.DefaultEnforcementLevel,
+		fmt.Sprintf("The enforcement level of the policies, one of %s", strings.Join(configuration.EnforcementLevels, ", ")),
+	)
+
+	configGenerateCmd.Flags().BoolVar(&generateDryRun, "dry-run",
+		false,
+		"Show the configuration instead of writing it",
+	)
+}
@@ -1,16 +1,15 @@
 package cmd
 
 import (
-	"errors"
 	"fmt"
-	"io/fs"
 	"os"
+	"time"
 
 	slint "github.com/glennsarti/sentinel-lint/lint"
 	"github.com/glennsarti/sentinel-parser/features"
-	defaultfs "github.com/glennsarti/sentinel-utils/lib/filesystem/os"
+	"github.com/glennsarti/sentinel-utils/lib/compatibility"
+	"github.com/glennsarti/sentinel-utils/lib/lintcache"
 	"github.com/glennsarti/sentinel-utils/lib/linting"
-	parsing "github.com/glennsarti/sentinel-utils/lib/parsing/default"
 	cwalker "github.com/glennsarti/sentinel-utils/lib/walkers/sentinel_config"
 	"github.com/spf13/cobra"
 )
@@ -24,71 +23,144 @@ var lintCmd = &cobra.Command{
 
 		exitCode := 0
 
-		// Validate the root path for the policies
-		rootPath := usePath
-		if rootPath == "" {
-			wd, err := os.Getwd()
+		fsys, rootPath := openRootFileSystem(cmdUi)
+
+		// Validate the sentinel version, which may be more than one
+		var lintVersions []string
+		var versionSource string
+		if isVersionMatrix(sentinelVersion) {
+			versions, err := compatibility.ParseVersions(sentinelVersion)
 			if err != nil {
-				cmdUi.Error(err.Error())
+				cmdUi.Error(fmt.Sprintf("Invalid sentinel versions %s: %s", sentinelVersion, err))
 				os.Exit(1)
 			}
-			rootPath = wd
+			lintVersions = versions
+		} else {
+			var ver string
+			ver, versionSource = validSentinelVersion(cmdUi, fsys, rootPath, sentinelVersion)
+			lintVersions = []string{ver}
 		}
-		fsys, err := defaultfs.NewOSFileSystem(rootPath)
-		if err != nil {
-			cmdUi.Error(fmt.Sprintf("Failed to open file system: %s", err))
-			os.Exit(1)
+
+		pf := newParsingFactory(fsys)
+		walkerOpts := make([]cwalker.WalkerOption, 0)
+		if lintContinueOnError {
+			walkerOpts = append(walkerOpts, cwalker.WithContinueOnError())
 		}
-		if _, err := fsys.Stat(rootPath); err != nil {
-			if errors.Is(err, fs.ErrNotExist) {
-				cmdUi.Error(fmt.Sprintf("The path %s does not exist", rootPath))
-			} else {
-				cmdUi.Error(fmt.Sprintf("Could not read the path %s: %s", rootPath, err))
+		if lintFileTimeout > 0 {
+			walkerOpts = append(walkerOpts, cwalker.WithFileTimeout(lintFileTimeout))
+		}
+		newWalker := func(ver string) cwalker.Walker {
+			walker := cwalker.NewSentinelConfigWalker(fsys, rootPath, ver, pf, walkerOpts...)
+			if walker != nil && lintOrphans {
+				walker = cwalker.NewOrphanedFileWalker(walker, pf)
 			}
-			os.Exit(1)
+			return walker
 		}
 
-		// Validate the sentinel version
-		actualSentinelVersion := ""
-		if ok, val := features.ValidateSentinelVersion(sentinelVersion); ok {
-			actualSentinelVersion = val
-		} else {
-			cmdUi.Error(fmt.Sprintf("Invalid sentinel version %s.", sentinelVersion))
+		if lintJobs < 1 {
+			cmdUi.Error(fmt.Sprintf("Invalid number of jobs %d.", lintJobs))
 			os.Exit(1)
 		}
-		cmdUi.Info(fmt.Sprintf("Using Sentinel version %s", actualSentinelVersion))
 
-		pf := parsing.NewDefaultParsingFactory(fsys)
-		walker := cwalker.NewSentinelConfigWalker(fsys, rootPath, actualSentinelVersion, pf)
+		lintOpts := []linting.LintOption{linting.WithJobs(lintJobs)}
+		var cache *lintcache.DiskCache
+		if cacheDir != "" {
+			cache = openLintCache(cmdUi, cacheDir)
+			lintOpts = append(lintOpts, linting.WithResultCache(cache))
+		}
+
+		if len(lintVersions) > 1 {
+			ctx, cancel := commandContext(lintTimeout)
+			exitCode = lintVersionMatrix(ctx, cmdUi, lintVersions, newWalker, pf, lintOpts)
+			cancel()
+			os.Exit(exitCode)
+		}
+
+		if versionSource != "" {
+			cmdUi.Info(fmt.Sprintf("Using Sentinel version %s (%s)", lintVersions[0], versionSource))
+			lintOpts = append(lintOpts, linting.WithRequiredVersionCheck(versionSource))
+		} else {
+			cmdUi.Info(fmt.Sprintf("Using Sentinel version %s", lintVersions[0]))
+		}
+		walker := newWalker(lintVersions[0])
 		if walker == nil {
 			cmdUi.Error("Failed to create walker")
 			os.Exit(1)
 		}
 
-		err = linting.Lint(walker, pf, func(lintFile slint.File, issues slint.Issues) {
+		ctx, cancel := commandContext(lintTimeout)
+		err := linting.Lint(ctx, walker, pf, func(lintFile slint.File, issues slint.Issues) {
 			cmdUi.OutputLintIssues(lintFile, issues, fsys)
 			if len(issues) > 0 {
 				exitCode = 1
 			}
-		})
+		}, lintOpts...)
+		cancel()
+		if cache != nil {
+			hits, misses := cache.Usage()
+			cmdUi.Info(fmt.Sprintf("Used cached results for %d of %d files", hits, hits+misses))
+		}
 		if err != nil {
-			cmdUi.Error(err.Error())
+			if cwalker.IsCancelled(err) {
+				cmdUi.Error(fmt.Sprintf("Linting stopped before all files were linted: %s", err))
+			} else {
+				cmdUi.Error(err.Error())
+			}
 			os.Exit(1)
 		}
 		os.Exit(exitCode)
 	},
 }
 
+var lintOrphans bool
+var lintContinueOnError bool
+var lintTimeout time.Duration
+var lintFileTimeout time.Duration
+var lintJobs int
+
 func init() {
 	rootCmd.AddCommand(lintCmd)
 
 	lintCmd.Flags().StringVarP(&sentinelVersion, "sentinel-version", "s",
 		features.LatestSentinelVersion,
-		fmt.Sprintf("The Sentinel version to use when linting. Default is the latest version (%s)", features.SentinelVersions[0]),
+		fmt.Sprintf("The Sentinel version to use when linting. Default is the latest version (%s). "+
+			"Use \"auto\" to read the version, or a version constraint, from a %s file in the policy set or its parents. "+
+			"Use \"all\", a comma separated list, or a range such as v0.19.0..v0.22.0 to compare versions",
+			features.SentinelVersions[0], compatibility.VersionFileName),
 	)
 
 	lintCmd.Flags().StringVarP(&usePath, "path", "p",
 		"",
 		"The path to search for files to lint. Default is the current working directory",
 	)
+
+	lintCmd.Flags().BoolVar(&lintOrphans, "orphans",
+		false,
+		"Report Sentinel, HCL and JSON files which are not referenced by the Sentinel configuration",
+	)
+
+	lintCmd.Flags().BoolVar(&lintContinueOnError, "continue-on-error",
+		false,
+		"Report configuration and file errors as issues, and continue linting whatever could be recovered",
+	)
+
+	lintCmd.Flags().DurationVar(&lintTimeout, "timeout",
+		0,
+		"The maximum time to spend linting, for example 30s. Default is no limit",
+	)
+
+	lintCmd.Flags().DurationVar(&lintFileTimeout, "file-timeout",
+		0,
+		"The maximum time to spend linting each file, for example 5s. Default is no limit",
+	)
+
+	lintCmd.Flags().StringVar(&cacheDir, "cache-dir",
+		"",
+		"The directory to cache lint results in, so that unchanged files are not linted again. Default is no cache",
+	)
+
+	lintCmd.Flags().IntVarP(&lintJobs, "jobs", "j",
+		1,
+		"The number of files to parse and lint at the same time",
+	)
 }
@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	slint "github.com/glennsarti/sentinel-lint/lint"
+	"github.com/glennsarti/sentinel-utils/cli/ui"
+	"github.com/glennsarti/sentinel-utils/lib/compatibility"
+	"github.com/glennsarti/sentinel-utils/lib/linting"
+	"github.com/glennsarti/sentinel-utils/lib/parsing"
+	cwalker "github.com/glennsarti/sentinel-utils/lib/walkers/sentinel_config"
+)
+
+// Whether the version flag selects more than one Sentinel version
+func isVersionMatrix(spec string) bool {
+	return spec == compatibility.AllVersions || strings.Contains(spec, ",") || strings.Contains(spec, "..")
+}
+
+// Lints with each version and outputs the compatibility matrix. Returns the exit code.
+func lintVersionMatrix(
+	ctx context.Context,
+	cmdUi ui.Ui,
+	versions []string,
+	newWalker compatibility.WalkerFactory,
+	pf parsing.Factory,
+	lintOpts []linting.LintOption,
+) int {
+	cmdUi.Info(fmt.Sprintf("Using %d Sentinel versions from %s to %s", len(versions), versions[len(versions)-1], versions[0]))
+
+	m, err := compatibility.Build(ctx, versions, newWalker, pf, lintOpts...)
+	if err != nil {
+		if cwalker.IsCancelled(err) {
+			cmdUi.Error(fmt.Sprintf("Linting stopped before all versions were linted: %s", err))
+		} else {
+			cmdUi.Error(err.Error())
+		}
+		return 1
+	}
+
+	exitCode := 0
+	cmdUi.Output("Results by Sentinel version:")
+	for _, r := range m.Results {
+		if r.Err != nil {
+			cmdUi.Output(fmt.Sprintf("  ❌ %s: Could not be linted: %s", r.Version, r.Err))
+		} else if r.Works() {
+			cmdUi.Output(fmt.Sprintf("  ✅ %s: %d errors, %d warnings", r.Version, r.Errors, r.Warnings))
+		} else {
+			cmdUi.Output(fmt.Sprintf("  ❌ %s: %d errors, %d warnings", r.Version, r.Errors, r.Warnings))
+		}
+		if !r.Works() {
+			exitCode = 1
+		}
+	}
+	cmdUi.Output("")
+
+	linted := m.Versions()
+	specific := m.VersionSpecificIssues()
+	if len(specific) == 0 {
+		cmdUi.Output("All versions found the same issues.")
+	} else {
+		cmdUi.Output("Issues found with only some versions:")
+		for _, i := range specific {
+			cmdUi.Output(fmt.Sprintf("  %s %s: %s (%s)\n      on %s\n      with %s",
+				matrixSeverityPrefix(i.Issue.Severity),
+				i.Issue.RuleId,
+				i.Issue.Summary,
+				matrixIssueLocation(i),
+				i.Path,
+				compatibility.FormatVersions(i.Versions, linted),
+			))
+		}
+	}
+	cmdUi.Output("")
+
+	if minimum := m.MinimumVersion(); minimum != "" {
+		cmdUi.Output(fmt.Sprintf("Minimum Sentinel version: %s", minimum))
+	} else {
+		cmdUi.Output(fmt.Sprintf("The policy set has errors with Sentinel %s", linted[0]))
+	}
+
+	return exitCode
+}
+
+func matrixSeverityPrefix(sev slint.SeverityLevel) string {
+	switch sev {
+	case slint.Error:
+		return "❌"
+	case slint.Warning:
+		return "⚠ "
+	case slint.Information:
+		return "ℹ "
+	default:
+		return "❓"
+	}
+}
+
+func matrixIssueLocation(i *compatibility.Issue) string {
+	if i.Issue.Range == nil {
+		return "no location"
+	}
+	return fmt.Sprintf("line %d", i.Issue.Range.Start.Line+1)
+}
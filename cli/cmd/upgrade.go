@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/glennsarti/sentinel-parser/features"
+	"github.com/glennsarti/sentinel-utils/lib/compatibility"
+	"github.com/glennsarti/sentinel-utils/lib/upgrade"
+	cwalker "github.com/glennsarti/sentinel-utils/lib/walkers/sentinel_config"
+	"github.com/spf13/cobra"
+)
+
+var upgradeCmd = &cobra.Command{
+	Use:   "upgrade",
+	Short: "Rewrite a policy set for a newer Sentinel version",
+	Long: `Rewrites the Sentinel configuration files in a policy set which use syntax that is deprecated or changed in a newer Sentinel version, for example version 1 module and plugin import blocks.
+Anything which can not be rewritten safely is reported, with a hint for migrating it by hand.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cmdUi := NewCommandUi(cmd)
+
+		if upgradeTo == "" {
+			cmdUi.Error("The Sentinel version to upgrade to must be set with --to.")
+			os.Exit(1)
+		}
+
+		fsys, rootPath := openRootFileSystem(cmdUi)
+		// Without a version file, the version being upgraded to is the only one known to apply
+		requested := upgradeSentinelVersion
+		if requested == compatibility.AutoVersion {
+			if detected, err := compatibility.DetectVersion(fsys, rootPath); err == nil && detected.Source == "" {
+				_, requested = features.ValidateSentinelVersion(upgradeTo)
+				cmdUi.Info(fmt.Sprintf("There is no %s file, so the policy set is assumed to use Sentinel %s. Use --sentinel-version to set it.",
+					compatibility.VersionFileName, requested))
+			}
+		}
+		fromVersion, versionSource := validSentinelVersion(cmdUi, fsys, rootPath, requested)
+
+		pf := newParsingFactory(fsys)
+		walker := cwalker.NewSentinelConfigWalker(fsys, rootPath, fromVersion, pf)
+		if walker == nil {
+			cmdUi.Error("Failed to create walker")
+			os.Exit(1)
+		}
+
+		ctx, cancel := commandContext(0)
+		result, err := upgrade.Upgrade(ctx, walker, pf, upgradeTo)
+		cancel()
+		if err != nil {
+			cmdUi.Error(err.Error())
+			os.Exit(1)
+		}
+		cmdUi.Info(fmt.Sprintf("Upgrading from Sentinel version %s to %s", result.From, result.To))
+
+		exitCode := 0
+		changed := 0
+		for _, fr := range result.Files {
+			if fr.Changed() {
+				changed++
+				if upgradeDryRun {
+					cmdUi.Output(fr.Diff())
+				} else if err := writeUpgradedFile(fr); err != nil {
+					cmdUi.Error(fmt.Sprintf("Failed to write %s: %s", fr.Path(), err))
+					exitCode = 1
+				} else {
+					cmdUi.Info(fmt.Sprintf("Upgraded %s", fr.Path()))
+				}
+			}
+			if len(fr.Issues) > 0 {
+				cmdUi.OutputLintIssues(fr, fr.Issues, fsys)
+				exitCode = 1
+			}
+		}
+
+		if changed == 0 {
+			cmdUi.Info("No files needed to be upgraded")
+		}
+		if versionSource != "" && upgradeSentinelVersion == compatibility.AutoVersion {
+			cmdUi.Info(fmt.Sprintf("The Sentinel version was detected (%s). Update the %s file to use %s.",
+				versionSource, compatibility.VersionFileName, result.To))
+		}
+		os.Exit(exitCode)
+	},
+}
+
+// Keeps the permissions of the original file
+func writeUpgradedFile(fr upgrade.FileResult) error {
+	info, err := os.Stat(fr.Path())
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(fr.Path(), fr.Upgraded, info.Mode().Perm())
+}
+
+var upgradeSentinelVersion string
+var upgradeTo string
+var upgradeDryRun bool
+
+func init() {
+	rootCmd.AddCommand(upgradeCmd)
+
+	upgradeCmd.Flags().StringVarP(&upgradeSentinelVersion, "sentinel-version", "s",
+		compatibility.AutoVersion,
+		fmt.Sprintf("The Sentinel version the policy set currently uses. Default is to read it from a %s file, or use the version being upgraded to",
+			compatibility.VersionFileName),
+	)
+
+	upgradeCmd.Flags().StringVar(&upgradeTo, "to",
+		"",
+		"The Sentinel version to upgrade to",
+	)
+
+	upgradeCmd.Flags().StringVarP(&usePath, "path", "p",
+		"",
+		"The path to the policy set. Default is the current working directory",
+	)
+
+	upgradeCmd.Flags().BoolVar(&upgradeDryRun, "dry-run",
+		false,
+		"Show the changes as a diff instead of writing them",
+	)
+}
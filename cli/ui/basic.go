@@ -93,7 +93,11 @@ func (u *BasicUi) OutputLintIssues(lintFile slint.File, issues slint.Issues, fsy
 					related.Range.Start.Line+1,
 				))
 
-				for idx, l := range u.getLines(content, related.Range.Start.Line, related.Range.End.Line) {
+				relatedContent := content
+				if related.Range.Filename != lintFile.Path() {
+					relatedContent, _ = fsys.ReadFile(related.Range.Filename)
+				}
+				for idx, l := range u.getLines(relatedContent, related.Range.Start.Line, related.Range.End.Line) {
 					line := l
 
 					// TODO This only copes with single lines
@@ -131,6 +135,8 @@ func (u *BasicUi) getLines(content []byte, startLine, endLine int) []string {
 
 // Base 0 from,to columns
 func (u *BasicUi) underline(line string, from, to int) string {
+	to = min(to, len(line))
+	from = min(from, to)
 	return line[:from] +
 		"\x1B[4m" +
 		line[from:to] +
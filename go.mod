@@ -7,7 +7,10 @@ require (
 	github.com/glennsarti/sentinel-lint v0.0.4
 	github.com/glennsarti/sentinel-parser v0.0.3
 	github.com/google/go-cmp v0.7.0
+	github.com/hashicorp/hcl/v2 v2.24.0
 	github.com/spf13/cobra v1.10.2
+	github.com/zclconf/go-cty v1.17.0
+	golang.org/x/mod v0.32.0
 	golang.org/x/tools v0.41.0
 )
 
@@ -15,12 +18,9 @@ require (
 	github.com/agext/levenshtein v1.2.3 // indirect
 	github.com/apparentlymart/go-textseg/v15 v15.0.0 // indirect
 	github.com/creachadair/mds v0.25.13 // indirect
-	github.com/hashicorp/hcl/v2 v2.24.0 // indirect
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
 	github.com/mitchellh/go-wordwrap v1.0.1 // indirect
 	github.com/spf13/pflag v1.0.9 // indirect
-	github.com/zclconf/go-cty v1.17.0 // indirect
-	golang.org/x/mod v0.32.0 // indirect
 	golang.org/x/sync v0.19.0 // indirect
 	golang.org/x/text v0.25.0 // indirect
 )
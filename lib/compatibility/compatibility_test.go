@@ -0,0 +1,119 @@
+package compatibility
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/glennsarti/sentinel-parser/features"
+	"golang.org/x/tools/txtar"
+
+	"github.com/glennsarti/sentinel-utils/lib/internal/txtar_fs"
+	parsing "github.com/glennsarti/sentinel-utils/lib/parsing/default"
+	cwalker "github.com/glennsarti/sentinel-utils/lib/walkers/sentinel_config"
+)
+
+func TestParseVersions(t *testing.T) {
+	for _, testcase := range []struct {
+		spec     string
+		expected []string
+	}{
+		{spec: "v0.20.0", expected: []string{"v0.20.0"}},
+		{spec: "v0.19.0, v0.20.0", expected: []string{"v0.20.0", "v0.19.0"}},
+		{spec: "v0.20.0..v0.21.1", expected: []string{"v0.21.1", "v0.21.0", "v0.20.0"}},
+		{spec: "v0.30.0..", expected: []string{"v0.40.0", "v0.30.0"}},
+		{spec: "v0.18.0..v0.18.1, v0.40.0", expected: []string{"v0.40.0", "v0.18.1", "v0.18.0"}},
+	} {
+		actual, err := ParseVersions(testcase.spec)
+		if err != nil {
+			t.Errorf("%q: unexpected error %s", testcase.spec, err)
+			continue
+		}
+		// Only the versions which exist can be selected
+		expected := make([]string, 0)
+		for _, ver := range testcase.expected {
+			if slices.Contains(features.SentinelVersions, ver) {
+				expected = append(expected, ver)
+			}
+		}
+		if !slices.Equal(expected, actual) {
+			t.Errorf("%q: expected %v but got %v", testcase.spec, expected, actual)
+		}
+	}
+
+	if actual, _ := ParseVersions(AllVersions); !slices.Equal(actual, features.SentinelVersions) {
+		t.Errorf("expected all versions but got %v", actual)
+	}
+
+	for _, spec := range []string{"", "v99.0.0", "v0.20.0..abc", "v99.0.0.."} {
+		if _, err := ParseVersions(spec); err == nil {
+			t.Errorf("%q: expected an error", spec)
+		}
+	}
+}
+
+func TestFormatVersions(t *testing.T) {
+	linted := []string{"v0.22.0", "v0.21.0", "v0.20.0", "v0.19.0", "v0.18.0"}
+
+	for _, testcase := range []struct {
+		versions []string
+		expected string
+	}{
+		{versions: []string{}, expected: ""},
+		{versions: []string{"v0.21.0"}, expected: "v0.21.0"},
+		{versions: []string{"v0.22.0", "v0.21.0", "v0.20.0"}, expected: "v0.20.0..v0.22.0"},
+		{versions: []string{"v0.22.0", "v0.20.0", "v0.19.0"}, expected: "v0.22.0, v0.19.0..v0.20.0"},
+	} {
+		if actual := FormatVersions(testcase.versions, linted); actual != testcase.expected {
+			t.Errorf("%v: expected %q but got %q", testcase.versions, testcase.expected, actual)
+		}
+	}
+}
+
+func TestBuild(t *testing.T) {
+	// The first policy is only there so that the second is linted
+	fsys := txtar_fs.NewTxtarFileSystem(txtar.Parse([]byte(`
+-- sentinel.hcl --
+policy "first" {
+  source = "./first.sentinel"
+}
+
+policy "policy" {
+  source = "./policy.sentinel"
+}
+-- first.sentinel --
+main = rule { true }
+-- policy.sentinel --
+func is_true(value) {
+  return value is true
+}
+
+main = rule { is_true(true) }
+`)))
+	pf := parsing.NewDefaultParsingFactory(fsys)
+	versions := []string{"v0.21.0", "v0.20.0", "v0.19.0"}
+
+	m, err := Build(context.Background(), versions, func(sentinelVersion string) cwalker.Walker {
+		return cwalker.NewSentinelConfigWalker(fsys, "/", sentinelVersion, pf)
+	}, pf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !slices.Equal(m.Versions(), versions) {
+		t.Errorf("expected versions %v but got %v", versions, m.Versions())
+	}
+	// Function declarations were added in v0.20.0
+	if actual := m.MinimumVersion(); actual != "v0.20.0" {
+		t.Errorf("expected the minimum version to be v0.20.0 but got %q", actual)
+	}
+	specific := m.VersionSpecificIssues()
+	if len(specific) == 0 {
+		t.Fatal("expected issues which are only found with some versions")
+	}
+	for _, issue := range specific {
+		if actual := FormatVersions(issue.Versions, versions); actual != "v0.19.0" {
+			t.Errorf("expected %q to only be found with older versions but got %q", issue.Issue.Summary, actual)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package compatibility
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/glennsarti/sentinel-parser/features"
+	"golang.org/x/mod/semver"
+)
+
+type versionCondition struct {
+	operator string
+	version  string
+}
+
+// Longest first, so that ">=" is not read as ">"
+var constraintOperators = []string{"~>", ">=", "<=", "!=", ">", "<", "="}
+
+// ResolveConstraint returns the newest Sentinel version which matches the constraint. The
+// constraint is a version, or a comma separated list of conditions using the =, !=, >, >=,
+// <, <= and ~> operators. Versions can leave out the leading "v".
+func ResolveConstraint(constraint string) (string, error) {
+	conditions, err := parseConstraint(constraint)
+	if err != nil {
+		return "", err
+	}
+
+	for _, ver := range features.SentinelVersions {
+		if matchesConditions(ver, conditions) {
+			return ver, nil
+		}
+	}
+	return "", fmt.Errorf("no sentinel versions match %q", constraint)
+}
+
+func parseConstraint(constraint string) ([]versionCondition, error) {
+	if strings.TrimSpace(constraint) == "" {
+		return nil, fmt.Errorf("the version constraint is empty")
+	}
+
+	conditions := make([]versionCondition, 0)
+	for _, item := range strings.Split(constraint, ",") {
+		item = strings.TrimSpace(item)
+		c := versionCondition{operator: "="}
+		for _, op := range constraintOperators {
+			if strings.HasPrefix(item, op) {
+				c.operator = op
+				item = strings.TrimSpace(item[len(op):])
+				break
+			}
+		}
+
+		c.version = item
+		if !strings.HasPrefix(c.version, "v") {
+			c.version = "v" + c.version
+		}
+		if !semver.IsValid(c.version) {
+			return nil, fmt.Errorf("invalid version %q in the constraint %q", item, constraint)
+		}
+		conditions = append(conditions, c)
+	}
+	return conditions, nil
+}
+
+func matchesConditions(ver string, conditions []versionCondition) bool {
+	for _, c := range conditions {
+		if !matchesCondition(ver, c) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchesCondition(ver string, c versionCondition) bool {
+	compared := semver.Compare(ver, c.version)
+	switch c.operator {
+	case "!=":
+		return compared != 0
+	case ">":
+		return compared > 0
+	case ">=":
+		return compared >= 0
+	case "<":
+		return compared < 0
+	case "<=":
+		return compared <= 0
+	case "~>":
+		return compared >= 0 && semver.Compare(ver, pessimisticUpperBound(c.version)) < 0
+	default:
+		// A partial version matches all of the versions it covers, e.g. v0.24 matches v0.24.3
+		return compared == 0 || strings.HasPrefix(ver, c.version+".")
+	}
+}
+
+// Only the last part of the version can increase. "~> 0.24.1" allows up to, but not
+// including, v0.25.0 and "~> 0.24" allows up to v1.0.0.
+func pessimisticUpperBound(ver string) string {
+	parts := strings.Split(strings.TrimPrefix(semver.Canonical(ver), "v"), ".")
+	given := len(strings.Split(strings.TrimPrefix(ver, "v"), "."))
+
+	bumpIdx := 0
+	if given > 1 {
+		bumpIdx = given - 2
+	}
+	n, _ := strconv.Atoi(parts[bumpIdx])
+	parts[bumpIdx] = strconv.Itoa(n + 1)
+	for idx := bumpIdx + 1; idx < len(parts); idx++ {
+		parts[idx] = "0"
+	}
+	return "v" + strings.Join(parts, ".")
+}
@@ -0,0 +1,72 @@
+package compatibility
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/fs"
+	"strings"
+
+	"github.com/glennsarti/sentinel-parser/features"
+	"github.com/glennsarti/sentinel-utils/lib/filesystem"
+)
+
+// AutoVersion is the version specification which detects the version from the policy set
+const AutoVersion = "auto"
+
+// VersionFileName is the file which contains the Sentinel version, or a version constraint,
+// for a policy set
+const VersionFileName = ".sentinel-version"
+
+type DetectedVersion struct {
+	Version string
+	// The path of the version file, or empty if there was no version file and the
+	// latest version is used
+	Source string
+	// The content of the version file
+	Constraint string
+}
+
+// DetectVersion finds the Sentinel version for the policy set at the root. The version file is
+// searched for in the root directory and then its parents. The file contains either a version,
+// or a constraint such as ">= 0.20.0, < 0.30.0" or "~> 0.24", in which case the newest matching
+// version is used. Lines starting with # are ignored.
+func DetectVersion(fsys filesystem.FS, root string) (DetectedVersion, error) {
+	// When the root is a file, there is no version file next to it so its directory is searched next
+	dir := root
+	for {
+		versionPath := fsys.PathJoin(dir, VersionFileName)
+		if content, err := fs.ReadFile(fsys, versionPath); err == nil {
+			constraint := readVersionFile(content)
+			ver, err := ResolveConstraint(constraint)
+			if err != nil {
+				return DetectedVersion{}, fmt.Errorf("%s: %w", versionPath, err)
+			}
+			return DetectedVersion{
+				Version:    ver,
+				Source:     versionPath,
+				Constraint: constraint,
+			}, nil
+		}
+
+		parent := fsys.ParentPath(dir)
+		if parent == dir || parent == "." || parent == "" {
+			break
+		}
+		dir = parent
+	}
+
+	return DetectedVersion{Version: features.SentinelVersions[0]}, nil
+}
+
+// The first line which is not blank or a comment
+func readVersionFile(content []byte) string {
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			return line
+		}
+	}
+	return ""
+}
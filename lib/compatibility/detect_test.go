@@ -0,0 +1,79 @@
+package compatibility
+
+import (
+	"testing"
+
+	"github.com/glennsarti/sentinel-parser/features"
+	"golang.org/x/tools/txtar"
+
+	"github.com/glennsarti/sentinel-utils/lib/internal/txtar_fs"
+)
+
+func TestResolveConstraint(t *testing.T) {
+	for _, testcase := range []struct {
+		constraint string
+		expected   string
+	}{
+		{constraint: "v0.25.1", expected: "v0.25.1"},
+		{constraint: "0.25.1", expected: "v0.25.1"},
+		{constraint: "= 0.26", expected: "v0.26.3"},
+		{constraint: "~> 0.26.0", expected: "v0.26.3"},
+		{constraint: "~> 0.26", expected: "v0.40.0"},
+		{constraint: ">= 0.25.0, < 0.27.0", expected: "v0.26.3"},
+		{constraint: "<= 0.26.1", expected: "v0.26.1"},
+		{constraint: "> 0.29.0, != 0.40.0", expected: "v0.30.0"},
+	} {
+		actual, err := ResolveConstraint(testcase.constraint)
+		if err != nil {
+			t.Errorf("%q: unexpected error %s", testcase.constraint, err)
+		} else if actual != testcase.expected {
+			t.Errorf("%q: expected %s but got %s", testcase.constraint, testcase.expected, actual)
+		}
+	}
+
+	for _, constraint := range []string{"", "abc", ">= abc", "> 1.0.0", "v0.26.9"} {
+		if _, err := ResolveConstraint(constraint); err == nil {
+			t.Errorf("%q: expected an error", constraint)
+		}
+	}
+}
+
+func TestDetectVersion(t *testing.T) {
+	fsys := txtar_fs.NewTxtarFileSystem(txtar.Parse([]byte(`
+-- .sentinel-version --
+# The version used in production
+
+~> 0.26.0
+-- policies/sentinel.hcl --
+-- other/sentinel.hcl --
+-- other/.sentinel-version --
+v0.25.0
+`)))
+
+	for _, testcase := range []struct {
+		root     string
+		expected string
+		source   string
+	}{
+		{root: "/policies", expected: "v0.26.3", source: "/.sentinel-version"},
+		{root: "/policies/sentinel.hcl", expected: "v0.26.3", source: "/.sentinel-version"},
+		{root: "/other", expected: "v0.25.0", source: "/other/.sentinel-version"},
+	} {
+		actual, err := DetectVersion(fsys, testcase.root)
+		if err != nil {
+			t.Errorf("%s: unexpected error %s", testcase.root, err)
+			continue
+		}
+		if actual.Version != testcase.expected || actual.Source != testcase.source {
+			t.Errorf("%s: expected %s from %s but got %s from %s", testcase.root, testcase.expected, testcase.source, actual.Version, actual.Source)
+		}
+	}
+
+	// Without a version file, the latest version is used
+	empty := txtar_fs.NewTxtarFileSystem(txtar.Parse([]byte("-- sentinel.hcl --\n")))
+	if actual, err := DetectVersion(empty, "/"); err != nil {
+		t.Errorf("unexpected error %s", err)
+	} else if actual.Version != features.SentinelVersions[0] || actual.Source != "" {
+		t.Errorf("expected the latest version but got %s from %q", actual.Version, actual.Source)
+	}
+}
@@ -0,0 +1,173 @@
+package compatibility
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+
+	slint "github.com/glennsarti/sentinel-lint/lint"
+	"github.com/glennsarti/sentinel-utils/lib/linting"
+	"github.com/glennsarti/sentinel-utils/lib/parsing"
+	cwalker "github.com/glennsarti/sentinel-utils/lib/walkers/sentinel_config"
+)
+
+// WalkerFactory creates the walker for a Sentinel version
+type WalkerFactory func(sentinelVersion string) cwalker.Walker
+
+// Issue is a lint issue, and the Sentinel versions it was found with
+type Issue struct {
+	Path  string
+	Issue *slint.Issue
+	// Newest first
+	Versions []string
+}
+
+// VersionResult is the outcome of linting with a single Sentinel version
+type VersionResult struct {
+	Version  string
+	Errors   int
+	Warnings int
+	// Set if linting could not finish with this version
+	Err error
+}
+
+// Works returns whether the policy set had no errors with this version
+func (vr VersionResult) Works() bool {
+	return vr.Err == nil && vr.Errors == 0
+}
+
+// Matrix records which issues are found with which Sentinel versions
+type Matrix struct {
+	// The results for each version, newest first
+	Results []VersionResult
+	// Sorted by path, then position
+	Issues []*Issue
+}
+
+// Build lints the policy set once for each version. Linting which fails for a version is
+// recorded in its result. If the context is cancelled, the matrix for the versions which
+// were linted is returned with the error.
+func Build(
+	ctx context.Context,
+	versions []string,
+	newWalker WalkerFactory,
+	pf parsing.Factory,
+	opts ...linting.LintOption,
+) (*Matrix, error) {
+	m := &Matrix{
+		Results: make([]VersionResult, 0, len(versions)),
+		Issues:  make([]*Issue, 0),
+	}
+	issues := make(map[string]*Issue, 0)
+
+	for _, ver := range versions {
+		walker := newWalker(ver)
+		if walker == nil {
+			return m, fmt.Errorf("failed to create walker for sentinel version %s", ver)
+		}
+
+		result := VersionResult{Version: ver}
+		err := linting.Lint(ctx, walker, pf, func(lintFile slint.File, fileIssues slint.Issues) {
+			for _, issue := range fileIssues {
+				if issue == nil {
+					continue
+				}
+				switch issue.Severity {
+				case slint.Error:
+					result.Errors++
+				case slint.Warning:
+					result.Warnings++
+				}
+
+				key := issueKey(lintFile.Path(), issue)
+				if existing, ok := issues[key]; ok {
+					existing.Versions = append(existing.Versions, ver)
+					continue
+				}
+				i := &Issue{
+					Path:     lintFile.Path(),
+					Issue:    issue,
+					Versions: []string{ver},
+				}
+				issues[key] = i
+				m.Issues = append(m.Issues, i)
+			}
+		}, opts...)
+		if cwalker.IsCancelled(err) {
+			return m.sorted(), err
+		}
+		result.Err = err
+		m.Results = append(m.Results, result)
+	}
+
+	return m.sorted(), nil
+}
+
+// Versions returns the versions which were linted, newest first
+func (m *Matrix) Versions() []string {
+	result := make([]string, len(m.Results))
+	for idx, r := range m.Results {
+		result[idx] = r.Version
+	}
+	return result
+}
+
+// VersionSpecificIssues returns the issues which were not found with every version
+func (m *Matrix) VersionSpecificIssues() []*Issue {
+	result := make([]*Issue, 0)
+	for _, issue := range m.Issues {
+		if len(issue.Versions) != len(m.Results) {
+			result = append(result, issue)
+		}
+	}
+	return result
+}
+
+// MinimumVersion returns the oldest version which the policy set works with, where every
+// newer version which was linted also works. Returns an empty string if the newest version
+// does not work.
+func (m *Matrix) MinimumVersion() string {
+	minimum := ""
+	for _, r := range m.Results {
+		if !r.Works() {
+			break
+		}
+		minimum = r.Version
+	}
+	return minimum
+}
+
+func (m *Matrix) sorted() *Matrix {
+	slices.SortStableFunc(m.Issues, func(a, b *Issue) int {
+		if c := cmp.Compare(a.Path, b.Path); c != 0 {
+			return c
+		}
+		return compareRanges(a.Issue, b.Issue)
+	})
+	return m
+}
+
+// Issues are the same across versions if they are for the same rule in the same place
+func issueKey(path string, issue *slint.Issue) string {
+	key := fmt.Sprintf("%s|%s|%s", path, issue.RuleId, issue.Summary)
+	if issue.Range != nil {
+		key += "|" + issue.Range.ToString()
+	}
+	return key
+}
+
+func compareRanges(a, b *slint.Issue) int {
+	switch {
+	case a.Range == nil && b.Range == nil:
+		return 0
+	case a.Range == nil:
+		return -1
+	case b.Range == nil:
+		return 1
+	}
+	if c := cmp.Compare(a.Range.Start.Line, b.Range.Start.Line); c != 0 {
+		return c
+	}
+	return cmp.Compare(a.Range.Start.Column, b.Range.Start.Column)
+}
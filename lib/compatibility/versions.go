@@ -0,0 +1,116 @@
+package compatibility
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/glennsarti/sentinel-parser/features"
+	"golang.org/x/mod/semver"
+)
+
+// AllVersions is the version specification for every supported Sentinel version
+const AllVersions = "all"
+
+// ParseVersions resolves a version specification into Sentinel versions, newest first, in
+// the same order as features.SentinelVersions. The specification is either "all", or a comma
+// separated list of versions and inclusive ranges. Ranges are written as "v0.19.0..v0.22.0",
+// and either end can be left out, for example "v0.24.0..".
+func ParseVersions(spec string) ([]string, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == AllVersions {
+		return slices.Clone(features.SentinelVersions), nil
+	}
+
+	selected := make(map[string]struct{}, 0)
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		if from, to, isRange := strings.Cut(item, ".."); isRange {
+			versions, err := versionRange(strings.TrimSpace(from), strings.TrimSpace(to))
+			if err != nil {
+				return nil, err
+			}
+			for _, ver := range versions {
+				selected[ver] = struct{}{}
+			}
+			continue
+		}
+
+		ok, ver := features.ValidateSentinelVersion(item)
+		if !ok {
+			return nil, fmt.Errorf("invalid sentinel version %s", item)
+		}
+		selected[ver] = struct{}{}
+	}
+
+	result := make([]string, 0, len(selected))
+	for _, ver := range features.SentinelVersions {
+		if _, ok := selected[ver]; ok {
+			result = append(result, ver)
+		}
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("no sentinel versions match %q", spec)
+	}
+	return result, nil
+}
+
+func versionRange(from, to string) ([]string, error) {
+	if from != "" && !semver.IsValid(from) {
+		return nil, fmt.Errorf("invalid sentinel version %s", from)
+	}
+	if to != "" && !semver.IsValid(to) {
+		return nil, fmt.Errorf("invalid sentinel version %s", to)
+	}
+
+	result := make([]string, 0)
+	for _, ver := range features.SentinelVersions {
+		if from != "" && semver.Compare(ver, from) < 0 {
+			continue
+		}
+		if to != "" && semver.Compare(ver, to) > 0 {
+			continue
+		}
+		result = append(result, ver)
+	}
+	return result, nil
+}
+
+// FormatVersions describes a subset of the linted versions, joining consecutive versions
+// into ranges, for example "v0.24.0..v0.40.0, v0.19.0". Both lists are newest first.
+func FormatVersions(versions, linted []string) string {
+	included := make(map[string]struct{}, len(versions))
+	for _, ver := range versions {
+		included[ver] = struct{}{}
+	}
+
+	parts := make([]string, 0)
+	newest, oldest := "", ""
+	flush := func() {
+		switch {
+		case newest == "":
+		case newest == oldest:
+			parts = append(parts, newest)
+		default:
+			parts = append(parts, oldest+".."+newest)
+		}
+		newest, oldest = "", ""
+	}
+	for _, ver := range linted {
+		if _, ok := included[ver]; !ok {
+			flush()
+			continue
+		}
+		if newest == "" {
+			newest = ver
+		}
+		oldest = ver
+	}
+	flush()
+
+	return strings.Join(parts, ", ")
+}
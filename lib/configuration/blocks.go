@@ -0,0 +1,222 @@
+package configuration
+
+import (
+	"fmt"
+
+	"github.com/glennsarti/sentinel-parser/position"
+	"github.com/glennsarti/sentinel-utils/lib/internal/helpers"
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+
+	scast "github.com/glennsarti/sentinel-parser/sentinel_config/ast"
+)
+
+// block is a configuration block, independent of the syntax it is written in
+type block struct {
+	typeName   string
+	labels     []string
+	rng        *position.SourceRange
+	attributes []attribute
+	blocks     []*block
+}
+
+type attribute struct {
+	name  string
+	value cty.Value
+	rng   *position.SourceRange
+}
+
+// Attributes are only added if they were set, which is when they have a range
+func (b *block) add(name string, value cty.Value, rng *position.SourceRange) {
+	if rng == nil {
+		return
+	}
+	b.attributes = append(b.attributes, attribute{name: name, value: value, rng: rng})
+}
+
+// toBlocks converts the configuration into blocks, in the order they are written out. Values
+// which could not be converted are unknown, and the first error is returned with the blocks.
+func (w *writer) toBlocks(file *scast.File) ([]*block, error) {
+	w.err = nil
+	result := make([]*block, 0)
+	if file == nil {
+		return result, nil
+	}
+
+	if opts := file.SentinelOptions; opts != nil {
+		b := &block{typeName: "sentinel", rng: opts.SentinelOptionsRange}
+		features := make(map[string]cty.Value, len(opts.Features))
+		for _, feature := range opts.Features {
+			if feature == nil {
+				continue
+			}
+			features[feature.Name] = w.dynamicValue(feature.Value)
+		}
+		b.add("features", cty.ObjectVal(features), opts.FeaturesRange)
+		result = append(result, b)
+	}
+
+	for _, name := range helpers.SortedKeys(file.Globals) {
+		if g := file.Globals[name]; g != nil {
+			b := &block{typeName: "global", labels: []string{name}, rng: g.GlobalRange}
+			b.add("value", w.dynamicValue(g.Value), g.ValueRange)
+			result = append(result, b)
+		}
+	}
+
+	for _, name := range helpers.SortedKeys(file.Params) {
+		if p := file.Params[name]; p != nil {
+			b := &block{typeName: "param", labels: []string{name}, rng: p.ParameterRange}
+			b.add("value", w.dynamicValue(p.Value), p.ValueRange)
+			result = append(result, b)
+		}
+	}
+
+	for _, name := range helpers.SortedKeys(file.Imports) {
+		if imp := file.Imports[name]; imp != nil {
+			if b := w.importBlock(name, imp); b != nil {
+				result = append(result, b)
+			}
+		}
+	}
+
+	for _, name := range helpers.SortedKeys(file.Mocks) {
+		m := file.Mocks[name]
+		if m == nil {
+			continue
+		}
+		b := &block{typeName: "mock", labels: []string{name}, rng: m.MockRange}
+		b.add("data", w.parameters(m.Data), m.DataRange)
+		if m.Module != nil {
+			module := &block{typeName: "module", rng: m.Module.MockModuleRange}
+			module.add("source", cty.StringVal(m.Module.Source), m.Module.SourceRange)
+			b.blocks = append(b.blocks, module)
+		}
+		result = append(result, b)
+	}
+
+	for _, name := range helpers.SortedKeys(file.Policies) {
+		p := file.Policies[name]
+		if p == nil {
+			continue
+		}
+		b := &block{typeName: "policy", labels: []string{name}, rng: p.PolicyRange}
+		b.add("source", cty.StringVal(p.Source), p.SourceRange)
+		b.add("enforcement_level", cty.StringVal(p.EnforcementLevel), p.EnforcementLevelRange)
+		b.add("params", w.parameters(p.Params), p.ParamsRange)
+		result = append(result, b)
+	}
+
+	if t := file.Test; t != nil {
+		b := &block{typeName: "test", rng: t.TestRange}
+		rules := make(map[string]cty.Value, len(t.Rules))
+		for _, rule := range t.Rules {
+			if rule == nil {
+				continue
+			}
+			// The parser does not keep the values of test rules, so they are read from the source
+			value, err := w.sourceValue(rule.ValueRange)
+			if err != nil {
+				w.fail(fmt.Errorf("could not read the value of the test rule %q: %w", rule.Name, err))
+				value = cty.DynamicVal
+			}
+			rules[rule.Name] = value
+		}
+		b.add("rules", cty.ObjectVal(rules), t.RulesRange)
+		result = append(result, b)
+	}
+
+	return result, w.err
+}
+
+func (w *writer) importBlock(name string, imp scast.Import) *block {
+	switch actual := imp.(type) {
+	case *scast.V1ModuleImport:
+		b := &block{typeName: "module", labels: []string{name}, rng: actual.BlockRange}
+		b.add("source", cty.StringVal(actual.Source), actual.SourceRange)
+		return b
+
+	case *scast.V1PluginImport:
+		b := &block{typeName: "import", labels: []string{name}, rng: actual.BlockRange}
+		b.add("path", cty.StringVal(actual.Path), actual.PathRange)
+		b.add("args", stringsToCty(actual.Args), actual.ArgsRange)
+		b.add("env", stringsToCty(actual.Env), actual.EnvRange)
+		b.add("config", w.parameters(actual.Config), actual.ConfigRange)
+		return b
+
+	case *scast.V2ModuleImport:
+		b := &block{typeName: "import", labels: []string{scast.V2ImportKindModule, name}, rng: actual.BlockRange}
+		b.add("source", cty.StringVal(actual.Source), actual.SourceRange)
+		return b
+
+	case *scast.V2PluginImport:
+		b := &block{typeName: "import", labels: []string{scast.V2ImportKindPlugin, name}, rng: actual.BlockRange}
+		b.add("source", cty.StringVal(actual.Source), actual.SourceRange)
+		b.add("args", stringsToCty(actual.Args), actual.ArgsRange)
+		b.add("env", w.parameters(actual.Env), actual.EnvRange)
+		b.add("config", w.parameters(actual.Config), actual.ConfigRange)
+		return b
+
+	case *scast.V2StaticImport:
+		b := &block{typeName: "import", labels: []string{scast.V2ImportKindStatic, name}, rng: actual.BlockRange}
+		b.add("source", cty.StringVal(actual.Source), actual.SourceRange)
+		b.add("format", cty.StringVal(actual.Format), actual.FormatRange)
+		return b
+
+	default:
+		w.fail(fmt.Errorf("unknown import type %T", imp))
+		return nil
+	}
+}
+
+func dynamicToCty(dv *scast.DynamicValue) (cty.Value, error) {
+	if dv == nil {
+		return cty.NullVal(cty.DynamicPseudoType), nil
+	}
+	content, err := dv.MarshalJSON()
+	if err != nil {
+		return cty.NilVal, err
+	}
+	var sv ctyjson.SimpleJSONValue
+	if err := sv.UnmarshalJSON(content); err != nil {
+		return cty.NilVal, err
+	}
+	return sv.Value, nil
+}
+
+// Keeps the first error
+func (w *writer) fail(err error) {
+	if w.err == nil {
+		w.err = err
+	}
+}
+
+func (w *writer) dynamicValue(dv *scast.DynamicValue) cty.Value {
+	value, err := dynamicToCty(dv)
+	if err != nil {
+		w.fail(err)
+		return cty.DynamicVal
+	}
+	return value
+}
+
+func (w *writer) parameters(params map[string]*scast.Parameter) cty.Value {
+	values := make(map[string]cty.Value, len(params))
+	for name, p := range params {
+		if p != nil {
+			values[name] = w.dynamicValue(p.Value)
+		}
+	}
+	return cty.ObjectVal(values)
+}
+
+func stringsToCty(values []string) cty.Value {
+	if len(values) == 0 {
+		return cty.ListValEmpty(cty.String)
+	}
+	items := make([]cty.Value, len(values))
+	for idx, value := range values {
+		items[idx] = cty.StringVal(value)
+	}
+	return cty.ListVal(items)
+}
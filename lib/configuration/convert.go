@@ -0,0 +1,171 @@
+package configuration
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/glennsarti/sentinel-utils/lib/filesystem"
+	"github.com/glennsarti/sentinel-utils/lib/internal/helpers"
+
+	scast "github.com/glennsarti/sentinel-parser/sentinel_config/ast"
+	scparser "github.com/glennsarti/sentinel-parser/sentinel_config/parser"
+	cwalker "github.com/glennsarti/sentinel-utils/lib/walkers/sentinel_config"
+)
+
+// The syntaxes a configuration file can be written in
+const (
+	HCLSyntax  = "hcl"
+	JSONSyntax = "json"
+)
+
+// ConvertedPath is the path of the file once it is converted to the syntax
+func ConvertedPath(filePath, syntax string) string {
+	ext := path.Ext(filePath)
+	return strings.TrimSuffix(filePath, ext) + "." + syntax
+}
+
+// Convert converts a configuration file to the other syntax. Comments can not be converted.
+// The result is parsed again, and an error is returned if it is not the same configuration.
+func Convert(filePath string, src []byte, syntax, sentinelVersion string) ([]byte, error) {
+	if syntax != HCLSyntax && syntax != JSONSyntax {
+		return nil, fmt.Errorf("the syntax %q is not supported", syntax)
+	}
+
+	cfg, err := parseConfig(filePath, src, sentinelVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	if syntax == JSONSyntax {
+		// The JSON syntax is read using the version 1 import schema whenever an import
+		// block is present, so the kind label of version 2 imports is lost
+		for _, name := range helpers.SortedKeys(cfg.Imports) {
+			if imp := cfg.Imports[name]; imp != nil && imp.Schema() == scast.V2ImportSchema {
+				return nil, fmt.Errorf("the %s %q can not be written in the JSON syntax", imp.BlockType(), name)
+			}
+		}
+	}
+
+	wr := newWriter(WriteOptions{})
+	wr.sources[filePath] = src
+	var out bytes.Buffer
+	if syntax == JSONSyntax {
+		err = wr.writeJSON(&out, cfg)
+	} else {
+		err = wr.writeHCL(&out, cfg)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Check the converted file is the same configuration
+	convertedPath := ConvertedPath(filePath, syntax)
+	converted, err := parseConfig(convertedPath, out.Bytes(), sentinelVersion)
+	if err != nil {
+		return nil, fmt.Errorf("the converted file could not be parsed: %w", err)
+	}
+	check := newWriter(WriteOptions{})
+	check.sources[convertedPath] = out.Bytes()
+	if err := sameConfiguration(wr.flatten(cfg), check.flatten(converted)); err != nil {
+		return nil, fmt.Errorf("the converted file is not the same configuration: %w", err)
+	}
+
+	return out.Bytes(), nil
+}
+
+func parseConfig(filePath string, src []byte, sentinelVersion string) (*scast.File, error) {
+	cfg, diags, err := scparser.ParseFile(sentinelVersion, filePath, src)
+	if err != nil {
+		return nil, err
+	}
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	return cfg, nil
+}
+
+// Compares the keys and values, but not where they are defined
+func sameConfiguration(expected, actual []flatEntry) error {
+	values := make(map[string]flatEntry, len(actual))
+	for _, e := range actual {
+		values[e.key] = e
+	}
+
+	for _, e := range expected {
+		a, ok := values[e.key]
+		if !ok {
+			return fmt.Errorf("%s is missing", e.key)
+		}
+		delete(values, e.key)
+		if e.isBlock {
+			continue
+		}
+		if !e.value.IsWhollyKnown() || !a.value.IsWhollyKnown() || !e.value.RawEquals(a.value) {
+			return fmt.Errorf("%s is %s instead of %s", e.key, FormatValue(a.value), FormatValue(e.value))
+		}
+	}
+	for key := range values {
+		return fmt.Errorf("%s was added", key)
+	}
+	return nil
+}
+
+// FindConfigFiles finds the primary configuration, override and test files of a policy set
+// which use the syntax
+func FindConfigFiles(fsys filesystem.FS, root, syntax string) ([]string, error) {
+	ext := "." + syntax
+	result := make([]string, 0)
+
+	entries, err := fsys.ReadDir(root)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ext) {
+			continue
+		}
+		if entry.Name() == "sentinel"+ext || cwalker.IsOverrideFileName(entry.Name()) {
+			result = append(result, fsys.PathJoin(root, entry.Name()))
+		}
+	}
+
+	return findTestFiles(fsys, root, true, ext, result)
+}
+
+// Test files are in test/<policy name>/ directories
+func findTestFiles(fsys filesystem.FS, dir string, isRoot bool, ext string, result []string) ([]string, error) {
+	entries, err := fsys.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	if !isRoot {
+		// Directories with their own configuration are a different policy set
+		for _, entry := range entries {
+			if !entry.IsDir() && (entry.Name() == "sentinel.hcl" || entry.Name() == "sentinel.json") {
+				return result, nil
+			}
+		}
+	}
+
+	for _, entry := range entries {
+		// Ignore hidden files and directories e.g. .git
+		if strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		itemPath := fsys.PathJoin(dir, entry.Name())
+
+		if entry.IsDir() {
+			if result, err = findTestFiles(fsys, itemPath, false, ext, result); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if strings.HasSuffix(entry.Name(), ext) && fsys.BasePath(fsys.ParentPath(dir)) == "test" {
+			result = append(result, itemPath)
+		}
+	}
+	return result, nil
+}
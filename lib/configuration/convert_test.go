@@ -0,0 +1,122 @@
+package configuration
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/tools/txtar"
+
+	"github.com/glennsarti/sentinel-utils/lib/internal/txtar_fs"
+)
+
+func TestConvertRoundTrip(t *testing.T) {
+	src := `module "helpers" {
+  source = "./helpers.sentinel"
+}
+
+import "tfplan" {
+  path   = "/plugins/tfplan"
+  args   = ["-v"]
+  env    = ["MODE=strict"]
+  config = { retries = 3 }
+}
+
+policy "policy" {
+  source            = "./policy.sentinel"
+  enforcement_level = "advisory"
+  params = {
+    names = ["a", "b"]
+  }
+}
+
+test {
+  rules = {
+    main = false
+  }
+}
+`
+	converted, err := Convert("/sentinel.hcl", []byte(src), JSONSyntax, "v0.21.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(converted), `"path": "/plugins/tfplan"`) {
+		t.Errorf("expected the plugin path in the JSON but got %s", converted)
+	}
+
+	back, err := Convert("/sentinel.json", converted, HCLSyntax, "v0.21.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `module "helpers" {
+  source = "./helpers.sentinel"
+}
+
+import "tfplan" {
+  path = "/plugins/tfplan"
+  args = ["-v"]
+  env  = ["MODE=strict"]
+  config = {
+    retries = 3
+  }
+}
+
+policy "policy" {
+  source            = "./policy.sentinel"
+  enforcement_level = "advisory"
+  params = {
+    names = ["a", "b"]
+  }
+}
+
+test {
+  rules = {
+    main = false
+  }
+}
+`
+	if diff := cmp.Diff(expected, string(back)); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestConvertV2ImportsToJSON(t *testing.T) {
+	src := `import "plugin" "time2" {
+  source = "./plugins/time"
+}
+`
+	_, err := Convert("/sentinel.hcl", []byte(src), JSONSyntax, "v0.40.0")
+	if err == nil || !strings.Contains(err.Error(), `"time2" can not be written in the JSON syntax`) {
+		t.Errorf("expected an error for the version 2 import but got %v", err)
+	}
+
+	converted, err := Convert("/sentinel.hcl", []byte(src), HCLSyntax, "v0.40.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(src, string(converted)); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestFindConfigFiles(t *testing.T) {
+	fsys := txtar_fs.NewTxtarFileSystem(txtar.Parse([]byte(`
+-- sentinel.hcl --
+-- a_override.hcl --
+-- a_override.json --
+-- other.hcl --
+-- policies/test/policy/pass.hcl --
+-- policies/test/policy/fail.json --
+-- nested/sentinel.hcl --
+-- nested/test/policy/pass.hcl --
+`)))
+
+	actual, err := FindConfigFiles(fsys, "/", HCLSyntax)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"/a_override.hcl", "/sentinel.hcl", "/policies/test/policy/pass.hcl"}
+	if diff := cmp.Diff(expected, actual); diff != "" {
+		t.Error(diff)
+	}
+}
@@ -0,0 +1,223 @@
+package configuration
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/glennsarti/sentinel-parser/features"
+	"github.com/glennsarti/sentinel-parser/filetypes"
+	"github.com/glennsarti/sentinel-utils/lib/filesystem"
+	"github.com/glennsarti/sentinel-utils/lib/internal/helpers"
+	"github.com/glennsarti/sentinel-utils/lib/parsing"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+
+	scparser "github.com/glennsarti/sentinel-parser/sentinel_config/parser"
+)
+
+// DefaultEnforcementLevel is used for generated policies
+const DefaultEnforcementLevel = "advisory"
+
+// EnforcementLevels are the valid policy enforcement levels
+var EnforcementLevels = []string{"advisory", "soft-mandatory", "hard-mandatory"}
+
+// GenerateOptions changes how a configuration is generated
+type GenerateOptions struct {
+	SentinelVersion  string
+	EnforcementLevel string
+}
+
+// GeneratedFile is a Sentinel file found when generating a configuration
+type GeneratedFile struct {
+	Name string
+	Path string
+	// The test directory of a policy, if it has one
+	TestDir string
+}
+
+// Generated is a configuration generated from the Sentinel files in a directory
+type Generated struct {
+	Policies []GeneratedFile
+	Modules  []GeneratedFile
+	// Files which could not be added, and test directories without a policy
+	Warnings []string
+	Content  []byte
+}
+
+// Generate creates a configuration for the Sentinel files in the root directory. Files which
+// are imported by other files are modules, and everything else is a policy.
+func Generate(ctx context.Context, fsys filesystem.FS, root string, pf parsing.Factory, opts GenerateOptions) (*Generated, error) {
+	if opts.EnforcementLevel == "" {
+		opts.EnforcementLevel = DefaultEnforcementLevel
+	}
+	if !slices.Contains(EnforcementLevels, opts.EnforcementLevel) {
+		return nil, fmt.Errorf("the enforcement level %q is not valid. Use one of %s", opts.EnforcementLevel, strings.Join(EnforcementLevels, ", "))
+	}
+
+	result := &Generated{
+		Policies: make([]GeneratedFile, 0),
+		Modules:  make([]GeneratedFile, 0),
+		Warnings: make([]string, 0),
+	}
+
+	files, testDirs, err := findSentinelFiles(fsys, root, true, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// The names imported by each file
+	imported := make(map[string]struct{})
+	for _, filePath := range files {
+		parsed, _, err := pf.ParseSentinelFile(ctx, &filesystem.File{
+			Path: filePath,
+			Name: fsys.BasePath(filePath),
+			Type: filetypes.PolicyFileType,
+		}, opts.SentinelVersion)
+		if err != nil {
+			return nil, err
+		}
+		if parsed == nil {
+			continue
+		}
+		for _, imp := range parsed.Imports {
+			if imp != nil && imp.Name != nil {
+				// The literal value includes the surrounding quotes
+				name := strings.Trim(imp.Name.Value, `"`)
+				if !scparser.IsStdLibName(name) {
+					imported[name] = struct{}{}
+				}
+			}
+		}
+	}
+
+	names := make(map[string]string)
+	usedTestDirs := make(map[string]struct{})
+	for _, filePath := range files {
+		name := strings.TrimSuffix(fsys.BasePath(filePath), ".sentinel")
+		if other, ok := names[name]; ok {
+			result.Warnings = append(result.Warnings,
+				fmt.Sprintf("%s was not added because %s already uses the name %q", filePath, other, name))
+			continue
+		}
+		names[name] = filePath
+
+		if _, ok := imported[name]; ok {
+			result.Modules = append(result.Modules, GeneratedFile{Name: name, Path: filePath})
+			continue
+		}
+		policy := GeneratedFile{Name: name, Path: filePath}
+		testDir := fsys.PathJoin(fsys.ParentPath(filePath), "test", name)
+		if _, ok := testDirs[testDir]; ok {
+			policy.TestDir = testDir
+			usedTestDirs[testDir] = struct{}{}
+		}
+		result.Policies = append(result.Policies, policy)
+	}
+
+	for _, testDir := range helpers.SortedKeys(testDirs) {
+		if _, ok := usedTestDirs[testDir]; !ok {
+			result.Warnings = append(result.Warnings,
+				fmt.Sprintf("The test directory %s does not match the name of any policy", testDir))
+		}
+	}
+
+	result.Content = generateHCL(result, root, opts)
+	if _, err := parseConfig(fsys.PathJoin(root, "sentinel.hcl"), result.Content, opts.SentinelVersion); err != nil {
+		return nil, fmt.Errorf("the generated configuration could not be parsed: %w", err)
+	}
+	return result, nil
+}
+
+func generateHCL(g *Generated, root string, opts GenerateOptions) []byte {
+	out := hclwrite.NewEmptyFile()
+	body := out.Body()
+
+	v2Imports := features.SupportedVersion(opts.SentinelVersion, features.V2ImportBlockMinimumVersion)
+	for _, module := range g.Modules {
+		var b *hclwrite.Block
+		if v2Imports {
+			b = body.AppendNewBlock("import", []string{"module", module.Name})
+		} else {
+			b = body.AppendNewBlock("module", []string{module.Name})
+		}
+		b.Body().SetAttributeValue("source", cty.StringVal(relativeSource(root, module.Path)))
+		body.AppendNewline()
+	}
+
+	for _, policy := range g.Policies {
+		if policy.TestDir != "" {
+			body.AppendUnstructuredTokens(hclwrite.Tokens{
+				{Type: hclsyntax.TokenComment, Bytes: []byte("# Tests are in " + relativeSource(root, policy.TestDir) + "\n")},
+			})
+		}
+		b := body.AppendNewBlock("policy", []string{policy.Name})
+		b.Body().SetAttributeValue("source", cty.StringVal(relativeSource(root, policy.Path)))
+		b.Body().SetAttributeValue("enforcement_level", cty.StringVal(opts.EnforcementLevel))
+		body.AppendNewline()
+	}
+
+	content := hclwrite.Format(out.Bytes())
+	return []byte(strings.TrimRight(string(content), "\n") + "\n")
+}
+
+// The path relative to the root, as it is written in a configuration file
+func relativeSource(root, filePath string) string {
+	rel := strings.TrimPrefix(filePath, root)
+	rel = strings.TrimLeft(filepath.ToSlash(rel), "/")
+	return "./" + rel
+}
+
+// Finds the Sentinel files and test directories. Directories with their own configuration
+// are a different policy set, and test directories do not contain policies.
+func findSentinelFiles(fsys filesystem.FS, dir string, isRoot bool, files []string, testDirs map[string]struct{}) ([]string, map[string]struct{}, error) {
+	if testDirs == nil {
+		testDirs = make(map[string]struct{})
+	}
+	entries, err := fsys.ReadDir(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !isRoot {
+		for _, entry := range entries {
+			if !entry.IsDir() && (entry.Name() == "sentinel.hcl" || entry.Name() == "sentinel.json") {
+				return files, testDirs, nil
+			}
+		}
+	}
+
+	for _, entry := range entries {
+		// Ignore hidden files and directories e.g. .git
+		if strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		itemPath := fsys.PathJoin(dir, entry.Name())
+
+		if entry.IsDir() {
+			if entry.Name() == "test" {
+				testEntries, err := fsys.ReadDir(itemPath)
+				if err != nil {
+					return nil, nil, err
+				}
+				for _, testEntry := range testEntries {
+					if testEntry.IsDir() {
+						testDirs[fsys.PathJoin(itemPath, testEntry.Name())] = struct{}{}
+					}
+				}
+				continue
+			}
+			if files, testDirs, err = findSentinelFiles(fsys, itemPath, false, files, testDirs); err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+		if strings.HasSuffix(entry.Name(), ".sentinel") {
+			files = append(files, itemPath)
+		}
+	}
+	return files, testDirs, nil
+}
@@ -0,0 +1,94 @@
+package configuration
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/tools/txtar"
+
+	"github.com/glennsarti/sentinel-utils/lib/internal/txtar_fs"
+	parsing "github.com/glennsarti/sentinel-utils/lib/parsing/default"
+)
+
+const generateArchive = `
+-- policies/deny.sentinel --
+import "helpers"
+import "strings"
+
+main = rule { helpers.ok }
+-- policies/allow.sentinel --
+main = rule { true }
+-- policies/test/deny/pass.hcl --
+test {
+  rules = {
+    main = true
+  }
+}
+-- policies/test/unknown/pass.hcl --
+-- modules/helpers.sentinel --
+ok = true
+-- retired/allow.sentinel --
+main = rule { false }
+-- nested/sentinel.hcl --
+-- nested/nested.sentinel --
+main = rule { true }
+`
+
+func TestGenerate(t *testing.T) {
+	fsys := txtar_fs.NewTxtarFileSystem(txtar.Parse([]byte(generateArchive)))
+	pf := parsing.NewDefaultParsingFactory(fsys)
+
+	generated, err := Generate(context.Background(), fsys, "/", pf, GenerateOptions{
+		SentinelVersion:  "v0.40.0",
+		EnforcementLevel: "soft-mandatory",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `import "module" "helpers" {
+  source = "./modules/helpers.sentinel"
+}
+
+policy "allow" {
+  source            = "./policies/allow.sentinel"
+  enforcement_level = "soft-mandatory"
+}
+
+# Tests are in ./policies/test/deny
+policy "deny" {
+  source            = "./policies/deny.sentinel"
+  enforcement_level = "soft-mandatory"
+}
+`
+	if diff := cmp.Diff(expected, string(generated.Content)); diff != "" {
+		t.Error(diff)
+	}
+
+	expectedWarnings := []string{
+		`/retired/allow.sentinel was not added because /policies/allow.sentinel already uses the name "allow"`,
+		"The test directory /policies/test/unknown does not match the name of any policy",
+	}
+	if diff := cmp.Diff(expectedWarnings, generated.Warnings); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestGenerateV1Modules(t *testing.T) {
+	fsys := txtar_fs.NewTxtarFileSystem(txtar.Parse([]byte(generateArchive)))
+	pf := parsing.NewDefaultParsingFactory(fsys)
+
+	generated, err := Generate(context.Background(), fsys, "/", pf, GenerateOptions{SentinelVersion: "v0.18.13"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(generated.Modules) != 1 || generated.Modules[0].Name != "helpers" {
+		t.Fatalf("expected the helpers module but got %v", generated.Modules)
+	}
+
+	_, err = Generate(context.Background(), fsys, "/", pf, GenerateOptions{SentinelVersion: "v0.40.0", EnforcementLevel: "strict"})
+	if err == nil {
+		t.Error("expected an error for an invalid enforcement level")
+	}
+}
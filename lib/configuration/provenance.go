@@ -0,0 +1,200 @@
+package configuration
+
+import (
+	"strings"
+
+	"github.com/glennsarti/sentinel-parser/diagnostics"
+	"github.com/glennsarti/sentinel-parser/position"
+	"github.com/glennsarti/sentinel-utils/lib/filesystem"
+	"github.com/zclconf/go-cty/cty"
+
+	scast "github.com/glennsarti/sentinel-parser/sentinel_config/ast"
+	scparser "github.com/glennsarti/sentinel-parser/sentinel_config/parser"
+)
+
+// Definition is where a block or attribute was set
+type Definition struct {
+	Range *position.SourceRange
+	// The value of an attribute. Blocks do not have a value.
+	Value cty.Value
+}
+
+// Origin is the history of a block or attribute in the resolved configuration
+type Origin struct {
+	// For example policy.name.enforcement_level
+	Key     string
+	IsBlock bool
+	// Oldest first. For attributes, each definition replaced the value of the one before it.
+	Definitions []Definition
+
+	// The range in the resolved configuration. Blocks keep the range of the primary file
+	// unless they are replaced.
+	resolvedRange *position.SourceRange
+}
+
+// Effective is the definition which set the resolved value
+func (o *Origin) Effective() Definition {
+	return o.Definitions[len(o.Definitions)-1]
+}
+
+// Replaced are the definitions which were overridden, newest first
+func (o *Origin) Replaced() []Definition {
+	result := make([]Definition, 0, len(o.Definitions)-1)
+	for idx := len(o.Definitions) - 2; idx >= 0; idx-- {
+		result = append(result, o.Definitions[idx])
+	}
+	return result
+}
+
+// Provenance records which file set each block and attribute of a resolved configuration
+type Provenance struct {
+	origins map[string]*Origin
+	// In the order the configuration is written out
+	keys []string
+}
+
+// Lookup finds the origin of a block or attribute, for example policy.name.enforcement_level
+func (p *Provenance) Lookup(key string) (*Origin, bool) {
+	o, ok := p.origins[key]
+	return o, ok
+}
+
+// Keys are every block and attribute in the resolved configuration
+func (p *Provenance) Keys() []string {
+	return p.keys
+}
+
+// Merger applies override files to a configuration, and records the provenance of each
+// block and attribute as it goes
+type Merger struct {
+	file            *scast.File
+	sentinelVersion string
+	provenance      *Provenance
+	writer          *writer
+}
+
+// NewMerger creates a merger for the primary configuration file. The overrides are applied
+// to the file in place, so it is usually a clone of the parsed file.
+func NewMerger(file *scast.File, sentinelVersion string, fsys filesystem.FS) *Merger {
+	m := &Merger{
+		file:            file,
+		sentinelVersion: sentinelVersion,
+		provenance:      &Provenance{origins: make(map[string]*Origin)},
+		writer:          newWriter(WriteOptions{FileSystem: fsys}),
+	}
+	m.record(nil)
+	return m
+}
+
+// File is the configuration with the overrides applied so far
+func (m *Merger) File() *scast.File {
+	return m.file
+}
+
+// Provenance of the configuration with the overrides applied so far
+func (m *Merger) Provenance() *Provenance {
+	return m.provenance
+}
+
+// Apply applies an override file using the same rules as Sentinel
+func (m *Merger) Apply(override *scast.File) diagnostics.Diagnostics {
+	diags := scparser.OverrideFileWith(m.file, override, m.sentinelVersion)
+	m.record(override)
+	return diags
+}
+
+// Shadowed returns the definitions which the block or attribute in the override file, at the
+// range, would override. The override must not have been applied yet.
+func (m *Merger) Shadowed(override *scast.File, rng *position.SourceRange) []Definition {
+	if rng == nil {
+		return nil
+	}
+	for _, e := range m.writer.flatten(override) {
+		if e.rng.Filename != rng.Filename || e.rng.Start != rng.Start {
+			continue
+		}
+		if o, ok := m.provenance.origins[e.key]; ok {
+			return append([]Definition{}, o.Definitions...)
+		}
+		return nil
+	}
+	return nil
+}
+
+// Updates the provenance after the override has been applied. Attributes which now have a
+// different range were set by the override, and blocks in the override are added to the
+// definitions of the block they override.
+func (m *Merger) record(override *scast.File) {
+	inOverride := make(map[string]*position.SourceRange)
+	for _, e := range m.writer.flatten(override) {
+		if e.isBlock {
+			inOverride[e.key] = e.rng
+		}
+	}
+
+	p := m.provenance
+	p.keys = make([]string, 0, len(p.keys))
+	for _, e := range m.writer.flatten(m.file) {
+		p.keys = append(p.keys, e.key)
+		def := Definition{Range: e.rng, Value: e.value}
+
+		o, ok := p.origins[e.key]
+		if !ok {
+			p.origins[e.key] = &Origin{Key: e.key, IsBlock: e.isBlock, Definitions: []Definition{def}, resolvedRange: e.rng}
+			continue
+		}
+		if !sameRange(o.resolvedRange, e.rng) {
+			o.Definitions = append(o.Definitions, def)
+			o.resolvedRange = e.rng
+			continue
+		}
+		if rng, ok := inOverride[e.key]; ok && e.isBlock {
+			o.Definitions = append(o.Definitions, Definition{Range: rng})
+		}
+	}
+}
+
+type flatEntry struct {
+	key     string
+	isBlock bool
+	rng     *position.SourceRange
+	value   cty.Value
+}
+
+// Flattens the blocks into keys like policy.name and policy.name.source. Values which can
+// not be converted are unknown.
+func (w *writer) flatten(file *scast.File) []flatEntry {
+	if file == nil {
+		return nil
+	}
+	blocks, _ := w.toBlocks(file)
+
+	result := make([]flatEntry, 0)
+	var walk func(prefix string, b *block)
+	walk = func(prefix string, b *block) {
+		key := strings.Join(append([]string{b.typeName}, b.labels...), ".")
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		if b.rng != nil {
+			result = append(result, flatEntry{key: key, isBlock: true, rng: b.rng})
+		}
+		for _, attr := range b.attributes {
+			result = append(result, flatEntry{key: key + "." + attr.name, rng: attr.rng, value: attr.value})
+		}
+		for _, child := range b.blocks {
+			walk(key, child)
+		}
+	}
+	for _, b := range blocks {
+		walk("", b)
+	}
+	return result
+}
+
+func sameRange(a, b *position.SourceRange) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Filename == b.Filename && a.Start == b.Start && a.End == b.End
+}
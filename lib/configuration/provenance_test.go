@@ -0,0 +1,88 @@
+package configuration
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/zclconf/go-cty/cty"
+	"golang.org/x/tools/txtar"
+
+	"github.com/glennsarti/sentinel-parser/filetypes"
+	scast "github.com/glennsarti/sentinel-parser/sentinel_config/ast"
+	"github.com/glennsarti/sentinel-utils/lib/filesystem"
+	"github.com/glennsarti/sentinel-utils/lib/internal/txtar_fs"
+	parsing "github.com/glennsarti/sentinel-utils/lib/parsing/default"
+)
+
+// The definitions as file:line = value
+func describe(defs []Definition) []string {
+	result := make([]string, len(defs))
+	for idx, def := range defs {
+		result[idx] = Location(def.Range, "/")
+		if def.Value != cty.NilVal {
+			result[idx] += " = " + FormatValue(def.Value)
+		}
+	}
+	return result
+}
+
+func TestProvenance(t *testing.T) {
+	resolved, _ := resolveArchive(t, overridesArchive, "v0.40.0")
+
+	tests := []struct {
+		key      string
+		expected []string
+	}{
+		{"policy.policy", []string{"sentinel.hcl:5", "a_override.hcl:1", "b_override.hcl:1"}},
+		{"policy.policy.enforcement_level", []string{
+			`sentinel.hcl:7 = "advisory"`,
+			`a_override.hcl:2 = "soft-mandatory"`,
+			`b_override.hcl:2 = "hard-mandatory"`,
+		}},
+		{"policy.policy.source", []string{`sentinel.hcl:6 = "./policy.sentinel"`}},
+		{"param.limit.value", []string{"sentinel.hcl:2 = 5"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.key, func(t *testing.T) {
+			origin, ok := resolved.Provenance.Lookup(tc.key)
+			if !ok {
+				t.Fatalf("expected %s to have an origin", tc.key)
+			}
+			if diff := cmp.Diff(tc.expected, describe(origin.Definitions)); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+
+	if _, ok := resolved.Provenance.Lookup("policy.missing"); ok {
+		t.Error("expected policy.missing to not have an origin")
+	}
+}
+
+func TestMergerShadowed(t *testing.T) {
+	fsys := txtar_fs.NewTxtarFileSystem(txtar.Parse([]byte(overridesArchive)))
+	pf := parsing.NewDefaultParsingFactory(fsys)
+	parse := func(path string, ft filetypes.FileType) *scast.File {
+		cfg, d, err := pf.ParseSentinelConfigFile(context.Background(), &filesystem.File{Path: path, Type: ft}, "v0.40.0")
+		if err != nil || d.HasErrors() {
+			t.Fatalf("could not parse %s: %v %v", path, err, d)
+		}
+		return cfg
+	}
+
+	merger := NewMerger(scast.CloneFile(parse("/sentinel.hcl", filetypes.ConfigPrimaryFileType)), "v0.40.0", fsys)
+	merger.Apply(parse("/a_override.hcl", filetypes.ConfigOverrideFileType))
+
+	override := parse("/b_override.hcl", filetypes.ConfigOverrideFileType)
+	shadowed := merger.Shadowed(override, override.Policies["policy"].PolicyRange)
+	if diff := cmp.Diff([]string{"sentinel.hcl:5", "a_override.hcl:1"}, describe(shadowed)); diff != "" {
+		t.Error(diff)
+	}
+
+	shadowed = merger.Shadowed(override, override.Policies["policy"].EnforcementLevelRange)
+	expected := []string{`sentinel.hcl:7 = "advisory"`, `a_override.hcl:2 = "soft-mandatory"`}
+	if diff := cmp.Diff(expected, describe(shadowed)); diff != "" {
+		t.Error(diff)
+	}
+}
@@ -0,0 +1,224 @@
+package configuration
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/glennsarti/sentinel-parser/position"
+	"github.com/glennsarti/sentinel-utils/lib/filesystem"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+
+	scast "github.com/glennsarti/sentinel-parser/sentinel_config/ast"
+	hcljson "github.com/hashicorp/hcl/v2/json"
+)
+
+// WriteOptions changes how a configuration is written
+type WriteOptions struct {
+	// Annotate adds the file and line each block and attribute came from
+	Annotate bool
+	// Annotated file paths are relative to this directory
+	BaseDir string
+	// Used to read the values the parser does not keep, for example test rules
+	FileSystem filesystem.FS
+}
+
+type writer struct {
+	opts    WriteOptions
+	sources map[string][]byte
+	// The first value which could not be converted
+	err error
+}
+
+func newWriter(opts WriteOptions) *writer {
+	return &writer{
+		opts:    opts,
+		sources: make(map[string][]byte),
+	}
+}
+
+// WriteHCL writes the configuration as HCL
+func WriteHCL(w io.Writer, file *scast.File, opts WriteOptions) error {
+	return newWriter(opts).writeHCL(w, file)
+}
+
+func (wr *writer) writeHCL(w io.Writer, file *scast.File) error {
+	blocks, err := wr.toBlocks(file)
+	if err != nil {
+		return err
+	}
+
+	out := hclwrite.NewEmptyFile()
+	body := out.Body()
+	for idx, b := range blocks {
+		if idx > 0 {
+			body.AppendNewline()
+		}
+		wr.appendHCLBlock(body, b)
+	}
+
+	_, err = w.Write(hclwrite.Format(out.Bytes()))
+	return err
+}
+
+func (w *writer) appendHCLBlock(body *hclwrite.Body, b *block) {
+	w.appendHCLComment(body, b.rng)
+	nested := body.AppendNewBlock(b.typeName, b.labels).Body()
+	for _, attr := range b.attributes {
+		w.appendHCLComment(nested, attr.rng)
+		nested.SetAttributeValue(attr.name, attr.value)
+	}
+	for _, child := range b.blocks {
+		w.appendHCLBlock(nested, child)
+	}
+}
+
+func (w *writer) appendHCLComment(body *hclwrite.Body, rng *position.SourceRange) {
+	if !w.opts.Annotate || rng == nil {
+		return
+	}
+	body.AppendUnstructuredTokens(hclwrite.Tokens{
+		{Type: hclsyntax.TokenComment, Bytes: []byte("# " + w.location(rng) + "\n")},
+	})
+}
+
+// WriteJSON writes the configuration using the HCL JSON syntax. Annotations are
+// written as "//" properties, which are ignored when the file is parsed.
+func WriteJSON(w io.Writer, file *scast.File, opts WriteOptions) error {
+	return newWriter(opts).writeJSON(w, file)
+}
+
+func (wr *writer) writeJSON(w io.Writer, file *scast.File) error {
+	blocks, err := wr.toBlocks(file)
+	if err != nil {
+		return err
+	}
+
+	root := make(map[string]interface{})
+	for _, b := range blocks {
+		content, err := wr.jsonBody(b)
+		if err != nil {
+			return err
+		}
+		// Each label is another level of nesting
+		parent := root
+		key := b.typeName
+		for _, label := range b.labels {
+			child, ok := parent[key].(map[string]interface{})
+			if !ok {
+				child = make(map[string]interface{})
+				parent[key] = child
+			}
+			parent, key = child, label
+		}
+		parent[key] = content
+	}
+
+	content, err := json.MarshalIndent(root, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(content, '\n'))
+	return err
+}
+
+func (w *writer) jsonBody(b *block) (map[string]interface{}, error) {
+	result := make(map[string]interface{})
+	annotations := make(map[string]string)
+
+	if w.opts.Annotate && b.rng != nil {
+		annotations[b.typeName] = w.location(b.rng)
+	}
+	for _, attr := range b.attributes {
+		content, err := ctyjson.SimpleJSONValue{Value: attr.value}.MarshalJSON()
+		if err != nil {
+			return nil, fmt.Errorf("could not convert %s to JSON: %w", attr.name, err)
+		}
+		result[attr.name] = json.RawMessage(content)
+		if w.opts.Annotate {
+			annotations[attr.name] = w.location(attr.rng)
+		}
+	}
+	for _, child := range b.blocks {
+		content, err := w.jsonBody(child)
+		if err != nil {
+			return nil, err
+		}
+		result[child.typeName] = content
+	}
+
+	if len(annotations) > 0 {
+		result["//"] = annotations
+	}
+	return result, nil
+}
+
+func (w *writer) location(rng *position.SourceRange) string {
+	return Location(rng, w.opts.BaseDir)
+}
+
+// Location is the file and one based line number of the range. The file is relative to the
+// base directory when it is inside it.
+func Location(rng *position.SourceRange, baseDir string) string {
+	path := rng.Filename
+	if baseDir != "" {
+		if rel, err := filepath.Rel(baseDir, path); err == nil && !strings.HasPrefix(rel, "..") {
+			path = filepath.ToSlash(rel)
+		}
+	}
+	return fmt.Sprintf("%s:%d", path, rng.Start.Line+1)
+}
+
+// FormatValue formats the value as it is written in HCL
+func FormatValue(value cty.Value) string {
+	if value == cty.NilVal || !value.IsWhollyKnown() {
+		return "(unknown)"
+	}
+	return string(hclwrite.TokensForValue(value).Bytes())
+}
+
+// sourceValue parses the expression at the range in the original file
+func (w *writer) sourceValue(rng *position.SourceRange) (cty.Value, error) {
+	if rng == nil {
+		return cty.NullVal(cty.DynamicPseudoType), nil
+	}
+	src, ok := w.sources[rng.Filename]
+	if !ok {
+		if w.opts.FileSystem == nil {
+			return cty.NilVal, fmt.Errorf("no filesystem to read %s", rng.Filename)
+		}
+		content, err := w.opts.FileSystem.ReadFile(rng.Filename)
+		if err != nil {
+			return cty.NilVal, err
+		}
+		src = content
+		w.sources[rng.Filename] = src
+	}
+	if rng.Start.Byte < 0 || rng.End.Byte > len(src) || rng.Start.Byte > rng.End.Byte {
+		return cty.NilVal, fmt.Errorf("range %s is outside of %s", rng.ToString(), rng.Filename)
+	}
+	text := bytes.TrimSpace(src[rng.Start.Byte:rng.End.Byte])
+
+	var expr hcl.Expression
+	var d hcl.Diagnostics
+	if strings.HasSuffix(rng.Filename, ".json") {
+		expr, d = hcljson.ParseExpression(text, rng.Filename)
+	} else {
+		expr, d = hclsyntax.ParseExpression(text, rng.Filename, hcl.InitialPos)
+	}
+	if d.HasErrors() {
+		return cty.NilVal, d
+	}
+	value, d := expr.Value(nil)
+	if d.HasErrors() {
+		return cty.NilVal, d
+	}
+	return value, nil
+}
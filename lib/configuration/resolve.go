@@ -0,0 +1,109 @@
+package configuration
+
+import (
+	"context"
+	"strings"
+
+	"github.com/glennsarti/sentinel-parser/diagnostics"
+	"github.com/glennsarti/sentinel-parser/filetypes"
+	"github.com/glennsarti/sentinel-parser/position"
+	"github.com/glennsarti/sentinel-utils/lib/filesystem"
+	"github.com/glennsarti/sentinel-utils/lib/parsing"
+
+	scast "github.com/glennsarti/sentinel-parser/sentinel_config/ast"
+	cwalker "github.com/glennsarti/sentinel-utils/lib/walkers/sentinel_config"
+)
+
+// Resolved is the configuration after the override files have been applied to the primary
+type Resolved struct {
+	File        *scast.File
+	PrimaryPath string
+	// In the order they were applied
+	OverridePaths []string
+	// Warnings from parsing and applying the files
+	Diagnostics diagnostics.Diagnostics
+	// Which file set each block and attribute
+	Provenance *Provenance
+}
+
+// Resolve applies the override files to the primary configuration file, in the order the
+// walker visits them. Errors in any of the files stop the resolution.
+func Resolve(ctx context.Context, walker cwalker.Walker, pf parsing.Factory) (*Resolved, error) {
+	result := &Resolved{
+		OverridePaths: make([]string, 0),
+		Diagnostics:   diagnostics.EmptyDiags(),
+	}
+
+	var merger *Merger
+	err := walker.Walk(ctx, func(fileCtx context.Context, file *filesystem.File, _ *position.SourceRange) (bool, error) {
+		switch file.Type {
+		case filetypes.ConfigPrimaryFileType, filetypes.ConfigOverrideFileType:
+		default:
+			// The overrides are always visited before anything else
+			return false, nil
+		}
+
+		cfg, d, err := pf.ParseSentinelConfigFile(fileCtx, file, walker.SentinelVersion())
+		if err != nil {
+			return false, err
+		}
+		if d.HasErrors() {
+			return false, d
+		}
+		result.Diagnostics = append(result.Diagnostics, d...)
+
+		if file.Type == filetypes.ConfigPrimaryFileType {
+			merger = NewMerger(scast.CloneFile(cfg), walker.SentinelVersion(), walker.FileSystem())
+			result.PrimaryPath = file.Path
+			return true, nil
+		}
+
+		d = merger.Apply(cfg)
+		if d.HasErrors() {
+			return false, d
+		}
+		result.Diagnostics = append(result.Diagnostics, d...)
+		result.OverridePaths = append(result.OverridePaths, file.Path)
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if merger == nil {
+		merger = NewMerger(scast.NewFile(), walker.SentinelVersion(), walker.FileSystem())
+	}
+	result.File = merger.File()
+	result.Provenance = merger.Provenance()
+	return result, nil
+}
+
+// ModulePath is the path of the file for a module import, when the module is a local file
+func (r *Resolved) ModulePath(fsys filesystem.FS, name string) (string, bool) {
+	if r.File == nil {
+		return "", false
+	}
+	switch imp := r.File.Imports[name].(type) {
+	case *scast.V1ModuleImport:
+		return r.localPath(fsys, imp.Source)
+	case *scast.V2ModuleImport:
+		return r.localPath(fsys, imp.Source)
+	}
+	return "", false
+}
+
+// PolicyPath is the path of the file for a policy, when the policy is a local file
+func (r *Resolved) PolicyPath(fsys filesystem.FS, name string) (string, bool) {
+	if r.File == nil || r.File.Policies[name] == nil {
+		return "", false
+	}
+	return r.localPath(fsys, r.File.Policies[name].Source)
+}
+
+// Only sources starting with ./ are local files, in the same way as the walker
+func (r *Resolved) localPath(fsys filesystem.FS, source string) (string, bool) {
+	if r.PrimaryPath == "" || !strings.HasPrefix(source, "./") {
+		return "", false
+	}
+	return fsys.PathJoin(fsys.ParentPath(r.PrimaryPath), source[2:]), true
+}
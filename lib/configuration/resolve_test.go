@@ -0,0 +1,170 @@
+package configuration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/tools/txtar"
+
+	"github.com/glennsarti/sentinel-utils/lib/filesystem"
+	"github.com/glennsarti/sentinel-utils/lib/internal/txtar_fs"
+	parsing "github.com/glennsarti/sentinel-utils/lib/parsing/default"
+	cwalker "github.com/glennsarti/sentinel-utils/lib/walkers/sentinel_config"
+)
+
+const overridesArchive = `
+-- sentinel.hcl --
+param "limit" {
+  value = 5
+}
+
+policy "policy" {
+  source            = "./policy.sentinel"
+  enforcement_level = "advisory"
+}
+
+test {
+  rules = {
+    main = true
+  }
+}
+-- a_override.hcl --
+policy "policy" {
+  enforcement_level = "soft-mandatory"
+}
+-- b_override.hcl --
+policy "policy" {
+  enforcement_level = "hard-mandatory"
+}
+-- policy.sentinel --
+main = rule { true }
+`
+
+func resolveArchive(t *testing.T, archive, version string) (*Resolved, filesystem.FS) {
+	t.Helper()
+
+	fsys := txtar_fs.NewTxtarFileSystem(txtar.Parse([]byte(archive)))
+	pf := parsing.NewDefaultParsingFactory(fsys)
+	walker := cwalker.NewSentinelConfigWalker(fsys, "/", version, pf)
+
+	resolved, err := Resolve(context.Background(), walker, pf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resolved, fsys
+}
+
+func TestResolveAppliesOverridesInOrder(t *testing.T) {
+	resolved, fsys := resolveArchive(t, overridesArchive, "v0.40.0")
+
+	if diff := cmp.Diff([]string{"/a_override.hcl", "/b_override.hcl"}, resolved.OverridePaths); diff != "" {
+		t.Error(diff)
+	}
+
+	var out bytes.Buffer
+	if err := WriteHCL(&out, resolved.File, WriteOptions{Annotate: true, BaseDir: "/", FileSystem: fsys}); err != nil {
+		t.Fatal(err)
+	}
+	expected := `# sentinel.hcl:1
+param "limit" {
+  # sentinel.hcl:2
+  value = 5
+}
+
+# sentinel.hcl:5
+policy "policy" {
+  # sentinel.hcl:6
+  source = "./policy.sentinel"
+  # b_override.hcl:2
+  enforcement_level = "hard-mandatory"
+}
+
+# sentinel.hcl:10
+test {
+  # sentinel.hcl:11
+  rules = {
+    main = true
+  }
+}
+`
+	if diff := cmp.Diff(expected, out.String()); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestResolveIgnoresOverridesBeforeTheyAreSupported(t *testing.T) {
+	resolved, _ := resolveArchive(t, overridesArchive, "v0.18.13")
+
+	if len(resolved.OverridePaths) != 0 {
+		t.Errorf("expected no overrides to be applied but got %v", resolved.OverridePaths)
+	}
+	if actual := resolved.File.Policies["policy"].EnforcementLevel; actual != "advisory" {
+		t.Errorf("expected the enforcement level to be advisory but got %q", actual)
+	}
+}
+
+func TestResolvedPaths(t *testing.T) {
+	resolved, fsys := resolveArchive(t, `
+-- sentinel.hcl --
+import "module" "helpers" {
+  source = "./modules/helpers.sentinel"
+}
+import "module" "remote" {
+  source = "https://example.com/remote.sentinel"
+}
+policy "policy" {
+  source = "./policies/policy.sentinel"
+}
+-- modules/helpers.sentinel --
+x = 1
+-- policies/policy.sentinel --
+main = rule { true }
+`, "")
+
+	if actual, ok := resolved.ModulePath(fsys, "helpers"); !ok || actual != "/modules/helpers.sentinel" {
+		t.Errorf("expected the helpers module path but got %q", actual)
+	}
+	if actual, ok := resolved.ModulePath(fsys, "remote"); ok {
+		t.Errorf("expected a remote module to not have a path but got %q", actual)
+	}
+	if actual, ok := resolved.PolicyPath(fsys, "policy"); !ok || actual != "/policies/policy.sentinel" {
+		t.Errorf("expected the policy path but got %q", actual)
+	}
+	if actual, ok := resolved.PolicyPath(fsys, "missing"); ok {
+		t.Errorf("expected a missing policy to not have a path but got %q", actual)
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	resolved, fsys := resolveArchive(t, overridesArchive, "v0.40.0")
+
+	var out bytes.Buffer
+	if err := WriteJSON(&out, resolved.File, WriteOptions{FileSystem: fsys}); err != nil {
+		t.Fatal(err)
+	}
+
+	var actual map[string]interface{}
+	if err := json.Unmarshal(out.Bytes(), &actual); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{
+		"param": map[string]interface{}{
+			"limit": map[string]interface{}{"value": float64(5)},
+		},
+		"policy": map[string]interface{}{
+			"policy": map[string]interface{}{
+				"source":            "./policy.sentinel",
+				"enforcement_level": "hard-mandatory",
+			},
+		},
+		"test": map[string]interface{}{
+			"rules": map[string]interface{}{"main": true},
+		},
+	}
+	if diff := cmp.Diff(expected, actual); diff != "" {
+		t.Error(diff)
+	}
+}
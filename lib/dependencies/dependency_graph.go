@@ -0,0 +1,324 @@
+package dependencies
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/glennsarti/sentinel-parser/filetypes"
+	"github.com/glennsarti/sentinel-parser/position"
+	"github.com/glennsarti/sentinel-utils/lib/configuration"
+	"github.com/glennsarti/sentinel-utils/lib/filesystem"
+	"github.com/glennsarti/sentinel-utils/lib/internal/helpers"
+	"github.com/glennsarti/sentinel-utils/lib/parsing"
+
+	scast "github.com/glennsarti/sentinel-parser/sentinel_config/ast"
+	cwalker "github.com/glennsarti/sentinel-utils/lib/walkers/sentinel_config"
+)
+
+// MockFileType is a Sentinel file which is only referenced by a mock in a test file
+const MockFileType filetypes.FileType = "mock"
+
+// StaticFileType is a data file which is referenced by a static import
+const StaticFileType filetypes.FileType = "static"
+
+type Node struct {
+	Path string
+	Type filetypes.FileType
+	// The name of the policy or import, if it has one
+	Name string
+}
+
+// Graph records which files in a policy set depend on which other files.
+// An edge from A to B means that a change in A affects B.
+type Graph struct {
+	fsys       filesystem.FS
+	nodes      map[string]*Node
+	dependents map[string]map[string]struct{}
+
+	primaryPath string
+	// Policy name -> Policy path
+	policies map[string]string
+	// Import name -> Import path (Only local files)
+	imports map[string]string
+	// Policy path -> The names of the imports used by the policy
+	policyImports map[string][]string
+	// Policy path -> The names of the parameters declared by the policy
+	policyParams map[string][]string
+}
+
+// Build walks the policy set and records the dependencies between the files it finds
+func Build(ctx context.Context, walker cwalker.Walker, pf parsing.Factory) (*Graph, error) {
+	g := &Graph{
+		fsys:          walker.FileSystem(),
+		nodes:         make(map[string]*Node, 0),
+		dependents:    make(map[string]map[string]struct{}, 0),
+		policies:      make(map[string]string, 0),
+		imports:       make(map[string]string, 0),
+		policyImports: make(map[string][]string, 0),
+		policyParams:  make(map[string][]string, 0),
+	}
+
+	primary := scast.NewFile()
+	overrides := make([]*scast.File, 0)
+	overridePaths := make([]string, 0)
+	testPaths := make([]string, 0)
+
+	err := walker.Walk(ctx, func(fileCtx context.Context, file *filesystem.File, _ *position.SourceRange) (bool, error) {
+		switch file.Type {
+		case filetypes.ConfigPrimaryFileType:
+			g.addNode(file.Path, file.Type, "")
+			g.primaryPath = file.Path
+			if cfg, d, err := pf.ParseSentinelConfigFile(fileCtx, file, walker.SentinelVersion()); err == nil && !d.HasErrors() {
+				g.addConfigImports(cfg, g.fsys.ParentPath(file.Path))
+				primary = cfg
+			}
+
+		case filetypes.ConfigOverrideFileType:
+			g.addNode(file.Path, file.Type, "")
+			if cfg, d, err := pf.ParseSentinelConfigFile(fileCtx, file, walker.SentinelVersion()); err == nil && !d.HasErrors() {
+				overrides = append(overrides, cfg)
+				overridePaths = append(overridePaths, file.Path)
+			}
+
+		case filetypes.ModuleFileType:
+			n := g.addNode(file.Path, file.Type, g.importName(file.Path))
+			g.addSentinelImports(fileCtx, file, n, walker.SentinelVersion(), pf)
+
+		case filetypes.PolicyFileType:
+			n := g.addNode(file.Path, file.Type, strings.TrimPrefix(file.ID, "policy-"))
+			g.policies[n.Name] = file.Path
+			g.addEdge(g.primaryPath, file.Path)
+			g.addSentinelImports(fileCtx, file, n, walker.SentinelVersion(), pf)
+
+		case filetypes.ConfigTestFileType:
+			g.addNode(file.Path, file.Type, "")
+			testPaths = append(testPaths, file.Path)
+			if cfg, d, err := pf.ParseSentinelConfigFile(fileCtx, file, walker.SentinelVersion()); err == nil && !d.HasErrors() {
+				g.addMocks(cfg, file.Path)
+			}
+		}
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Tests are in a directory named after their policy, for example test/<policy>/pass.hcl
+	for _, testPath := range testPaths {
+		name := g.fsys.BasePath(g.fsys.ParentPath(testPath))
+		if policyPath, ok := g.policies[name]; ok {
+			g.addEdge(policyPath, testPath)
+		}
+	}
+
+	// Overrides can only be resolved once all of the policies are known
+	g.addOverrideEdges(primary, overrides, overridePaths, walker.SentinelVersion())
+
+	// Now that all the policies are known, link the imports to the files that use them
+	for _, policyPath := range helpers.SortedKeys(g.policyImports) {
+		for _, name := range g.policyImports[policyPath] {
+			if importPath, ok := g.imports[name]; ok {
+				g.addEdge(importPath, policyPath)
+			}
+		}
+	}
+
+	return g, nil
+}
+
+// Nodes returns all of the files in the graph, sorted by path
+func (g *Graph) Nodes() []*Node {
+	result := make([]*Node, 0, len(g.nodes))
+	for _, key := range helpers.SortedKeys(g.nodes) {
+		result = append(result, g.nodes[key])
+	}
+	return result
+}
+
+// Node returns the file at the path, or nil if it is not in the graph
+func (g *Graph) Node(path string) *Node {
+	return g.nodes[path]
+}
+
+// Dependents returns the paths of the files which directly depend on the path, sorted by path
+func (g *Graph) Dependents(path string) []string {
+	return helpers.SortedKeys(g.dependents[path])
+}
+
+// Affected returns every file which is affected by a change to any of the paths,
+// including the changed files themselves, sorted by path. Paths which are not in the
+// graph are ignored.
+func (g *Graph) Affected(changed []string) []*Node {
+	seen := make(map[string]struct{}, 0)
+	queue := slices.Clone(changed)
+
+	for len(queue) > 0 {
+		item := queue[0]
+		queue = queue[1:]
+		if _, ok := seen[item]; ok {
+			continue
+		}
+		if _, ok := g.nodes[item]; !ok {
+			continue
+		}
+		seen[item] = struct{}{}
+		queue = append(queue, g.Dependents(item)...)
+	}
+
+	result := make([]*Node, 0, len(seen))
+	for _, key := range helpers.SortedKeys(seen) {
+		result = append(result, g.nodes[key])
+	}
+	return result
+}
+
+func (g *Graph) addNode(path string, fileType filetypes.FileType, name string) *Node {
+	if n, ok := g.nodes[path]; ok {
+		return n
+	}
+	n := &Node{
+		Path: path,
+		Type: fileType,
+		Name: name,
+	}
+	g.nodes[path] = n
+	return n
+}
+
+func (g *Graph) addEdge(from, to string) {
+	if from == "" || to == "" || from == to {
+		return
+	}
+	if _, ok := g.dependents[from]; !ok {
+		g.dependents[from] = make(map[string]struct{}, 0)
+	}
+	g.dependents[from][to] = struct{}{}
+}
+
+func (g *Graph) importName(path string) string {
+	for _, name := range helpers.SortedKeys(g.imports) {
+		if g.imports[name] == path {
+			return name
+		}
+	}
+	return ""
+}
+
+// Records where the local modules and static imports live
+func (g *Graph) addConfigImports(cfg *scast.File, parentDir string) {
+	for _, name := range helpers.SortedKeys(cfg.Imports) {
+		source := ""
+		fileType := filetypes.ModuleFileType
+		switch actual := cfg.Imports[name].(type) {
+		case *scast.V1ModuleImport:
+			source = actual.Source
+		case *scast.V2ModuleImport:
+			source = actual.Source
+		case *scast.V2StaticImport:
+			source = actual.Source
+			fileType = StaticFileType
+		}
+		if !strings.HasPrefix(source, "./") {
+			continue
+		}
+		importPath := g.fsys.PathJoin(parentDir, source[2:])
+		g.imports[name] = importPath
+		if fileType == StaticFileType {
+			g.addNode(importPath, fileType, name)
+		}
+	}
+}
+
+// Records which imports and parameters a policy or module uses. Modules can import other modules.
+func (g *Graph) addSentinelImports(ctx context.Context, file *filesystem.File, n *Node, sentinelVersion string, pf parsing.Factory) {
+	parsed, _, err := pf.ParseSentinelFile(ctx, file, sentinelVersion)
+	if err != nil || parsed == nil {
+		return
+	}
+	names := make([]string, 0, len(parsed.Imports))
+	for _, imp := range parsed.Imports {
+		if imp != nil && imp.Name != nil {
+			// The literal value includes the surrounding quotes
+			names = append(names, strings.Trim(imp.Name.Value, `"`))
+		}
+	}
+	g.policyImports[n.Path] = names
+
+	params := make([]string, 0, len(parsed.Params))
+	for _, param := range parsed.Params {
+		if param != nil && param.Name != nil {
+			params = append(params, param.Name.Name)
+		}
+	}
+	g.policyParams[n.Path] = params
+}
+
+// Mock modules are relative to the test file
+func (g *Graph) addMocks(cfg *scast.File, testPath string) {
+	parentDir := g.fsys.ParentPath(testPath)
+	for _, name := range helpers.SortedKeys(cfg.Mocks) {
+		mock := cfg.Mocks[name]
+		if mock == nil || mock.Module == nil || mock.Module.Source == "" {
+			continue
+		}
+		mockPath := g.fsys.PathJoin(parentDir, mock.Module.Source)
+		g.addNode(mockPath, MockFileType, name)
+		g.addEdge(mockPath, testPath)
+	}
+}
+
+// An override affects the policies whose resolved configuration it changes. The overrides
+// are applied in order, and each policy's part of the configuration is compared before and
+// after each one.
+func (g *Graph) addOverrideEdges(primary *scast.File, overrides []*scast.File, overridePaths []string, sentinelVersion string) {
+	merger := configuration.NewMerger(scast.CloneFile(primary), sentinelVersion, g.fsys)
+	before := g.policyConfigs(merger.File())
+	for idx, cfg := range overrides {
+		merger.Apply(cfg)
+		after := g.policyConfigs(merger.File())
+		for _, name := range helpers.SortedKeys(g.policies) {
+			was, okBefore := before[name]
+			now, okAfter := after[name]
+			if !okBefore || !okAfter || was != now {
+				g.addEdge(overridePaths[idx], g.policies[name])
+			}
+		}
+		before = after
+	}
+}
+
+// Renders the parts of the configuration which each policy uses, by policy name
+func (g *Graph) policyConfigs(cfg *scast.File) map[string]string {
+	result := make(map[string]string, len(g.policies))
+	for name, policyPath := range g.policies {
+		view := &scast.File{
+			Globals:         cfg.Globals,
+			Imports:         make(map[string]scast.Import, 0),
+			Mocks:           cfg.Mocks,
+			Params:          make(map[string]*scast.Parameter, 0),
+			Policies:        make(map[string]*scast.Policy, 0),
+			SentinelOptions: cfg.SentinelOptions,
+		}
+		if p, ok := cfg.Policies[name]; ok {
+			view.Policies[name] = p
+		}
+		for _, param := range g.policyParams[policyPath] {
+			if p, ok := cfg.Params[param]; ok {
+				view.Params[param] = p
+			}
+		}
+		for _, imp := range g.policyImports[policyPath] {
+			if i, ok := cfg.Imports[imp]; ok {
+				view.Imports[imp] = i
+			}
+		}
+
+		// Configurations which can not be rendered are left out, and are always treated as changed
+		var buf strings.Builder
+		if err := configuration.WriteHCL(&buf, view, configuration.WriteOptions{FileSystem: g.fsys}); err == nil {
+			result[name] = buf.String()
+		}
+	}
+	return result
+}
@@ -0,0 +1,95 @@
+package spec
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/glennsarti/sentinel-utils/lib/internal/txtar_fs"
+
+	subject "github.com/glennsarti/sentinel-utils/lib/dependencies"
+	parsing "github.com/glennsarti/sentinel-utils/lib/parsing/default"
+	cwalker "github.com/glennsarti/sentinel-utils/lib/walkers/sentinel_config"
+)
+
+func TestLibDependenciesSpecs(t *testing.T) {
+	fixturesDir := path.Join("test-fixtures")
+
+	items, err := os.ReadDir(fixturesDir)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	for _, item := range items {
+		if item.IsDir() {
+			t.Run(item.Name(), func(t *testing.T) {
+				processTestFixturesDir(item.Name(), fixturesDir, item.Name(), t)
+			})
+		}
+	}
+}
+
+func processTestFixturesDir(relPath, srcDir, sentinelVersion string, t *testing.T) {
+	dirPath := path.Join(srcDir, relPath)
+
+	entries, err := os.ReadDir(dirPath)
+	if err != nil {
+		panic(err)
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".txtar") {
+			t.Run(entry.Name(), func(t *testing.T) {
+				if err := testSpecFile(entry.Name(), dirPath, sentinelVersion, t); err != nil {
+					t.Error(err)
+				}
+			})
+		}
+	}
+}
+
+func testSpecFile(filename, parentPath, sentinelVersion string, t *testing.T) error {
+	filePath := path.Join(parentPath, filename)
+
+	arc, err := parseTxtarArchive(filePath)
+	if err != nil {
+		return err
+	}
+
+	arcfs := txtar_fs.NewTxtarFileSystem(arc.raw)
+	pf := parsing.NewDefaultParsingFactory(arcfs)
+	w := cwalker.NewSentinelConfigWalker(arcfs, "/", sentinelVersion, pf)
+	if w == nil {
+		return fmt.Errorf("Failed to create walker")
+	}
+
+	g, err := subject.Build(context.Background(), w, pf)
+	if err != nil {
+		return err
+	}
+
+	changed := make([]string, 0)
+	for _, line := range strings.Split(string(arc.ChangedFile.Data), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			changed = append(changed, line)
+		}
+	}
+
+	affected := make([]string, 0)
+	for _, node := range g.Affected(changed) {
+		affected = append(affected, fmt.Sprintf("Path:%s FileType:%s", node.Path, node.Type))
+	}
+
+	expectedString := string(arc.AffectedFile.Data)
+	actualString := strings.Join(affected, "\n") + "\n"
+	if diff := cmp.Diff(expectedString, actualString); diff != "" {
+		t.Fatal(diff)
+	}
+
+	return nil
+}
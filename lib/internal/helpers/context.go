@@ -0,0 +1,32 @@
+package helpers
+
+import (
+	"context"
+)
+
+// RunWithContext runs a function which cannot be cancelled, but stops waiting for it
+// when the context is done. The function keeps running in the background until it
+// finishes, but its result is discarded.
+func RunWithContext[T any](ctx context.Context, fn func() (T, error)) (T, error) {
+	var empty T
+	if err := ctx.Err(); err != nil {
+		return empty, err
+	}
+
+	type result struct {
+		value T
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		value, err := fn()
+		done <- result{value: value, err: err}
+	}()
+
+	select {
+	case <-ctx.Done():
+		return empty, ctx.Err()
+	case r := <-done:
+		return r.value, r.err
+	}
+}
@@ -0,0 +1,251 @@
+package completion
+
+import (
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+)
+
+// ConfigPositionKind is what can be written at a position in a configuration file
+type ConfigPositionKind int
+
+const (
+	UnknownPosition ConfigPositionKind = iota
+	// The start of an item in a body, which is either a block type or an attribute name
+	BodyPosition
+	// A block label, after the block type
+	LabelPosition
+	// The value of an attribute
+	ValuePosition
+	// A key in an object, for example a rule name in the rules of a test block
+	KeyPosition
+)
+
+// ConfigBlock is a block which contains a position
+type ConfigBlock struct {
+	Type   string
+	Labels []string
+}
+
+// ConfigContext describes a position in a configuration file. The file does not need to be
+// valid, as the position is usually where something is being written.
+type ConfigContext struct {
+	Kind ConfigPositionKind
+	// The blocks which contain the position, outermost first
+	Blocks []ConfigBlock
+	// The block being written for a label position, with the labels before the position
+	Block ConfigBlock
+	// The attribute for a value, or the attribute of the object for a key
+	Attribute string
+	// The text before the position which is being completed, and the byte offset it
+	// starts at
+	Prefix      string
+	PrefixStart int
+	// Whether the position is in a quoted string
+	Quoted bool
+}
+
+// The innermost block which contains the position, if there is one
+func (c ConfigContext) InnermostBlock() (ConfigBlock, bool) {
+	if len(c.Blocks) == 0 {
+		return ConfigBlock{}, false
+	}
+	return c.Blocks[len(c.Blocks)-1], true
+}
+
+// A block, or an object or list in an attribute value
+type configFrame struct {
+	block     *ConfigBlock
+	attribute string
+}
+
+// ConfigContextAt describes the byte offset in a configuration file. Only the text before
+// the offset is read, as the rest of the file is often unfinished.
+func ConfigContextAt(text []byte, offset int) ConfigContext {
+	if offset < 0 || offset > len(text) {
+		return ConfigContext{}
+	}
+	tokens := lexConfig(text[:offset])
+
+	stack := make([]configFrame, 0)
+	// The tokens of the current item, that is since the last new line or comma
+	item := make(hclsyntax.Tokens, 0)
+	for _, tok := range tokens {
+		switch tok.Type {
+		case hclsyntax.TokenNewline, hclsyntax.TokenComma, hclsyntax.TokenQuotedNewline:
+			item = item[:0]
+		case hclsyntax.TokenComment:
+			// Line comments include the new line
+			if len(tok.Bytes) > 0 && tok.Bytes[len(tok.Bytes)-1] == '\n' {
+				item = item[:0]
+			}
+		case hclsyntax.TokenEOF:
+		case hclsyntax.TokenOBrace, hclsyntax.TokenOBrack:
+			frame := configFrame{}
+			if blockType, labels, ok := blockHeader(item); ok && tok.Type == hclsyntax.TokenOBrace && !inValue(stack) {
+				frame.block = &ConfigBlock{Type: blockType, Labels: labels}
+			} else if name, ok := attributeName(item); ok {
+				frame.attribute = name
+			} else if len(stack) > 0 {
+				// Lists of objects use the attribute of the list
+				frame.attribute = stack[len(stack)-1].attribute
+			}
+			stack = append(stack, frame)
+			item = item[:0]
+		case hclsyntax.TokenCBrace, hclsyntax.TokenCBrack:
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+			item = append(item[:0], tok)
+		default:
+			item = append(item, tok)
+		}
+	}
+
+	result := ConfigContext{PrefixStart: offset}
+	for _, frame := range stack {
+		if frame.block != nil {
+			result.Blocks = append(result.Blocks, *frame.block)
+		}
+	}
+
+	// Find the text being completed, and what is before it
+	before := item
+	if idx := openQuoteIndex(item); idx >= 0 {
+		result.Quoted = true
+		result.PrefixStart = item[idx].Range.End.Byte
+		result.Prefix = string(text[result.PrefixStart:offset])
+		before = item[:idx]
+	} else if n := len(item); n > 0 && item[n-1].Type == hclsyntax.TokenIdent && item[n-1].Range.End.Byte == offset {
+		result.PrefixStart = item[n-1].Range.Start.Byte
+		result.Prefix = string(item[n-1].Bytes)
+		before = item[:n-1]
+	}
+
+	switch {
+	case len(before) == 0:
+		if inValue(stack) {
+			result.Kind = KeyPosition
+			result.Attribute = stack[len(stack)-1].attribute
+		} else {
+			result.Kind = BodyPosition
+		}
+	case hasAssignment(before):
+		result.Kind = ValuePosition
+		result.Attribute, _ = attributeName(before)
+	default:
+		if blockType, labels, ok := blockHeader(before); ok && !inValue(stack) {
+			result.Kind = LabelPosition
+			result.Block = ConfigBlock{Type: blockType, Labels: labels}
+		}
+	}
+	return result
+}
+
+// Lexes a configuration file. Strings which are not closed continue onto the following
+// lines, so the lexer is restarted on the next line to read the rest of the file.
+func lexConfig(text []byte) hclsyntax.Tokens {
+	result := make(hclsyntax.Tokens, 0)
+	start := hcl.InitialPos
+	for {
+		tokens, _ := hclsyntax.LexConfig(text[start.Byte:], "", start)
+		restart := false
+		for _, tok := range tokens {
+			result = append(result, tok)
+			if tok.Type == hclsyntax.TokenQuotedNewline {
+				start = tok.Range.End
+				restart = true
+				break
+			}
+		}
+		if !restart {
+			return result
+		}
+	}
+}
+
+// Whether the innermost frame is an object or list, rather than a block
+func inValue(stack []configFrame) bool {
+	return len(stack) > 0 && stack[len(stack)-1].block == nil
+}
+
+// The index of a quote which has not been closed, or -1
+func openQuoteIndex(tokens hclsyntax.Tokens) int {
+	for idx := len(tokens) - 1; idx >= 0; idx-- {
+		switch tokens[idx].Type {
+		case hclsyntax.TokenCQuote:
+			return -1
+		case hclsyntax.TokenOQuote:
+			return idx
+		}
+	}
+	return -1
+}
+
+func hasAssignment(tokens hclsyntax.Tokens) bool {
+	for _, tok := range tokens {
+		if tok.Type == hclsyntax.TokenEqual || tok.Type == hclsyntax.TokenColon {
+			return true
+		}
+	}
+	return false
+}
+
+// The name before the equals sign of an attribute, or of a key in an object
+func attributeName(tokens hclsyntax.Tokens) (string, bool) {
+	if len(tokens) < 2 {
+		return "", false
+	}
+	switch tokens[0].Type {
+	case hclsyntax.TokenIdent:
+		if tokens[1].Type == hclsyntax.TokenEqual || tokens[1].Type == hclsyntax.TokenColon {
+			return string(tokens[0].Bytes), true
+		}
+	case hclsyntax.TokenOQuote:
+		if value, next, ok := quotedString(tokens, 0); ok && next < len(tokens) &&
+			(tokens[next].Type == hclsyntax.TokenEqual || tokens[next].Type == hclsyntax.TokenColon) {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// The type and labels of a block header, for example policy "name"
+func blockHeader(tokens hclsyntax.Tokens) (string, []string, bool) {
+	if len(tokens) == 0 || tokens[0].Type != hclsyntax.TokenIdent {
+		return "", nil, false
+	}
+	labels := make([]string, 0)
+	for idx := 1; idx < len(tokens); {
+		switch tokens[idx].Type {
+		case hclsyntax.TokenIdent:
+			labels = append(labels, string(tokens[idx].Bytes))
+			idx++
+		case hclsyntax.TokenOQuote:
+			value, next, ok := quotedString(tokens, idx)
+			if !ok {
+				return "", nil, false
+			}
+			labels = append(labels, value)
+			idx = next
+		default:
+			return "", nil, false
+		}
+	}
+	return string(tokens[0].Bytes), labels, true
+}
+
+// Reads the quoted string starting at the index, and returns the index after it
+func quotedString(tokens hclsyntax.Tokens, start int) (string, int, bool) {
+	value := ""
+	for idx := start + 1; idx < len(tokens); idx++ {
+		switch tokens[idx].Type {
+		case hclsyntax.TokenQuotedLit:
+			value += string(tokens[idx].Bytes)
+		case hclsyntax.TokenCQuote:
+			return value, idx + 1, true
+		default:
+			return "", 0, false
+		}
+	}
+	return "", 0, false
+}
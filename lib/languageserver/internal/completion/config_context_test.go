@@ -0,0 +1,100 @@
+package completion
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/glennsarti/sentinel-parser/filetypes"
+)
+
+func TestConfigContextAt(t *testing.T) {
+	for _, testcase := range []struct {
+		name      string
+		text      string
+		kind      ConfigPositionKind
+		blocks    []string
+		block     string
+		attribute string
+		prefix    string
+		quoted    bool
+	}{
+		{name: "top level", text: "pol|", kind: BodyPosition, prefix: "pol"},
+		{name: "after a block", text: "policy \"a\" {\n}\n|", kind: BodyPosition},
+		{name: "in a block", text: "policy \"a\" {\n  enf|\n}", kind: BodyPosition, blocks: []string{"policy"}, prefix: "enf"},
+		{name: "label", text: "import \"|", kind: LabelPosition, block: "import", quoted: true},
+		{name: "second label", text: "import \"module\" \"he|", kind: LabelPosition, block: "import", prefix: "he", quoted: true},
+		{name: "value", text: "policy \"a\" {\n  enforcement_level = \"ha|\"\n}", kind: ValuePosition, blocks: []string{"policy"}, attribute: "enforcement_level", prefix: "ha", quoted: true},
+		{name: "unquoted value", text: "policy \"a\" {\n  enforcement_level = |", kind: ValuePosition, blocks: []string{"policy"}, attribute: "enforcement_level"},
+		{name: "path", text: "policy \"a\" {\n  source = \"./modules/|", kind: ValuePosition, blocks: []string{"policy"}, attribute: "source", prefix: "./modules/", quoted: true},
+		{name: "object key", text: "test {\n  rules = {\n    main = true\n    ma|", kind: KeyPosition, blocks: []string{"test"}, attribute: "rules", prefix: "ma"},
+		{name: "object key after comma", text: "test {\n  rules = { main = true, |", kind: KeyPosition, blocks: []string{"test"}, attribute: "rules"},
+		{name: "nested block", text: "mock \"time\" {\n  module {\n    |", kind: BodyPosition, blocks: []string{"mock", "module"}},
+		{name: "after an object", text: "policy \"a\" {\n  params = {\n    x = 1\n  }\n  |", kind: BodyPosition, blocks: []string{"policy"}},
+		{name: "after an unclosed string", text: "policy \"a\" {\n  source = \"./a\n  enforcement_level = \"|", kind: ValuePosition, blocks: []string{"policy"}, attribute: "enforcement_level", quoted: true},
+		{name: "comment", text: "# policy \"a\" {\n|", kind: BodyPosition},
+	} {
+		t.Run(testcase.name, func(t *testing.T) {
+			offset := strings.Index(testcase.text, "|")
+			text := []byte(strings.Replace(testcase.text, "|", "", 1))
+			cc := ConfigContextAt(text, offset)
+
+			if cc.Kind != testcase.kind {
+				t.Errorf("expected the kind %d but got %d", testcase.kind, cc.Kind)
+			}
+			blocks := make([]string, 0)
+			for _, b := range cc.Blocks {
+				blocks = append(blocks, b.Type)
+			}
+			if testcase.blocks == nil {
+				testcase.blocks = []string{}
+			}
+			if !slices.Equal(blocks, testcase.blocks) {
+				t.Errorf("expected the blocks %v but got %v", testcase.blocks, blocks)
+			}
+			if cc.Block.Type != testcase.block {
+				t.Errorf("expected the block %q but got %q", testcase.block, cc.Block.Type)
+			}
+			if cc.Attribute != testcase.attribute {
+				t.Errorf("expected the attribute %q but got %q", testcase.attribute, cc.Attribute)
+			}
+			if cc.Prefix != testcase.prefix || cc.PrefixStart != offset-len(testcase.prefix) {
+				t.Errorf("expected the prefix %q at %d but got %q at %d", testcase.prefix, offset-len(testcase.prefix), cc.Prefix, cc.PrefixStart)
+			}
+			if cc.Quoted != testcase.quoted {
+				t.Errorf("expected quoted to be %t", testcase.quoted)
+			}
+		})
+	}
+}
+
+func TestBodyItemsVersions(t *testing.T) {
+	labels := func(sentinelVersion string, fileType filetypes.FileType, cc ConfigContext) []string {
+		result := make([]string, 0)
+		for _, item := range BodyItems(cc, fileType, sentinelVersion, false) {
+			result = append(result, item.Label)
+		}
+		return result
+	}
+
+	latest := labels("v0.40.0", filetypes.ConfigPrimaryFileType, ConfigContext{})
+	if !slices.Contains(latest, `import "kind" "name"`) || !slices.Contains(latest, "sentinel") || slices.Contains(latest, `module "name"`) {
+		t.Errorf("expected version 2 imports and the sentinel block but got %v", latest)
+	}
+	if slices.Contains(latest, "test") {
+		t.Errorf("expected no test block outside of test files but got %v", latest)
+	}
+
+	older := labels("v0.18.0", filetypes.ConfigPrimaryFileType, ConfigContext{})
+	if !slices.Contains(older, `module "name"`) || !slices.Contains(older, `import "name"`) || slices.Contains(older, "sentinel") {
+		t.Errorf("expected version 1 imports without the sentinel block but got %v", older)
+	}
+
+	policy := ConfigContext{Blocks: []ConfigBlock{{Type: "policy", Labels: []string{"a"}}}}
+	if attrs := labels("v0.20.0", filetypes.ConfigPrimaryFileType, policy); slices.Contains(attrs, "params") {
+		t.Errorf("expected no params attribute before Sentinel v0.21.0 but got %v", attrs)
+	}
+	if attrs := labels("v0.21.0", filetypes.ConfigPrimaryFileType, policy); !slices.Equal(attrs, []string{"source", "enforcement_level", "params"}) {
+		t.Errorf("expected the policy attributes but got %v", attrs)
+	}
+}
@@ -0,0 +1,242 @@
+package completion
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/glennsarti/sentinel-parser/features"
+	"github.com/glennsarti/sentinel-parser/filetypes"
+	"github.com/glennsarti/sentinel-utils/lib/configuration"
+	lsp "github.com/glennsarti/sentinel-utils/lib/languageserver/internal/protocol"
+)
+
+// The import kinds of version 2 import blocks
+var importKinds = []string{"module", "plugin", "static"}
+
+type configAttribute struct {
+	name   string
+	detail string
+	// The first Sentinel version with the attribute, or empty for all versions
+	minimumVersion string
+}
+
+type configBlockType struct {
+	name   string
+	labels []string
+	detail string
+	// The Sentinel versions with the block, or empty for no limit
+	minimumVersion string
+	maximumVersion string
+	// The file types the block is used in
+	fileTypes []filetypes.FileType
+}
+
+var primaryFileTypes = []filetypes.FileType{filetypes.ConfigPrimaryFileType, filetypes.ConfigOverrideFileType}
+var allFileTypes = []filetypes.FileType{filetypes.ConfigPrimaryFileType, filetypes.ConfigOverrideFileType, filetypes.ConfigTestFileType}
+
+var configBlockTypes = []configBlockType{
+	{
+		name:           "sentinel",
+		detail:         "Options for Sentinel",
+		minimumVersion: features.SentinelBlockMinimumVersion,
+		fileTypes:      primaryFileTypes,
+	},
+	{name: "policy", labels: []string{"name"}, detail: "A policy in the policy set", fileTypes: primaryFileTypes},
+	{
+		name:           "import",
+		labels:         []string{"kind", "name"},
+		detail:         "An import which policies can use",
+		minimumVersion: features.V2ImportBlockMinimumVersion,
+		fileTypes:      allFileTypes,
+	},
+	{
+		name:           "import",
+		labels:         []string{"name"},
+		detail:         "A plugin import which policies can use",
+		maximumVersion: features.V2ImportBlockMinimumVersion,
+		fileTypes:      allFileTypes,
+	},
+	{
+		name:           "module",
+		labels:         []string{"name"},
+		detail:         "A module which policies can import",
+		maximumVersion: features.V2ImportBlockMinimumVersion,
+		fileTypes:      allFileTypes,
+	},
+	{name: "param", labels: []string{"name"}, detail: "The value of a policy parameter", fileTypes: allFileTypes},
+	{name: "mock", labels: []string{"name"}, detail: "Mock data for an import", fileTypes: allFileTypes},
+	{name: "global", labels: []string{"name"}, detail: "A global value for policies", fileTypes: allFileTypes},
+	{name: "test", detail: "The expected results of the rules", fileTypes: []filetypes.FileType{filetypes.ConfigTestFileType}},
+}
+
+var sourceAttribute = configAttribute{name: "source", detail: "The path of the file"}
+
+var pluginAttributes = []configAttribute{
+	{name: "args", detail: "The arguments of the plugin"},
+	{name: "env", detail: "The environment variables of the plugin"},
+	{name: "config", detail: "The configuration of the plugin"},
+}
+
+// The attributes of a block, which can depend on its labels
+func blockAttributes(block ConfigBlock, sentinelVersion string) []configAttribute {
+	switch block.Type {
+	case "sentinel":
+		return []configAttribute{{name: "features", detail: "The optional features to enable"}}
+	case "policy":
+		return []configAttribute{
+			sourceAttribute,
+			{name: "enforcement_level", detail: "What happens when the policy fails"},
+			{name: "params", detail: "The values of the policy parameters", minimumVersion: features.ParamsInPolicyMinimumVersion},
+		}
+	case "import":
+		if features.UnsupportedVersion(sentinelVersion, features.V2ImportBlockMinimumVersion) {
+			return append([]configAttribute{{name: "path", detail: "The path of the plugin"}}, pluginAttributes...)
+		}
+		kind := ""
+		if len(block.Labels) > 0 {
+			kind = block.Labels[0]
+		}
+		switch kind {
+		case "plugin":
+			return append([]configAttribute{sourceAttribute}, pluginAttributes...)
+		case "static":
+			return []configAttribute{sourceAttribute, {name: "format", detail: "The format of the data"}}
+		default:
+			return []configAttribute{sourceAttribute}
+		}
+	case "module":
+		// The module block in a mock does not have labels
+		return []configAttribute{sourceAttribute}
+	case "mock":
+		return []configAttribute{{name: "data", detail: "The mock data"}}
+	case "global", "param":
+		return []configAttribute{{name: "value", detail: "The value"}}
+	case "test":
+		return []configAttribute{{name: "rules", detail: "The expected result of each rule"}}
+	}
+	return nil
+}
+
+func versionInRange(sentinelVersion, minimumVersion, maximumVersion string) bool {
+	if minimumVersion != "" && features.UnsupportedVersion(sentinelVersion, minimumVersion) {
+		return false
+	}
+	if maximumVersion != "" && features.SupportedVersion(sentinelVersion, maximumVersion) {
+		return false
+	}
+	return true
+}
+
+// BodyItems are the block types, attributes and nested blocks which can be written in a
+// body. Snippets are only used if the client supports them.
+func BodyItems(cc ConfigContext, fileType filetypes.FileType, sentinelVersion string, snippets bool) []lsp.CompletionItem {
+	items := make([]lsp.CompletionItem, 0)
+
+	block, ok := cc.InnermostBlock()
+	if !ok {
+		for _, bt := range configBlockTypes {
+			if !versionInRange(sentinelVersion, bt.minimumVersion, bt.maximumVersion) || !slices.Contains(bt.fileTypes, fileType) {
+				continue
+			}
+			items = append(items, blockItem(bt.name, bt.labels, bt.detail, snippets))
+		}
+		return items
+	}
+
+	for _, attr := range blockAttributes(block, sentinelVersion) {
+		if !versionInRange(sentinelVersion, attr.minimumVersion, "") {
+			continue
+		}
+		item := lsp.CompletionItem{
+			Label:      attr.name,
+			Kind:       lsp.PropertyCompletion,
+			Detail:     attr.detail,
+			InsertText: attr.name + " = ",
+		}
+		if snippets {
+			item.InsertText = attr.name + " = $0"
+			item.InsertTextFormat = lsp.SnippetTextFormat
+		}
+		items = append(items, item)
+	}
+	if block.Type == "mock" {
+		items = append(items, blockItem("module", nil, "A mock of a module", snippets))
+	}
+	return items
+}
+
+func blockItem(name string, labels []string, detail string, snippets bool) lsp.CompletionItem {
+	item := lsp.CompletionItem{
+		Label:      name,
+		Kind:       lsp.KeywordCompletion,
+		Detail:     detail,
+		InsertText: name,
+	}
+	if len(labels) > 0 {
+		item.Label = fmt.Sprintf("%s \"%s\"", name, strings.Join(labels, "\" \""))
+		item.FilterText = name
+	}
+	if snippets {
+		text := name
+		for idx, label := range labels {
+			text += fmt.Sprintf(" \"${%d:%s}\"", idx+1, label)
+		}
+		item.InsertText = text + " {\n\t$0\n}"
+		item.InsertTextFormat = lsp.SnippetTextFormat
+	}
+	return item
+}
+
+// LabelItems are the fixed values of a block label, for example the kind of an import
+func LabelItems(cc ConfigContext, sentinelVersion string) []lsp.CompletionItem {
+	if cc.Block.Type != "import" || len(cc.Block.Labels) > 0 || len(cc.Blocks) > 0 ||
+		features.UnsupportedVersion(sentinelVersion, features.V2ImportBlockMinimumVersion) {
+		return nil
+	}
+	return ValueItems(cc, importKinds, lsp.EnumMemberCompletion, "Import kind")
+}
+
+// EnumeratedValues are the fixed values of an attribute, for example enforcement_level
+func EnumeratedValues(cc ConfigContext) []string {
+	block, ok := cc.InnermostBlock()
+	if !ok {
+		return nil
+	}
+	switch {
+	case block.Type == "policy" && cc.Attribute == "enforcement_level":
+		return configuration.EnforcementLevels
+	case block.Type == "import" && cc.Attribute == "format":
+		return []string{"json"}
+	}
+	return nil
+}
+
+// EnumeratedKeys are the fixed keys of an object attribute, for example the features of
+// the sentinel block
+func EnumeratedKeys(cc ConfigContext) []string {
+	if block, ok := cc.InnermostBlock(); ok && block.Type == "sentinel" && cc.Attribute == "features" {
+		return []string{"apply-all", "terraform"}
+	}
+	return nil
+}
+
+// ValueItems complete a string value, which is quoted if the position is not in a string
+// already
+func ValueItems(cc ConfigContext, values []string, kind lsp.CompletionItemKind, detail string) []lsp.CompletionItem {
+	items := make([]lsp.CompletionItem, 0, len(values))
+	for _, value := range values {
+		text := value
+		if !cc.Quoted {
+			text = fmt.Sprintf("%q", value)
+		}
+		items = append(items, lsp.CompletionItem{
+			Label:      value,
+			Kind:       kind,
+			Detail:     detail,
+			InsertText: text,
+			FilterText: text,
+		})
+	}
+	return items
+}
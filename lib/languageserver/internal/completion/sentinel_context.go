@@ -0,0 +1,200 @@
+package completion
+
+import (
+	"bytes"
+
+	sast "github.com/glennsarti/sentinel-parser/sentinel/ast"
+	sparser "github.com/glennsarti/sentinel-parser/sentinel/parser"
+)
+
+// SentinelPositionKind is what can be written at a position in a Sentinel file
+type SentinelPositionKind int
+
+const (
+	// Nothing can be completed, for example in a comment
+	NoSentinelPosition SentinelPositionKind = iota
+	// An identifier or keyword in an expression or statement
+	ExpressionPosition
+	// A member after a dot, for example the name in module.name
+	MemberPosition
+	// The name of an import, after the import keyword
+	ImportNamePosition
+)
+
+// SentinelContext describes a position in a Sentinel file
+type SentinelContext struct {
+	Kind SentinelPositionKind
+	// The identifier before the dot of a member, or empty if it is not an identifier
+	Receiver string
+	// The text before the position which is being completed, and the byte offset it
+	// starts at
+	Prefix      string
+	PrefixStart int
+	// Whether the position is in a quoted string
+	Quoted bool
+}
+
+// SentinelContextAt describes the byte offset in a Sentinel file. Only the text before the
+// offset is read, so the file does not need to be valid.
+func SentinelContextAt(text []byte, offset int) SentinelContext {
+	if offset < 0 || offset > len(text) {
+		return SentinelContext{}
+	}
+	src := text[:offset]
+
+	// Find whether the offset is in a comment or string
+	for idx := 0; idx < len(src); idx++ {
+		switch {
+		case src[idx] == '#' || bytes.HasPrefix(src[idx:], []byte("//")):
+			end := bytes.IndexByte(src[idx:], '\n')
+			if end < 0 {
+				return SentinelContext{}
+			}
+			idx += end
+		case bytes.HasPrefix(src[idx:], []byte("/*")):
+			end := bytes.Index(src[idx+2:], []byte("*/"))
+			if end < 0 {
+				return SentinelContext{}
+			}
+			idx += end + 3
+		case src[idx] == '"':
+			end := stringEnd(src, idx)
+			if end < 0 {
+				// Strings which are not closed are usually being written, so they end
+				// on the line they start on
+				if newline := bytes.IndexByte(src[idx:], '\n'); newline >= 0 {
+					idx += newline
+					continue
+				}
+				// Only the names of imports are completed in strings
+				if precedingWord(src, idx) != "import" {
+					return SentinelContext{}
+				}
+				return SentinelContext{
+					Kind:        ImportNamePosition,
+					Prefix:      string(src[idx+1:]),
+					PrefixStart: idx + 1,
+					Quoted:      true,
+				}
+			}
+			idx = end
+		}
+	}
+
+	result := SentinelContext{Kind: ExpressionPosition, PrefixStart: offset}
+	start := identifierStart(src, offset)
+	if start < offset && isDigit(src[start]) {
+		return SentinelContext{}
+	}
+	result.Prefix = string(src[start:offset])
+	result.PrefixStart = start
+
+	switch {
+	case start > 0 && src[start-1] == '.':
+		result.Kind = MemberPosition
+		receiverStart := identifierStart(src, start-1)
+		// Only simple identifiers are resolved, not a.b.c
+		if receiverStart < start-1 && (receiverStart == 0 || src[receiverStart-1] != '.') {
+			result.Receiver = string(src[receiverStart : start-1])
+		}
+	case precedingWord(src, start) == "import":
+		result.Kind = ImportNamePosition
+	}
+	return result
+}
+
+// The index of the quote which ends the string starting at the index, or -1
+func stringEnd(src []byte, start int) int {
+	for idx := start + 1; idx < len(src); idx++ {
+		switch src[idx] {
+		case '\\':
+			idx++
+		case '"':
+			return idx
+		}
+	}
+	return -1
+}
+
+// The word before the index, ignoring whitespace
+func precedingWord(src []byte, idx int) string {
+	end := idx
+	for end > 0 && (src[end-1] == ' ' || src[end-1] == '\t') {
+		end--
+	}
+	return string(src[identifierStart(src, end):end])
+}
+
+// The start of the identifier which ends at the index
+func identifierStart(src []byte, end int) int {
+	start := end
+	for start > 0 && isIdentifierByte(src[start-1]) {
+		start--
+	}
+	return start
+}
+
+func isIdentifierByte(b byte) bool {
+	return b == '_' || isDigit(b) || (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z')
+}
+
+func isDigit(b byte) bool {
+	return b >= '0' && b <= '9'
+}
+
+// completionPlaceholder is written at the position when repairing a file
+const completionPlaceholder = "__completion__"
+
+// ParseSentinelFile parses a Sentinel file which is being written at the offset. Partly
+// written statements are often syntax errors, which hide the rest of the file, so repaired
+// copies of the file are parsed until one is valid. Returns the file and the text which was
+// parsed. Byte offsets before the position are the same as in the original text.
+func ParseSentinelFile(sentinelVersion, filename string, text []byte, offset int) (*sast.File, []byte) {
+	if offset < 0 || offset > len(text) {
+		offset = len(text)
+	}
+	lineStart := bytes.LastIndexByte(text[:offset], '\n') + 1
+	lineEnd := len(text)
+	if idx := bytes.IndexByte(text[offset:], '\n'); idx >= 0 {
+		lineEnd = offset + idx
+	}
+
+	candidates := [][]byte{
+		text,
+		// An identifier completes expressions such as "a." or "a >"
+		concat(text[:offset], []byte(completionPlaceholder), text[offset:]),
+		// Without the line being written
+		concat(text[:lineStart], bytes.Repeat([]byte(" "), lineEnd-lineStart), text[lineEnd:]),
+	}
+
+	var fallback *sast.File
+	var fallbackText []byte
+	for _, candidate := range candidates {
+		file, valid := parseQuietly(sentinelVersion, filename, candidate)
+		if valid {
+			return file, candidate
+		}
+		if fallback == nil && file != nil {
+			fallback, fallbackText = file, candidate
+		}
+	}
+	return fallback, fallbackText
+}
+
+// Parses a file, and recovers if the parser fails on text it does not expect
+func parseQuietly(sentinelVersion, filename string, text []byte) (file *sast.File, valid bool) {
+	defer func() {
+		if recover() != nil {
+			file, valid = nil, false
+		}
+	}()
+	file, _, diags, err := sparser.ParseFile(sentinelVersion, filename, text)
+	if err != nil {
+		return nil, false
+	}
+	return file, !diags.HasErrors()
+}
+
+func concat(parts ...[]byte) []byte {
+	return bytes.Join(parts, nil)
+}
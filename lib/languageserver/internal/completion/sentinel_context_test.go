@@ -0,0 +1,89 @@
+package completion
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/glennsarti/sentinel-utils/lib/languageserver/internal/symbols"
+)
+
+func TestSentinelContextAt(t *testing.T) {
+	for _, testcase := range []struct {
+		name     string
+		text     string
+		kind     SentinelPositionKind
+		receiver string
+		prefix   string
+		quoted   bool
+	}{
+		{name: "start of a statement", text: "x = 1\n|", kind: ExpressionPosition},
+		{name: "identifier", text: "x = lim|", kind: ExpressionPosition, prefix: "lim"},
+		{name: "member", text: "x = helpers.ch|", kind: MemberPosition, receiver: "helpers", prefix: "ch"},
+		{name: "member after a dot", text: "x = helpers.|", kind: MemberPosition, receiver: "helpers"},
+		{name: "nested member", text: "x = a.b.|", kind: MemberPosition},
+		{name: "import string", text: "import \"str|", kind: ImportNamePosition, prefix: "str", quoted: true},
+		{name: "import name", text: "import st|", kind: ImportNamePosition, prefix: "st"},
+		{name: "other string", text: "x = \"ab|", kind: NoSentinelPosition},
+		{name: "after a string", text: "x = \"a\\\"b\" + |", kind: ExpressionPosition},
+		{name: "after an unclosed string", text: "import \"\nx = l|", kind: ExpressionPosition, prefix: "l"},
+		{name: "line comment", text: "x = 1 # a|", kind: NoSentinelPosition},
+		{name: "after a line comment", text: "// a\n|", kind: ExpressionPosition},
+		{name: "block comment", text: "/* a |", kind: NoSentinelPosition},
+		{name: "after a block comment", text: "/* a */ x|", kind: ExpressionPosition, prefix: "x"},
+		{name: "number", text: "x = 12|", kind: NoSentinelPosition},
+	} {
+		t.Run(testcase.name, func(t *testing.T) {
+			offset := strings.Index(testcase.text, "|")
+			text := []byte(strings.Replace(testcase.text, "|", "", 1))
+			sc := SentinelContextAt(text, offset)
+
+			if sc.Kind != testcase.kind {
+				t.Fatalf("expected the kind %d but got %d", testcase.kind, sc.Kind)
+			}
+			if sc.Kind == NoSentinelPosition {
+				return
+			}
+			if sc.Receiver != testcase.receiver {
+				t.Errorf("expected the receiver %q but got %q", testcase.receiver, sc.Receiver)
+			}
+			if sc.Prefix != testcase.prefix || sc.PrefixStart != offset-len(testcase.prefix) {
+				t.Errorf("expected the prefix %q at %d but got %q at %d", testcase.prefix, offset-len(testcase.prefix), sc.Prefix, sc.PrefixStart)
+			}
+			if sc.Quoted != testcase.quoted {
+				t.Errorf("expected quoted to be %t", testcase.quoted)
+			}
+		})
+	}
+}
+
+func TestParseSentinelFile(t *testing.T) {
+	for _, testcase := range []struct {
+		name string
+		text string
+	}{
+		{name: "member", text: "import \"helpers\"\nlimit = 10\nmain = rule {\n  limit > helpers.|\n}\nlast = 1\n"},
+		{name: "identifier", text: "import \"helpers\"\nlimit = 10\nmain = rule {\n  limit > |\n}\nlast = 1\n"},
+		{name: "statement", text: "import \"helpers\"\nlimit = 10\nif li|\nlast = 1\nmain = rule { true }\n"},
+	} {
+		t.Run(testcase.name, func(t *testing.T) {
+			offset := strings.Index(testcase.text, "|")
+			text := []byte(strings.Replace(testcase.text, "|", "", 1))
+			file, parsed := ParseSentinelFile("", "policy.sentinel", text, offset)
+			if file == nil {
+				t.Fatal("expected the file to be parsed")
+			}
+			// The line being written can be removed, but the lines before it are unchanged
+			lineStart := strings.LastIndex(string(text[:offset]), "\n") + 1
+			if len(parsed) < offset || string(parsed[:lineStart]) != string(text[:lineStart]) {
+				t.Error("expected the text before the line to be unchanged")
+			}
+
+			idx := symbols.NewIndex(file, parsed)
+			for _, name := range []string{"helpers", "limit", "last", "main"} {
+				if idx.Lookup(name, offset) == nil {
+					t.Errorf("expected %s to be declared", name)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package completion
+
+import (
+	"strings"
+
+	"github.com/glennsarti/sentinel-parser/sentinel/token"
+	lsp "github.com/glennsarti/sentinel-utils/lib/languageserver/internal/protocol"
+	"github.com/glennsarti/sentinel-utils/lib/languageserver/internal/symbols"
+)
+
+// The keywords which can start an expression or statement. The lexer decides which
+// keywords the Sentinel version has.
+var sentinelKeywords = []string{
+	"all", "and", "any", "as", "break", "case", "contains", "continue", "default", "defined",
+	"else", "empty", "filter", "for", "func", "if", "import", "in", "is", "map", "matches",
+	"not", "or", "param", "return", "rule", "when", "xor",
+}
+
+// A built in function or standard import, and the first Sentinel version with it
+type versionedName struct {
+	name string
+	// Empty for all versions
+	minimumVersion string
+}
+
+var builtinFunctions = []versionedName{
+	{name: "append"},
+	{name: "bool"},
+	{name: "delete"},
+	{name: "error", minimumVersion: "v0.15.0"},
+	{name: "float"},
+	{name: "int"},
+	{name: "keys"},
+	{name: "length"},
+	{name: "print"},
+	{name: "range", minimumVersion: "v0.13.0"},
+	{name: "string"},
+	{name: "values"},
+}
+
+var builtinValues = []string{"false", "null", "true", "undefined"}
+
+// StandardImports are the imports in the Sentinel standard library
+var StandardImports = []versionedName{
+	{name: "base64", minimumVersion: "v0.15.0"},
+	{name: "decimal", minimumVersion: "v0.11.0"},
+	{name: "http", minimumVersion: "v0.13.0"},
+	{name: "json"},
+	{name: "runtime", minimumVersion: "v0.10.0"},
+	{name: "sockaddr", minimumVersion: "v0.10.0"},
+	{name: "strings"},
+	{name: "time"},
+	{name: "types"},
+	{name: "units"},
+	{name: "version", minimumVersion: "v0.15.0"},
+}
+
+// KeywordItems are the keywords and built in functions and values of the Sentinel version
+func KeywordItems(sentinelVersion string, snippets bool) []lsp.CompletionItem {
+	items := make([]lsp.CompletionItem, 0, len(sentinelKeywords)+len(builtinFunctions)+len(builtinValues))
+	for _, keyword := range sentinelKeywords {
+		if token.LookupIdent(sentinelVersion, keyword) == token.IDENT {
+			continue
+		}
+		items = append(items, lsp.CompletionItem{Label: keyword, Kind: lsp.KeywordCompletion})
+	}
+	for _, fn := range builtinFunctions {
+		if versionInRange(sentinelVersion, fn.minimumVersion, "") {
+			items = append(items, functionItem(fn.name, "built in function", snippets))
+		}
+	}
+	for _, name := range builtinValues {
+		items = append(items, lsp.CompletionItem{Label: name, Kind: lsp.ConstantCompletion})
+	}
+	return items
+}
+
+// SymbolItems are the rules, functions, variables, params and imports which can be used
+func SymbolItems(syms []*symbols.Symbol, snippets bool) []lsp.CompletionItem {
+	items := make([]lsp.CompletionItem, 0, len(syms))
+	for _, s := range syms {
+		// Repaired files can declare the placeholder, and broken files can have imports
+		// with names which are not identifiers
+		if strings.Contains(s.Name, completionPlaceholder) || !isIdentifier(s.Name) {
+			continue
+		}
+		var item lsp.CompletionItem
+		switch s.Kind {
+		case symbols.Function:
+			item = functionItem(s.Name, s.Declaration, snippets)
+		case symbols.Import:
+			item = lsp.CompletionItem{Label: s.Name, Kind: lsp.ModuleCompletion, Detail: s.Declaration}
+		case symbols.Rule:
+			item = lsp.CompletionItem{Label: s.Name, Kind: lsp.FieldCompletion, Detail: s.Declaration}
+		default:
+			item = lsp.CompletionItem{Label: s.Name, Kind: lsp.VariableCompletion, Detail: s.Declaration}
+		}
+		item.Documentation = s.Doc
+		items = append(items, item)
+	}
+	return items
+}
+
+func isIdentifier(name string) bool {
+	if name == "" || isDigit(name[0]) {
+		return false
+	}
+	for idx := 0; idx < len(name); idx++ {
+		if !isIdentifierByte(name[idx]) {
+			return false
+		}
+	}
+	return true
+}
+
+func functionItem(name, detail string, snippets bool) lsp.CompletionItem {
+	item := lsp.CompletionItem{Label: name, Kind: lsp.FunctionCompletion, Detail: detail}
+	if snippets {
+		item.InsertText = name + "($0)"
+		item.InsertTextFormat = lsp.SnippetTextFormat
+	}
+	return item
+}
+
+// ImportItems are the names of the configured imports and the standard imports of the
+// Sentinel version. Names are quoted if the position is not in a string already.
+func ImportItems(sc SentinelContext, configured []string, sentinelVersion string) []lsp.CompletionItem {
+	items := make([]lsp.CompletionItem, 0, len(configured)+len(StandardImports))
+	add := func(name, detail string) {
+		text := name
+		if !sc.Quoted {
+			text = "\"" + name + "\""
+		}
+		items = append(items, lsp.CompletionItem{
+			Label:      name,
+			Kind:       lsp.ModuleCompletion,
+			Detail:     detail,
+			InsertText: text,
+			FilterText: text,
+		})
+	}
+	for _, name := range configured {
+		add(name, "configured import")
+	}
+	for _, imp := range StandardImports {
+		if versionInRange(sentinelVersion, imp.minimumVersion, "") {
+			add(imp.name, "standard import")
+		}
+	}
+	return items
+}
@@ -0,0 +1,38 @@
+package completion
+
+import (
+	"slices"
+	"testing"
+
+	lsp "github.com/glennsarti/sentinel-utils/lib/languageserver/internal/protocol"
+)
+
+func TestItemsSentinelVersion(t *testing.T) {
+	labels := func(items []lsp.CompletionItem) []string {
+		result := make([]string, 0, len(items))
+		for _, item := range items {
+			result = append(result, item.Label)
+		}
+		return result
+	}
+
+	for _, testcase := range []struct {
+		sentinelVersion string
+		name            string
+		expected        bool
+	}{
+		{sentinelVersion: "v0.12.0", name: "range", expected: false},
+		{sentinelVersion: "v0.13.0", name: "range", expected: true},
+		{sentinelVersion: "", name: "error", expected: true},
+		{sentinelVersion: "v0.14.0", name: "version", expected: false},
+		{sentinelVersion: "latest", name: "version", expected: true},
+		{sentinelVersion: "v0.1.0", name: "strings", expected: true},
+	} {
+		t.Run(testcase.sentinelVersion+" "+testcase.name, func(t *testing.T) {
+			items := append(KeywordItems(testcase.sentinelVersion, false), ImportItems(SentinelContext{}, nil, testcase.sentinelVersion)...)
+			if actual := slices.Contains(labels(items), testcase.name); actual != testcase.expected {
+				t.Errorf("expected %q to be completed %t but got %t", testcase.name, testcase.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,29 @@
+package contexts
+
+import (
+	"context"
+	"errors"
+)
+
+type lintModeContextKey struct{}
+
+func SetLintMode(ctx context.Context, value *string) error {
+	if ret, ok := ctx.Value(lintModeContextKey{}).(*string); !ok {
+		return errors.New("lint mode not found")
+	} else {
+		*ret = *value
+	}
+	return nil
+}
+
+func WithLintMode(ctx context.Context, value *string) context.Context {
+	return context.WithValue(ctx, lintModeContextKey{}, value)
+}
+
+func LintMode(ctx context.Context) (string, error) {
+	if value, ok := ctx.Value(lintModeContextKey{}).(*string); !ok {
+		return "", errors.New("lint mode not found")
+	} else {
+		return *value, nil
+	}
+}
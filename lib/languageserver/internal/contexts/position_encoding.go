@@ -0,0 +1,31 @@
+package contexts
+
+import (
+	"context"
+	"errors"
+
+	lsp "github.com/glennsarti/sentinel-utils/lib/languageserver/internal/protocol"
+)
+
+type positionEncodingContextKey struct{}
+
+func SetPositionEncoding(ctx context.Context, value *lsp.PositionEncodingKind) error {
+	if ret, ok := ctx.Value(positionEncodingContextKey{}).(*lsp.PositionEncodingKind); !ok {
+		return errors.New("position encoding not found")
+	} else {
+		*ret = *value
+	}
+	return nil
+}
+
+func WithPositionEncoding(ctx context.Context, value *lsp.PositionEncodingKind) context.Context {
+	return context.WithValue(ctx, positionEncodingContextKey{}, value)
+}
+
+func PositionEncoding(ctx context.Context) (lsp.PositionEncodingKind, error) {
+	if value, ok := ctx.Value(positionEncodingContextKey{}).(*lsp.PositionEncodingKind); !ok {
+		return "", errors.New("position encoding not found")
+	} else {
+		return *value, nil
+	}
+}
@@ -0,0 +1,29 @@
+package document
+
+import (
+	"bytes"
+
+	"github.com/glennsarti/sentinel-parser/position"
+)
+
+// BlockRange is the range of a configuration block from its header to the closing brace.
+// The parser only gives the range of the header.
+func BlockRange(text []byte, header position.SourceRange) position.SourceRange {
+	depth := 0
+	for i := header.End.Byte; i >= 0 && i < len(text); i++ {
+		switch text[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				end := header.End
+				end.Byte = i + 1
+				end.Line += bytes.Count(text[header.End.Byte:i], []byte("\n"))
+				end.Column = i + 1 - (bytes.LastIndexByte(text[:i], '\n') + 1)
+				return position.SourceRange{Filename: header.Filename, Start: header.Start, End: end}
+			}
+		}
+	}
+	return header
+}
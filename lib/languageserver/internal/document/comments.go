@@ -0,0 +1,48 @@
+package document
+
+import (
+	"strings"
+)
+
+// CommentsAbove returns the text of the line comments directly above the line containing
+// the offset, without the comment prefixes. Sentinel and HCL files both use # and // for
+// line comments. Block comments are not included.
+func CommentsAbove(text []byte, offset int) string {
+	lineStart := lineStartOffset(text, max(0, min(offset, len(text))))
+
+	lines := make([]string, 0)
+	for lineStart > 0 {
+		// The end of the previous line, treating \r\n as one line ending
+		end := lineStart - 1
+		if end > 0 && text[end] == '\n' && text[end-1] == '\r' {
+			end--
+		}
+		start := lineStartOffset(text, end)
+
+		comment, ok := lineComment(string(text[start:end]))
+		if !ok {
+			break
+		}
+		lines = append([]string{comment}, lines...)
+		lineStart = start
+	}
+	return strings.Join(lines, "\n")
+}
+
+func lineComment(line string) (string, bool) {
+	line = strings.TrimSpace(line)
+	for _, prefix := range []string{"#", "//"} {
+		if strings.HasPrefix(line, prefix) {
+			return strings.TrimPrefix(line[len(prefix):], " "), true
+		}
+	}
+	return "", false
+}
+
+// The offset of the start of the line containing the offset
+func lineStartOffset(text []byte, offset int) int {
+	for offset > 0 && text[offset-1] != '\n' && text[offset-1] != '\r' {
+		offset--
+	}
+	return offset
+}
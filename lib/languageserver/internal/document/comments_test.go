@@ -0,0 +1,16 @@
+package document
+
+import (
+	"testing"
+)
+
+func TestCommentsAbove(t *testing.T) {
+	text := []byte("x = 1\n\n# The limit\r\n// in bytes\nlimit = 10\n")
+
+	if actual := CommentsAbove(text, 38); actual != "The limit\nin bytes" {
+		t.Errorf("expected the comments above the line but got %q", actual)
+	}
+	if actual := CommentsAbove(text, 2); actual != "" {
+		t.Errorf("expected no comments above the first line but got %q", actual)
+	}
+}
@@ -0,0 +1,126 @@
+package document
+
+import (
+	"fmt"
+	"slices"
+	"unicode/utf8"
+
+	"github.com/glennsarti/sentinel-parser/position"
+	lsp "github.com/glennsarti/sentinel-utils/lib/languageserver/internal/protocol"
+)
+
+// NegotiateEncoding chooses the position encoding to use with a client. UTF-8 is used when
+// the client supports it, because it is what the parser uses, otherwise UTF-16 which every
+// client must support.
+func NegotiateEncoding(clientEncodings []lsp.PositionEncodingKind) lsp.PositionEncodingKind {
+	if slices.Contains(clientEncodings, lsp.UTF8) {
+		return lsp.UTF8
+	}
+	return lsp.UTF16
+}
+
+// PositionToOffset converts an LSP position to a byte offset in the text. The character is
+// counted in the code units of the encoding, and a character past the end of the line is
+// the end of the line.
+func PositionToOffset(text []byte, pos lsp.Position, enc lsp.PositionEncodingKind) (int, error) {
+	offset := 0
+	for line := uint32(0); line < pos.Line; line++ {
+		next := nextLineStart(text, offset)
+		if next < 0 {
+			return 0, fmt.Errorf("%w: line %d", ErrPositionOutOfRange, pos.Line)
+		}
+		offset = next
+	}
+
+	units := uint32(0)
+	for offset < len(text) && units < pos.Character {
+		if text[offset] == '\n' || text[offset] == '\r' {
+			break
+		}
+		r, size := utf8.DecodeRune(text[offset:])
+		units += codeUnits(r, size, enc)
+		offset += size
+	}
+	return offset, nil
+}
+
+// OffsetToPosition converts a byte offset in the text to an LSP position, with the character
+// counted in the code units of the encoding. Offsets outside of the text are clamped to it.
+func OffsetToPosition(text []byte, offset int, enc lsp.PositionEncodingKind) lsp.Position {
+	offset = max(0, min(offset, len(text)))
+
+	pos := lsp.Position{}
+	lineStart := 0
+	for {
+		next := nextLineStart(text, lineStart)
+		if next < 0 || next > offset {
+			break
+		}
+		lineStart = next
+		pos.Line++
+	}
+
+	for idx := lineStart; idx < offset; {
+		r, size := utf8.DecodeRune(text[idx:])
+		pos.Character += codeUnits(r, size, enc)
+		idx += size
+	}
+	return pos
+}
+
+// ToRange converts a parser range in the text to an LSP range.
+func ToRange(text []byte, rng position.SourceRange, enc lsp.PositionEncodingKind) lsp.Range {
+	return lsp.Range{
+		Start: OffsetToPosition(text, sourcePosToOffset(text, rng.Start), enc),
+		End:   OffsetToPosition(text, sourcePosToOffset(text, rng.End), enc),
+	}
+}
+
+// The parser counts columns in bytes for Sentinel files, but in characters for configuration
+// files, so the byte offset is used when it is on the same line as the position. Otherwise
+// the column is assumed to be in bytes.
+func sourcePosToOffset(text []byte, pos position.SourcePos) int {
+	if pos.Line < 0 {
+		return 0
+	}
+
+	lineStart := 0
+	for line := 0; line < pos.Line; line++ {
+		next := nextLineStart(text, lineStart)
+		if next < 0 {
+			return len(text)
+		}
+		lineStart = next
+	}
+	lineEnd := lineEndOffset(text, lineStart)
+
+	if pos.Byte >= lineStart && pos.Byte <= lineEnd {
+		return pos.Byte
+	}
+	return lineStart + max(0, min(pos.Column, lineEnd-lineStart))
+}
+
+// The offset of the line ending, or the end of the text, for the line starting at the offset
+func lineEndOffset(text []byte, lineStart int) int {
+	for idx := lineStart; idx < len(text); idx++ {
+		if text[idx] == '\n' || text[idx] == '\r' {
+			return idx
+		}
+	}
+	return len(text)
+}
+
+// The number of code units the character takes in the encoding. Size is the number of bytes
+// it takes in UTF-8.
+func codeUnits(r rune, size int, enc lsp.PositionEncodingKind) uint32 {
+	switch enc {
+	case lsp.UTF8:
+		return uint32(size)
+	case lsp.UTF32:
+		return 1
+	}
+	if r >= 0x10000 {
+		return 2
+	}
+	return 1
+}
@@ -0,0 +1,111 @@
+package document
+
+import (
+	"testing"
+
+	"github.com/glennsarti/sentinel-parser/position"
+	lsp "github.com/glennsarti/sentinel-utils/lib/languageserver/internal/protocol"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	if actual := NegotiateEncoding(nil); actual != lsp.UTF16 {
+		t.Errorf("expected %s when the client does not say, but got %s", lsp.UTF16, actual)
+	}
+	if actual := NegotiateEncoding([]lsp.PositionEncodingKind{lsp.UTF16, lsp.UTF8}); actual != lsp.UTF8 {
+		t.Errorf("expected %s when the client supports it, but got %s", lsp.UTF8, actual)
+	}
+	if actual := NegotiateEncoding([]lsp.PositionEncodingKind{lsp.UTF32}); actual != lsp.UTF16 {
+		t.Errorf("expected %s when the client does not support %s, but got %s", lsp.UTF16, lsp.UTF8, actual)
+	}
+}
+
+func TestToRange(t *testing.T) {
+	// é is two bytes and one UTF-16 code unit, and 😀 is four bytes and two UTF-16 code units
+	text := []byte("# é😀\r\nmain = \"😀\" is x\n")
+
+	for _, testcase := range []struct {
+		name     string
+		rng      position.SourceRange
+		encoding lsp.PositionEncodingKind
+		expected lsp.Range
+	}{
+		{
+			name: "byte columns in UTF-16",
+			rng: position.SourceRange{
+				Start: position.SourcePos{Line: 1, Column: 14, Byte: 24},
+				End:   position.SourcePos{Line: 1, Column: 18, Byte: 28},
+			},
+			encoding: lsp.UTF16,
+			expected: *rng(1, 12, 1, 16),
+		},
+		{
+			name: "byte columns in UTF-8",
+			rng: position.SourceRange{
+				Start: position.SourcePos{Line: 1, Column: 14, Byte: 24},
+				End:   position.SourcePos{Line: 1, Column: 18, Byte: 28},
+			},
+			encoding: lsp.UTF8,
+			expected: *rng(1, 14, 1, 18),
+		},
+		{
+			// Configuration files count columns in characters, but the byte offset is right
+			name: "character columns",
+			rng: position.SourceRange{
+				Start: position.SourcePos{Line: 0, Column: 2, Byte: 2},
+				End:   position.SourcePos{Line: 0, Column: 4, Byte: 8},
+			},
+			encoding: lsp.UTF16,
+			expected: *rng(0, 2, 0, 5),
+		},
+		{
+			name: "without a byte offset",
+			rng: position.SourceRange{
+				Start: position.SourcePos{Line: 1, Column: 7, Byte: 0},
+				End:   position.SourcePos{Line: 1, Column: 100, Byte: 0},
+			},
+			encoding: lsp.UTF16,
+			expected: *rng(1, 7, 1, 16),
+		},
+		{
+			name: "invalid range",
+			rng: position.SourceRange{
+				Start: position.SourcePos{Line: -1, Column: -1, Byte: -1},
+				End:   position.SourcePos{Line: -1, Column: -1, Byte: -1},
+			},
+			encoding: lsp.UTF16,
+			expected: *rng(0, 0, 0, 0),
+		},
+	} {
+		t.Run(testcase.name, func(t *testing.T) {
+			actual := ToRange(text, testcase.rng, testcase.encoding)
+			if actual != testcase.expected {
+				t.Errorf("expected %v but got %v", testcase.expected, actual)
+			}
+		})
+	}
+}
+
+func TestOffsetToPositionRoundTrip(t *testing.T) {
+	text := []byte("a = \"é😀\"\nb = 1\r\nc = 2")
+
+	for _, encoding := range []lsp.PositionEncodingKind{lsp.UTF8, lsp.UTF16, lsp.UTF32} {
+		for offset := range len(text) + 1 {
+			// Offsets inside of a character or a line ending are not on a position
+			if offset < len(text) && (!isCharacterStart(text[offset]) || (offset > 0 && text[offset-1] == '\r')) {
+				continue
+			}
+			pos := OffsetToPosition(text, offset, encoding)
+			actual, err := PositionToOffset(text, pos, encoding)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if actual != offset {
+				t.Errorf("%s: expected offset %d to round trip through %v but got %d", encoding, offset, pos, actual)
+			}
+		}
+	}
+}
+
+func isCharacterStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
@@ -0,0 +1,58 @@
+package document
+
+import (
+	"errors"
+	"fmt"
+
+	lsp "github.com/glennsarti/sentinel-utils/lib/languageserver/internal/protocol"
+)
+
+var ErrPositionOutOfRange = errors.New("position is outside of the document")
+
+// ApplyChanges applies the content changes, in order. A change without a range replaces
+// the whole text. The ranges use the position encoding.
+func ApplyChanges(text []byte, changes []lsp.TextDocumentContentChangeEvent, enc lsp.PositionEncodingKind) ([]byte, error) {
+	for _, change := range changes {
+		if change.Range == nil {
+			text = []byte(change.Text)
+			continue
+		}
+
+		start, err := PositionToOffset(text, change.Range.Start, enc)
+		if err != nil {
+			return nil, err
+		}
+		end, err := PositionToOffset(text, change.Range.End, enc)
+		if err != nil {
+			return nil, err
+		}
+		if end < start {
+			return nil, fmt.Errorf("the range %d:%d -> %d:%d ends before it starts",
+				change.Range.Start.Line, change.Range.Start.Character, change.Range.End.Line, change.Range.End.Character)
+		}
+
+		result := make([]byte, 0, len(text)-(end-start)+len(change.Text))
+		result = append(result, text[:start]...)
+		result = append(result, change.Text...)
+		result = append(result, text[end:]...)
+		text = result
+	}
+	return text, nil
+}
+
+// The offset of the start of the next line, or -1 if this is the last line.
+// Lines can end with \n, \r\n or \r.
+func nextLineStart(text []byte, offset int) int {
+	for idx := offset; idx < len(text); idx++ {
+		switch text[idx] {
+		case '\n':
+			return idx + 1
+		case '\r':
+			if idx+1 < len(text) && text[idx+1] == '\n' {
+				return idx + 2
+			}
+			return idx + 1
+		}
+	}
+	return -1
+}
@@ -0,0 +1,96 @@
+package document
+
+import (
+	"testing"
+
+	lsp "github.com/glennsarti/sentinel-utils/lib/languageserver/internal/protocol"
+)
+
+func rng(startLine, startChar, endLine, endChar uint32) *lsp.Range {
+	return &lsp.Range{
+		Start: lsp.Position{Line: startLine, Character: startChar},
+		End:   lsp.Position{Line: endLine, Character: endChar},
+	}
+}
+
+func TestApplyChanges(t *testing.T) {
+	for _, testcase := range []struct {
+		name     string
+		text     string
+		encoding lsp.PositionEncodingKind
+		changes  []lsp.TextDocumentContentChangeEvent
+		expected string
+	}{
+		{
+			name:     "whole document",
+			text:     "a = 1\n",
+			changes:  []lsp.TextDocumentContentChangeEvent{{Text: "b = 2\n"}},
+			expected: "b = 2\n",
+		},
+		{
+			name:     "insert",
+			text:     "a = 1\nmain = rule { a }\n",
+			changes:  []lsp.TextDocumentContentChangeEvent{{Range: rng(1, 14, 1, 15), Text: "a == 1"}},
+			expected: "a = 1\nmain = rule { a == 1 }\n",
+		},
+		{
+			name: "in order",
+			text: "a = 1\n",
+			changes: []lsp.TextDocumentContentChangeEvent{
+				{Range: rng(0, 5, 0, 5), Text: "\nb = 2"},
+				{Range: rng(1, 4, 1, 5), Text: "3"},
+			},
+			expected: "a = 1\nb = 3\n",
+		},
+		{
+			name:     "across lines with CRLF",
+			text:     "a = 1\r\nb = 2\r\nc = 3\r\n",
+			changes:  []lsp.TextDocumentContentChangeEvent{{Range: rng(0, 4, 2, 4), Text: "4"}},
+			expected: "a = 43\r\n",
+		},
+		{
+			name: "UTF-16 code units",
+			// é is one UTF-16 code unit, and 😀 is two
+			text:     "s = \"é😀x\"\n",
+			changes:  []lsp.TextDocumentContentChangeEvent{{Range: rng(0, 8, 0, 9), Text: "y"}},
+			expected: "s = \"é😀y\"\n",
+		},
+		{
+			name: "UTF-8 code units",
+			// é is two bytes, and 😀 is four
+			text:     "s = \"é😀x\"\n",
+			encoding: lsp.UTF8,
+			changes:  []lsp.TextDocumentContentChangeEvent{{Range: rng(0, 11, 0, 12), Text: "y"}},
+			expected: "s = \"é😀y\"\n",
+		},
+		{
+			name:     "past the end of the line",
+			text:     "a = 1\nb = 2\n",
+			changes:  []lsp.TextDocumentContentChangeEvent{{Range: rng(0, 100, 0, 100), Text: "0"}},
+			expected: "a = 10\nb = 2\n",
+		},
+	} {
+		t.Run(testcase.name, func(t *testing.T) {
+			encoding := testcase.encoding
+			if encoding == "" {
+				encoding = lsp.UTF16
+			}
+			actual, err := ApplyChanges([]byte(testcase.text), testcase.changes, encoding)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(actual) != testcase.expected {
+				t.Errorf("expected %q but got %q", testcase.expected, actual)
+			}
+		})
+	}
+}
+
+func TestApplyChangesOutOfRange(t *testing.T) {
+	_, err := ApplyChanges([]byte("a = 1\n"), []lsp.TextDocumentContentChangeEvent{
+		{Range: rng(5, 0, 5, 0), Text: "b"},
+	}, lsp.UTF16)
+	if err == nil {
+		t.Error("expected an error for a line outside of the document")
+	}
+}
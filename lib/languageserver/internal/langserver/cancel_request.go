@@ -0,0 +1,27 @@
+package langserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/creachadair/jrpc2"
+	lsp "github.com/glennsarti/sentinel-utils/lib/languageserver/internal/protocol"
+)
+
+func CancelRequest(ctx context.Context, params lsp.CancelParams) error {
+	rpcServer := jrpc2.ServerFromContext(ctx)
+	if rpcServer == nil {
+		return errors.New("missing RPC server from context")
+	}
+
+	// The RPC server identifies requests by the JSON text of their ID
+	id, err := json.Marshal(params.ID)
+	if err != nil {
+		return fmt.Errorf("invalid request id: %w", err)
+	}
+	rpcServer.CancelRequest(string(id))
+
+	return nil
+}
@@ -4,4 +4,7 @@ import (
 	"github.com/creachadair/jrpc2"
 )
 
-const requestCancelledCode jrpc2.Code = -32800
+const (
+	requestCancelledCode jrpc2.Code = -32800
+	requestFailedCode    jrpc2.Code = -32803
+)
@@ -0,0 +1,180 @@
+package langserver
+
+import (
+	"context"
+	"io/fs"
+	"sort"
+	"strings"
+
+	"github.com/glennsarti/sentinel-parser/features"
+	"github.com/glennsarti/sentinel-parser/filetypes"
+	"github.com/glennsarti/sentinel-utils/lib/languageserver/internal/completion"
+	lsp "github.com/glennsarti/sentinel-utils/lib/languageserver/internal/protocol"
+	"github.com/glennsarti/sentinel-utils/lib/languageserver/internal/symbols"
+
+	cwalker "github.com/glennsarti/sentinel-utils/lib/walkers/sentinel_config"
+)
+
+// Completes a configuration file, and returns the byte offset of the text being completed
+func (svc *service) configCompletion(ctx context.Context, docPath string, text []byte, offset int, sv string, snippets bool) ([]lsp.CompletionItem, int) {
+	cc := completion.ConfigContextAt(text, offset)
+	fileType := filetypes.ConfigPrimaryFileType
+	if cwalker.IsOverrideFileName(svc.sessionFS.BasePath(docPath)) {
+		fileType = filetypes.ConfigOverrideFileType
+	} else if isTestFile(svc.sessionFS, docPath) {
+		fileType = filetypes.ConfigTestFileType
+	}
+	block, inBlock := cc.InnermostBlock()
+
+	switch cc.Kind {
+	case completion.BodyPosition:
+		if cc.Quoted {
+			return nil, offset
+		}
+		return completion.BodyItems(cc, fileType, sv, snippets), cc.PrefixStart
+
+	case completion.LabelPosition:
+		if items := completion.LabelItems(cc, sv); len(items) > 0 {
+			return items, cc.PrefixStart
+		}
+		// Test files set the params of their policy
+		if cc.Block.Type == "param" && len(cc.Block.Labels) == 0 && !inBlock && fileType == filetypes.ConfigTestFileType {
+			return completion.ValueItems(cc, svc.testPolicySymbols(ctx, docPath, sv, symbols.Param), lsp.VariableCompletion, "Policy param"), cc.PrefixStart
+		}
+
+	case completion.ValuePosition:
+		if values := completion.EnumeratedValues(cc); len(values) > 0 {
+			return completion.ValueItems(cc, values, lsp.EnumMemberCompletion, cc.Attribute), cc.PrefixStart
+		}
+		if inBlock && cc.Quoted && (cc.Attribute == "source" || cc.Attribute == "path") {
+			return svc.pathItems(docPath, cc, sourceExtension(block, sv))
+		}
+
+	case completion.KeyPosition:
+		var names []string
+		detail := ""
+		switch {
+		case !inBlock:
+		case block.Type == "test" && cc.Attribute == "rules":
+			names, detail = svc.testPolicySymbols(ctx, docPath, sv, symbols.Rule), "Policy rule"
+		case block.Type == "policy" && cc.Attribute == "params" && len(block.Labels) > 0:
+			names, detail = svc.policySymbols(ctx, block.Labels[0], "", sv, symbols.Param), "Policy param"
+		default:
+			names, detail = completion.EnumeratedKeys(cc), cc.Attribute
+		}
+		items := make([]lsp.CompletionItem, 0, len(names))
+		for _, name := range names {
+			item := lsp.CompletionItem{Label: name, Kind: lsp.PropertyCompletion, Detail: detail, InsertText: name + " = "}
+			if cc.Quoted {
+				item.InsertText = name
+			}
+			items = append(items, item)
+		}
+		return items, cc.PrefixStart
+	}
+	return nil, offset
+}
+
+// The file extension of the source of a block, or empty for any file
+func sourceExtension(block completion.ConfigBlock, sv string) string {
+	switch block.Type {
+	case "policy", "module":
+		return ".sentinel"
+	case "import":
+		if features.UnsupportedVersion(sv, features.V2ImportBlockMinimumVersion) || len(block.Labels) == 0 {
+			return ""
+		}
+		switch block.Labels[0] {
+		case "module":
+			return ".sentinel"
+		case "static":
+			return ".json"
+		}
+	}
+	return ""
+}
+
+// Completes a path relative to the directory of the configuration file. Only the part
+// after the last slash is replaced.
+func (svc *service) pathItems(docPath string, cc completion.ConfigContext, extension string) ([]lsp.CompletionItem, int) {
+	dirPart := "./"
+	start := cc.PrefixStart
+	if idx := strings.LastIndex(cc.Prefix, "/"); idx >= 0 {
+		dirPart = cc.Prefix[:idx+1]
+		start += idx + 1
+	}
+	dir := svc.sessionFS.ParentPath(docPath)
+	for _, segment := range strings.Split(strings.TrimSuffix(dirPart, "/"), "/") {
+		switch segment {
+		case "", ".":
+		case "..":
+			dir = svc.sessionFS.ParentPath(dir)
+		default:
+			dir = svc.sessionFS.PathJoin(dir, segment)
+		}
+	}
+
+	entries, err := fs.ReadDir(svc.sessionFS, dir)
+	if err != nil {
+		return nil, start
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+
+	// Paths must start with ./ so it is added when nothing has been written yet
+	prefix := ""
+	if !strings.Contains(cc.Prefix, "/") {
+		prefix = "./"
+	}
+	items := make([]lsp.CompletionItem, 0, len(entries))
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.HasPrefix(name, ".") {
+			continue
+		}
+		if entry.IsDir() {
+			items = append(items, lsp.CompletionItem{Label: prefix + name + "/", Kind: lsp.FolderCompletion})
+		} else if extension == "" || strings.HasSuffix(name, extension) {
+			items = append(items, lsp.CompletionItem{Label: prefix + name, Kind: lsp.FileCompletion})
+		}
+	}
+	return items, start
+}
+
+// The names of the top level symbols of a kind, in the policy of a test file
+func (svc *service) testPolicySymbols(ctx context.Context, docPath, sv string, kind symbols.Kind) []string {
+	// Test files are in <policy directory>/test/<policy name>/
+	testDir := svc.sessionFS.ParentPath(docPath)
+	policyDir := svc.sessionFS.ParentPath(svc.sessionFS.ParentPath(testDir))
+	return svc.policySymbols(ctx, svc.sessionFS.BasePath(testDir), policyDir, sv, kind)
+}
+
+// The names of the top level symbols of a kind in a policy. Policies which are not in the
+// configuration are found in the fallback directory, by their file name.
+func (svc *service) policySymbols(ctx context.Context, name, fallbackDir, sv string, kind symbols.Kind) []string {
+	policyPath := ""
+	if resolved, err := svc.resolveConfiguration(ctx, sv); err == nil {
+		policyPath, _ = resolved.PolicyPath(svc.sessionFS, name)
+	}
+	if policyPath == "" && fallbackDir != "" {
+		policyPath = svc.sessionFS.PathJoin(fallbackDir, name+".sentinel")
+	}
+	if policyPath == "" {
+		return nil
+	}
+
+	policyText, err := svc.sessionFS.ReadFile(policyPath)
+	if err != nil {
+		return nil
+	}
+	policyFile := svc.parseSentinelFile(ctx, policyPath, policyText, sv)
+	if policyFile == nil {
+		return nil
+	}
+	names := make([]string, 0)
+	for _, s := range symbols.NewIndex(policyFile, policyText).TopLevel() {
+		if s.Kind == kind {
+			names = append(names, s.Name)
+		}
+	}
+	return names
+}
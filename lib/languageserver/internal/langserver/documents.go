@@ -0,0 +1,91 @@
+package langserver
+
+import (
+	"context"
+	"errors"
+
+	"github.com/glennsarti/sentinel-parser/filetypes"
+	"github.com/glennsarti/sentinel-utils/lib/configuration"
+	"github.com/glennsarti/sentinel-utils/lib/filesystem"
+	lsp "github.com/glennsarti/sentinel-utils/lib/languageserver/internal/protocol"
+
+	sast "github.com/glennsarti/sentinel-parser/sentinel/ast"
+	scast "github.com/glennsarti/sentinel-parser/sentinel_config/ast"
+	cwalker "github.com/glennsarti/sentinel-utils/lib/walkers/sentinel_config"
+)
+
+// Reads the path and content of a document. Open documents use the content from the
+// editor, which may not be saved yet.
+func (svc *service) readDocument(uri lsp.DocumentURI) (string, []byte, error) {
+	docPath, err := svc.sessionFS.UriToPath(uri)
+	if err != nil {
+		return "", nil, err
+	}
+	text, err := svc.sessionFS.ReadFile(docPath)
+	if err != nil {
+		return "", nil, err
+	}
+	return docPath, text, nil
+}
+
+// Editors can send URIs which are written differently to the ones the server creates
+func (svc *service) normaliseUri(uri lsp.DocumentURI) lsp.DocumentURI {
+	if p, err := svc.sessionFS.UriToPath(uri); err == nil {
+		if u, err := svc.sessionFS.PathToUri(p); err == nil {
+			return u
+		}
+	}
+	return uri
+}
+
+// Parses a Sentinel file. Files with syntax errors return whatever could be parsed.
+func (svc *service) parseSentinelFile(ctx context.Context, filePath string, text []byte, sentinelVersion string) *sast.File {
+	file, _, err := svc.parseFactory.ParseSentinelFile(ctx, &filesystem.File{
+		Path:    filePath,
+		Name:    svc.sessionFS.BasePath(filePath),
+		Type:    filetypes.PolicyFileType,
+		Content: &text,
+	}, sentinelVersion)
+	if err != nil {
+		return nil
+	}
+	return file
+}
+
+// Parses a configuration file. Files with errors return whatever could be recovered.
+func (svc *service) parseConfigFile(ctx context.Context, filePath string, text []byte, sentinelVersion string) *scast.File {
+	fileType := filetypes.ConfigPrimaryFileType
+	if cwalker.IsOverrideFileName(svc.sessionFS.BasePath(filePath)) {
+		fileType = filetypes.ConfigOverrideFileType
+	} else if isTestFile(svc.sessionFS, filePath) {
+		fileType = filetypes.ConfigTestFileType
+	}
+	cfg, _, err := svc.parseFactory.ParseSentinelConfigFile(ctx, &filesystem.File{
+		Path:    filePath,
+		Name:    svc.sessionFS.BasePath(filePath),
+		Type:    fileType,
+		Content: &text,
+	}, sentinelVersion)
+	if err != nil {
+		return nil
+	}
+	return cfg
+}
+
+// The configuration of the policy set in the root of the workspace, with the overrides applied
+func (svc *service) resolveConfiguration(ctx context.Context, sentinelVersion string) (*configuration.Resolved, error) {
+	rootPath, err := svc.sessionFS.UriToPath(svc.rootUri)
+	if err != nil {
+		return nil, err
+	}
+	walker := cwalker.NewSentinelConfigWalker(svc.sessionFS, rootPath, sentinelVersion, svc.parseFactory)
+	if walker == nil {
+		return nil, errors.New("failed to create walker")
+	}
+	return configuration.Resolve(ctx, walker, svc.parseFactory)
+}
+
+// Test files are in test/<policy name>/ directories
+func isTestFile(fsys filesystem.FS, filePath string) bool {
+	return fsys.BasePath(fsys.ParentPath(fsys.ParentPath(filePath))) == "test"
+}
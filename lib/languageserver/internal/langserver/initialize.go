@@ -2,22 +2,37 @@ package langserver
 
 import (
 	"context"
+	"encoding/json"
 
 	ictx "github.com/glennsarti/sentinel-utils/lib/languageserver/internal/contexts"
+	"github.com/glennsarti/sentinel-utils/lib/languageserver/internal/document"
 	lsp "github.com/glennsarti/sentinel-utils/lib/languageserver/internal/protocol"
 	ver "github.com/glennsarti/sentinel-utils/version"
 )
 
 func (svc *service) Initialize(ctx context.Context, params lsp.InitializeParams) (lsp.InitializeResult, error) {
+	hoverProvider := true
+	definitionProvider := true
+	referencesProvider := true
+	documentSymbolProvider := true
+	workspaceSymbolProvider := true
 	serverCaps := lsp.InitializeResult{
 		Capabilities: lsp.ServerCapabilities{
 			TextDocumentSync: lsp.TextDocumentSyncOptions{
 				OpenClose: true,
-				Change:    lsp.Full,
+				Change:    lsp.Incremental,
 				Save: lsp.SaveOptions{
 					IncludeText: false,
 				},
 			},
+			CompletionProvider: &lsp.CompletionOptions{
+				TriggerCharacters: []string{"\"", "/", "."},
+			},
+			HoverProvider:           &hoverProvider,
+			DefinitionProvider:      &definitionProvider,
+			ReferencesProvider:      &referencesProvider,
+			DocumentSymbolProvider:  &documentSymbolProvider,
+			WorkspaceSymbolProvider: &workspaceSymbolProvider,
 			Workspace: &lsp.Workspace6Gn{
 				WorkspaceFolders: lsp.WorkspaceFolders5Gn{
 					Supported: false,
@@ -30,14 +45,87 @@ func (svc *service) Initialize(ctx context.Context, params lsp.InitializeParams)
 	serverCaps.ServerInfo.Version = ver.Version
 
 	clientCaps := params.Capabilities
+	if clientCaps.TextDocument.Rename.PrepareSupport {
+		serverCaps.Capabilities.RenameProvider = &lsp.RenameOptions{PrepareProvider: true}
+	} else {
+		serverCaps.Capabilities.RenameProvider = true
+	}
+
+	// Clients without code action literals can only be sent commands
+	if len(clientCaps.TextDocument.CodeAction.CodeActionLiteralSupport.CodeActionKind.ValueSet) > 0 {
+		serverCaps.Capabilities.CodeActionProvider = &lsp.CodeActionOptions{
+			CodeActionKinds: []lsp.CodeActionKind{lsp.QuickFix},
+		}
+	}
 
 	if err := ictx.SetClientCapabilities(ctx, &clientCaps); err != nil {
 		return serverCaps, err
 	}
 
-	if err := svc.setupService(params.RootURI, ctx); err != nil {
+	var clientEncodings []lsp.PositionEncodingKind
+	if clientCaps.General != nil {
+		clientEncodings = clientCaps.General.PositionEncodings
+	}
+	enc := document.NegotiateEncoding(clientEncodings)
+	if err := ictx.SetPositionEncoding(ctx, &enc); err != nil {
 		return serverCaps, err
 	}
+	serverCaps.Capabilities.PositionEncoding = &enc
+	svc.logger.Printf("Using the %s position encoding", enc)
+
+	if err := svc.setupService(params.RootURI, enc, ctx); err != nil {
+		return serverCaps, err
+	}
+
+	svc.applyInitializationOptions(ctx, params.InitializationOptions)
 
 	return serverCaps, nil
 }
+
+// An invalid option is logged, and the default is used instead
+func (svc *service) applyInitializationOptions(ctx context.Context, raw interface{}) {
+	if raw == nil {
+		return
+	}
+
+	var opts lsp.SentinelInitializationOptions
+	if content, err := json.Marshal(raw); err != nil {
+		svc.logger.Printf("Invalid initialization options: %s", err)
+		return
+	} else if err := json.Unmarshal(content, &opts); err != nil {
+		svc.logger.Printf("Invalid initialization options: %s", err)
+		return
+	}
+
+	if opts.SentinelVersion != "" {
+		svc.applySentinelVersionOption(ctx, opts.SentinelVersion)
+	}
+	if opts.LintOn != "" {
+		svc.applyLintOnOption(ctx, opts.LintOn)
+	}
+}
+
+func (svc *service) applySentinelVersionOption(ctx context.Context, requested string) {
+	actualVersion, description, err := svc.resolveSentinelVersion(requested)
+	if err != nil {
+		svc.logger.Printf("Ignoring the sentinelVersion initialization option: %s", err)
+		return
+	}
+	if err := ictx.SetSentinelVersion(ctx, &actualVersion); err != nil {
+		svc.logger.Printf("Failed to set Sentinel version: %s", err)
+		return
+	}
+	svc.logger.Print(description)
+}
+
+func (svc *service) applyLintOnOption(ctx context.Context, mode string) {
+	if mode != lsp.LintOnChange && mode != lsp.LintOnSave {
+		svc.logger.Printf("Ignoring the lintOn initialization option %q. It must be %q or %q", mode, lsp.LintOnChange, lsp.LintOnSave)
+		return
+	}
+	if err := ictx.SetLintMode(ctx, &mode); err != nil {
+		svc.logger.Printf("Failed to set lint mode: %s", err)
+		return
+	}
+	svc.logger.Printf("Linting documents on %s", mode)
+}
@@ -0,0 +1,100 @@
+package langserver
+
+import (
+	"strings"
+
+	lsp "github.com/glennsarti/sentinel-utils/lib/languageserver/internal/protocol"
+)
+
+// The first format the client prefers which the server can write. Clients which do not
+// say can only be relied on to show plain text.
+func preferredMarkupKind(formats []lsp.MarkupKind) lsp.MarkupKind {
+	for _, kind := range formats {
+		if kind == lsp.Markdown || kind == lsp.PlainText {
+			return kind
+		}
+	}
+	return lsp.PlainText
+}
+
+// Builds markup content in either Markdown or plain text
+type markup struct {
+	kind       lsp.MarkupKind
+	paragraphs []string
+	// Separators are only added between sections which have content
+	separate bool
+}
+
+func newMarkup(kind lsp.MarkupKind) *markup {
+	return &markup{kind: kind, paragraphs: make([]string, 0)}
+}
+
+// Adds a block of code in the language
+func (m *markup) code(language, text string) {
+	if text == "" {
+		return
+	}
+	if m.kind == lsp.Markdown {
+		text = "```" + language + "\n" + text + "\n```"
+	}
+	m.add(text)
+}
+
+// Adds a paragraph of text
+func (m *markup) text(text string) {
+	if text = strings.TrimSpace(text); text != "" {
+		m.add(text)
+	}
+}
+
+// Adds a list, with one item on each line
+func (m *markup) list(items []string) {
+	if len(items) == 0 {
+		return
+	}
+	if m.kind == lsp.Markdown {
+		m.add("- " + strings.Join(items, "\n- "))
+	} else {
+		m.add(strings.Join(items, "\n"))
+	}
+}
+
+// Separates the sections of the content
+func (m *markup) separator() {
+	m.separate = len(m.paragraphs) > 0
+}
+
+func (m *markup) add(paragraph string) {
+	if m.separate && m.kind == lsp.Markdown {
+		m.paragraphs = append(m.paragraphs, "---")
+	}
+	m.separate = false
+	m.paragraphs = append(m.paragraphs, paragraph)
+}
+
+// Formats text as inline code
+func (m *markup) inlineCode(text string) string {
+	if m.kind == lsp.Markdown {
+		return "`" + text + "`"
+	}
+	return text
+}
+
+// Formats text in bold
+func (m *markup) bold(text string) string {
+	if m.kind == lsp.Markdown {
+		return "**" + text + "**"
+	}
+	return text
+}
+
+func (m *markup) isEmpty() bool {
+	return len(m.paragraphs) == 0
+}
+
+func (m *markup) content() lsp.MarkupContent {
+	return lsp.MarkupContent{
+		Kind:  m.kind,
+		Value: strings.Join(m.paragraphs, "\n\n"),
+	}
+}
@@ -0,0 +1,63 @@
+package langserver
+
+import (
+	"context"
+
+	"github.com/glennsarti/sentinel-utils/lib/internal/helpers"
+	"github.com/glennsarti/sentinel-utils/lib/languageserver/internal/completion"
+	lsp "github.com/glennsarti/sentinel-utils/lib/languageserver/internal/protocol"
+	"github.com/glennsarti/sentinel-utils/lib/languageserver/internal/symbols"
+)
+
+// Completes a Sentinel file, and returns the byte offset of the text being completed. The
+// text is usually being written, so it does not need to be valid.
+func (svc *service) sentinelCompletion(ctx context.Context, docPath string, text []byte, offset int, sv string, snippets bool) ([]lsp.CompletionItem, int) {
+	sc := completion.SentinelContextAt(text, offset)
+	switch sc.Kind {
+	case completion.NoSentinelPosition:
+		return nil, offset
+	case completion.ImportNamePosition:
+		imports := make([]string, 0)
+		if resolved, err := svc.resolveConfiguration(ctx, sv); err == nil {
+			imports = helpers.SortedKeys(resolved.File.Imports)
+		}
+		return completion.ImportItems(sc, imports, sv), sc.PrefixStart
+	}
+
+	file, parsed := completion.ParseSentinelFile(sv, docPath, text, offset)
+	if file == nil {
+		if sc.Kind == completion.ExpressionPosition {
+			return completion.KeywordItems(sv, snippets), sc.PrefixStart
+		}
+		return nil, offset
+	}
+	idx := symbols.NewIndex(file, parsed)
+
+	if sc.Kind == completion.MemberPosition {
+		if sc.Receiver == "" {
+			return nil, offset
+		}
+		imp := idx.Lookup(sc.Receiver, offset)
+		if imp == nil || imp.Kind != symbols.Import {
+			return nil, offset
+		}
+		resolved, err := svc.resolveConfiguration(ctx, sv)
+		if err != nil {
+			return nil, offset
+		}
+		modIdx, _ := svc.moduleIndex(ctx, resolved, imp.ImportName, sv)
+		if modIdx == nil {
+			return nil, offset
+		}
+		members := make([]*symbols.Symbol, 0)
+		for _, member := range modIdx.TopLevel() {
+			if member.Kind != symbols.Import {
+				members = append(members, member)
+			}
+		}
+		return completion.SymbolItems(members, snippets), sc.PrefixStart
+	}
+
+	items := completion.SymbolItems(idx.InScope(offset), snippets)
+	return append(items, completion.KeywordItems(sv, snippets)...), sc.PrefixStart
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/creachadair/jrpc2"
 	"github.com/glennsarti/sentinel-parser/features"
+	"github.com/glennsarti/sentinel-utils/lib/compatibility"
 	ictx "github.com/glennsarti/sentinel-utils/lib/languageserver/internal/contexts"
 	lsp "github.com/glennsarti/sentinel-utils/lib/languageserver/internal/protocol"
 	"github.com/glennsarti/sentinel-utils/lib/languageserver/internal/queues"
@@ -27,32 +28,38 @@ func (svc *service) SentinelSetVersion(ctx context.Context, params lsp.SentinelS
 		return nil, err
 	}
 
-	// Did it actually change?
-	if ver == params.Version {
-		response.SentinelVersion = ver
-		return response, nil
-	}
-	// Is the new version valid?
-	versionOk, actualVersion := features.ValidateSentinelVersion(params.Version)
-
 	rpcServer := jrpc2.ServerFromContext(ctx)
 	if rpcServer == nil {
 		return response, errors.New("missing RPC server from context")
 	}
 
-	if !versionOk {
+	// Is the new version valid?
+	actualVersion, description, err := svc.resolveSentinelVersion(params.Version)
+	if err != nil {
 		_ = rpcServer.Notify(ctx, "window/showMessage", &lsp.ShowMessageParams{
 			Type:    lsp.Warning,
-			Message: fmt.Sprintf("%q is not a valid Sentinel vesion.", params.Version),
+			Message: fmt.Sprintf("%s.", err),
 		})
-		return response, fmt.Errorf("%q is not a valid Sentinel vesion", params.Version)
+		return response, err
+	}
+
+	// Did it actually change?
+	if ver == actualVersion {
+		response.SentinelVersion = ver
+		return response, nil
 	}
 
 	// Set the new version
 	if err := ictx.SetSentinelVersion(ctx, &actualVersion); err != nil {
 		return response, fmt.Errorf("failed to set Sentinel version: %w", err)
 	}
-	response.SentinelVersion = params.Version
+	response.SentinelVersion = actualVersion
+	if params.Version == compatibility.AutoVersion {
+		_ = rpcServer.Notify(ctx, "window/logMessage", &lsp.LogMessageParams{
+			Type:    lsp.Info,
+			Message: description,
+		})
+	}
 
 	// Enqueue new diagnostics
 	req := queues.LintQueueRequest{
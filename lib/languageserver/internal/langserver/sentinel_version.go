@@ -0,0 +1,36 @@
+package langserver
+
+import (
+	"fmt"
+
+	"github.com/glennsarti/sentinel-parser/features"
+	"github.com/glennsarti/sentinel-utils/lib/compatibility"
+)
+
+// Validates the requested Sentinel version, detecting it from the workspace when it is "auto".
+// The description says where the version came from.
+func (svc *service) resolveSentinelVersion(requested string) (string, string, error) {
+	if requested != compatibility.AutoVersion {
+		if ok, actual := features.ValidateSentinelVersion(requested); ok {
+			return actual, fmt.Sprintf("Using Sentinel version %s", actual), nil
+		}
+		return "", "", fmt.Errorf("%q is not a valid Sentinel vesion", requested)
+	}
+
+	if svc.sessionFS == nil {
+		return "", "", fmt.Errorf("the workspace has not been initialized")
+	}
+	rootPath, err := svc.sessionFS.UriToPath(svc.rootUri)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to convert root URI to path: %w", err)
+	}
+
+	detected, err := compatibility.DetectVersion(svc.sessionFS, rootPath)
+	if err != nil {
+		return "", "", fmt.Errorf("could not detect the Sentinel version: %w", err)
+	}
+	if detected.Source == "" {
+		return detected.Version, fmt.Sprintf("Using the latest Sentinel version %s, as there is no %s file", detected.Version, compatibility.VersionFileName), nil
+	}
+	return detected.Version, fmt.Sprintf("Using Sentinel version %s from %s", detected.Version, detected.Source), nil
+}
@@ -17,6 +17,10 @@ import (
 	"github.com/glennsarti/sentinel-utils/lib/languageserver/internal/session"
 	"github.com/glennsarti/sentinel-utils/lib/languageserver/internal/stores"
 	storesImpl "github.com/glennsarti/sentinel-utils/lib/languageserver/internal/stores/concrete"
+	"github.com/glennsarti/sentinel-utils/lib/languageserver/internal/workspace"
+	"github.com/glennsarti/sentinel-utils/lib/parsing"
+	cachingParsing "github.com/glennsarti/sentinel-utils/lib/parsing/caching"
+	defaultParsing "github.com/glennsarti/sentinel-utils/lib/parsing/default"
 
 	"github.com/glennsarti/sentinel-utils/lib/languageserver/internal/queues"
 	dispatchImpl "github.com/glennsarti/sentinel-utils/lib/languageserver/internal/queues/client_dispatch"
@@ -36,17 +40,24 @@ type service struct {
 	srvCtx     context.Context
 	stateStore stores.StateStore
 	sessionFS  filesystem.SessionFS
+	rootUri    lsp.DocumentURI
+	// Shared by the lint queue and the requests, so that only the files which changed
+	// need to be parsed again
+	parseFactory parsing.CachingFactory
+	// The symbols of the policy set, which are indexed again after documents change
+	workspaceIndex *workspace.Index
 
 	lintQueue         queues.LintQueue
 	clientNotifyQueue queues.ClientNotifyDispatchQueue
 }
 
-func (svc *service) setupService(rootUri lsp.DocumentURI, ctx context.Context) error {
+func (svc *service) setupService(rootUri lsp.DocumentURI, enc lsp.PositionEncodingKind, ctx context.Context) error {
 	rpcServer := jrpc2.ServerFromContext(ctx)
 	if rpcServer == nil {
 		return errors.New("missing RPC server from context")
 	}
 
+	svc.rootUri = rootUri
 	baseFS, err := osFS.NewOSFileSystem(rootUri)
 	if err != nil {
 		return err
@@ -80,6 +91,17 @@ func (svc *service) setupService(rootUri lsp.DocumentURI, ctx context.Context) e
 	}
 	svc.logger.Printf("Wrapped the file system with a document store")
 
+	svc.parseFactory = cachingParsing.NewCachingParsingFactory(svc.sessionFS, defaultParsing.NewDefaultParsingFactory(svc.sessionFS))
+
+	rootPath, err := svc.sessionFS.UriToPath(rootUri)
+	if err != nil {
+		return err
+	}
+	svc.workspaceIndex = workspace.NewIndex(svc.sessionFS, rootPath, svc.parseFactory)
+	svc.stateStore.DocumentStore().OnChange(func(lsp.DocumentURI) {
+		svc.workspaceIndex.Invalidate()
+	})
+
 	if q, err := dispatchImpl.NewQueue(50, rpcServer, svc.logger); err != nil {
 		return err
 	} else {
@@ -89,7 +111,9 @@ func (svc *service) setupService(rootUri lsp.DocumentURI, ctx context.Context) e
 	if q, err := lintImpl.NewLintQueue(
 		1,
 		rootUri,
+		enc,
 		svc.sessionFS,
+		svc.parseFactory,
 		svc.clientNotifyQueue,
 		svc.logger,
 	); err != nil {
@@ -112,6 +136,9 @@ func (svc *service) Assigner() (jrpc2.Assigner, error) {
 	m := rpch.Map{
 		"initialize": func(ctx context.Context, req *jrpc2.Request) (any, error) {
 			ctx = ictx.WithClientCapabilities(ctx, clientSession.ClientCapabilities)
+			ctx = ictx.WithSentinelVersion(ctx, clientSession.SentinelVersion)
+			ctx = ictx.WithLintMode(ctx, clientSession.LintMode)
+			ctx = ictx.WithPositionEncoding(ctx, clientSession.PositionEncoding)
 
 			return handle(ctx, req, svc.Initialize)
 		},
@@ -122,6 +149,9 @@ func (svc *service) Assigner() (jrpc2.Assigner, error) {
 			if err := svc.clientNotifyQueue.StartAsync(context.Background()); err != nil {
 				return nil, fmt.Errorf("failed to start client notify queue: %w", err)
 			}
+			if err := svc.lintQueue.StartAsync(svc.srvCtx); err != nil {
+				return nil, fmt.Errorf("failed to start lint queue: %w", err)
+			}
 
 			return handle(ctx, req, Initialized)
 		},
@@ -134,6 +164,8 @@ func (svc *service) Assigner() (jrpc2.Assigner, error) {
 			ctx = ictx.WithDocumentStore(ctx, svc.stateStore.DocumentStore())
 			ctx = ictx.WithSentinelVersion(ctx, clientSession.SentinelVersion)
 			ctx = ictx.WithLintQueue(ctx, svc.lintQueue)
+			ctx = ictx.WithLintMode(ctx, clientSession.LintMode)
+			ctx = ictx.WithPositionEncoding(ctx, clientSession.PositionEncoding)
 
 			return handle(ctx, req, svc.TextDocumentDidChange)
 		},
@@ -149,17 +181,130 @@ func (svc *service) Assigner() (jrpc2.Assigner, error) {
 			return handle(ctx, req, svc.TextDocumentDidOpen)
 		},
 		"textDocument/didClose": func(ctx context.Context, req *jrpc2.Request) (interface{}, error) {
-			return nil, nil
+			if !clientSession.Ready {
+				return nil, newClientNotReadyError()
+			}
+
+			ctx = ictx.WithDocumentStore(ctx, svc.stateStore.DocumentStore())
+			ctx = ictx.WithSentinelVersion(ctx, clientSession.SentinelVersion)
+			ctx = ictx.WithLintQueue(ctx, svc.lintQueue)
+
+			return handle(ctx, req, svc.TextDocumentDidClose)
 		},
 		"textDocument/didSave": func(ctx context.Context, req *jrpc2.Request) (interface{}, error) {
-			return nil, nil
+			if !clientSession.Ready {
+				return nil, newClientNotReadyError()
+			}
+
+			ctx = ictx.WithDocumentStore(ctx, svc.stateStore.DocumentStore())
+			ctx = ictx.WithSentinelVersion(ctx, clientSession.SentinelVersion)
+			ctx = ictx.WithLintQueue(ctx, svc.lintQueue)
+
+			return handle(ctx, req, svc.TextDocumentDidSave)
+		},
+		"textDocument/completion": func(ctx context.Context, req *jrpc2.Request) (interface{}, error) {
+			if !clientSession.Ready {
+				return nil, newClientNotReadyError()
+			}
+
+			ctx = ictx.WithClientCapabilities(ctx, clientSession.ClientCapabilities)
+			ctx = ictx.WithSentinelVersion(ctx, clientSession.SentinelVersion)
+			ctx = ictx.WithPositionEncoding(ctx, clientSession.PositionEncoding)
+
+			return handle(ctx, req, svc.TextDocumentCompletion)
+		},
+		"textDocument/hover": func(ctx context.Context, req *jrpc2.Request) (interface{}, error) {
+			if !clientSession.Ready {
+				return nil, newClientNotReadyError()
+			}
+
+			ctx = ictx.WithClientCapabilities(ctx, clientSession.ClientCapabilities)
+			ctx = ictx.WithSentinelVersion(ctx, clientSession.SentinelVersion)
+			ctx = ictx.WithPositionEncoding(ctx, clientSession.PositionEncoding)
+			ctx = ictx.WithLintQueue(ctx, svc.lintQueue)
+
+			return handle(ctx, req, svc.TextDocumentHover)
+		},
+		"textDocument/definition": func(ctx context.Context, req *jrpc2.Request) (interface{}, error) {
+			if !clientSession.Ready {
+				return nil, newClientNotReadyError()
+			}
+
+			ctx = ictx.WithSentinelVersion(ctx, clientSession.SentinelVersion)
+			ctx = ictx.WithPositionEncoding(ctx, clientSession.PositionEncoding)
+
+			return handle(ctx, req, svc.TextDocumentDefinition)
+		},
+		"textDocument/references": func(ctx context.Context, req *jrpc2.Request) (interface{}, error) {
+			if !clientSession.Ready {
+				return nil, newClientNotReadyError()
+			}
+
+			ctx = ictx.WithSentinelVersion(ctx, clientSession.SentinelVersion)
+			ctx = ictx.WithPositionEncoding(ctx, clientSession.PositionEncoding)
+
+			return handle(ctx, req, svc.TextDocumentReferences)
+		},
+		"textDocument/documentSymbol": func(ctx context.Context, req *jrpc2.Request) (interface{}, error) {
+			if !clientSession.Ready {
+				return nil, newClientNotReadyError()
+			}
+
+			ctx = ictx.WithClientCapabilities(ctx, clientSession.ClientCapabilities)
+			ctx = ictx.WithSentinelVersion(ctx, clientSession.SentinelVersion)
+			ctx = ictx.WithPositionEncoding(ctx, clientSession.PositionEncoding)
+
+			return handle(ctx, req, svc.TextDocumentDocumentSymbol)
+		},
+		"workspace/symbol": func(ctx context.Context, req *jrpc2.Request) (interface{}, error) {
+			if !clientSession.Ready {
+				return nil, newClientNotReadyError()
+			}
+
+			ctx = ictx.WithSentinelVersion(ctx, clientSession.SentinelVersion)
+			ctx = ictx.WithPositionEncoding(ctx, clientSession.PositionEncoding)
+
+			return handle(ctx, req, svc.WorkspaceSymbol)
+		},
+		"textDocument/prepareRename": func(ctx context.Context, req *jrpc2.Request) (interface{}, error) {
+			if !clientSession.Ready {
+				return nil, newClientNotReadyError()
+			}
+
+			ctx = ictx.WithSentinelVersion(ctx, clientSession.SentinelVersion)
+			ctx = ictx.WithPositionEncoding(ctx, clientSession.PositionEncoding)
+
+			return handle(ctx, req, svc.TextDocumentPrepareRename)
+		},
+		"textDocument/rename": func(ctx context.Context, req *jrpc2.Request) (interface{}, error) {
+			if !clientSession.Ready {
+				return nil, newClientNotReadyError()
+			}
+
+			ctx = ictx.WithClientCapabilities(ctx, clientSession.ClientCapabilities)
+			ctx = ictx.WithDocumentStore(ctx, svc.stateStore.DocumentStore())
+			ctx = ictx.WithSentinelVersion(ctx, clientSession.SentinelVersion)
+			ctx = ictx.WithPositionEncoding(ctx, clientSession.PositionEncoding)
+
+			return handle(ctx, req, svc.TextDocumentRename)
+		},
+		"textDocument/codeAction": func(ctx context.Context, req *jrpc2.Request) (interface{}, error) {
+			if !clientSession.Ready {
+				return nil, newClientNotReadyError()
+			}
+
+			ctx = ictx.WithClientCapabilities(ctx, clientSession.ClientCapabilities)
+			ctx = ictx.WithSentinelVersion(ctx, clientSession.SentinelVersion)
+			ctx = ictx.WithPositionEncoding(ctx, clientSession.PositionEncoding)
+
+			return handle(ctx, req, svc.TextDocumentCodeAction)
 		},
 
 		"$/setTrace": func(ctx context.Context, req *jrpc2.Request) (any, error) {
 			return nil, nil // TODO: Ignore these for now
 		},
 		"$/cancelRequest": func(ctx context.Context, req *jrpc2.Request) (any, error) {
-			return nil, nil // TODO: Ignore these for now
+			return handle(ctx, req, CancelRequest)
 		},
 
 		"shutdown": func(ctx context.Context, req *jrpc2.Request) (any, error) {
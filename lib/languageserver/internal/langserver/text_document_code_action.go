@@ -0,0 +1,127 @@
+package langserver
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	slint "github.com/glennsarti/sentinel-lint/lint"
+	ictx "github.com/glennsarti/sentinel-utils/lib/languageserver/internal/contexts"
+	"github.com/glennsarti/sentinel-utils/lib/languageserver/internal/document"
+	lsp "github.com/glennsarti/sentinel-utils/lib/languageserver/internal/protocol"
+	"github.com/glennsarti/sentinel-utils/lib/languageserver/internal/queues"
+	"github.com/glennsarti/sentinel-utils/lib/languageserver/internal/quickfix"
+	"github.com/glennsarti/sentinel-utils/lib/linting"
+)
+
+// Fixes the lint diagnostics which the client sends back. Every diagnostic, other than
+// syntax errors, can also be suppressed with a comment.
+func (svc *service) TextDocumentCodeAction(ctx context.Context, params lsp.CodeActionParams) ([]lsp.CodeAction, error) {
+	clientCaps, err := ictx.ClientCapabilities(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sv, err := ictx.SentinelVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	enc, err := ictx.PositionEncoding(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]lsp.CodeAction, 0)
+	if len(params.Context.Only) > 0 && !slices.Contains(params.Context.Only, lsp.QuickFix) {
+		return result, nil
+	}
+
+	docPath, text, err := svc.readDocument(params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	uri := params.TextDocument.URI
+	// Comments can not be added to the other files, such as the data of static imports
+	canSuppress := strings.HasSuffix(docPath, ".sentinel") || strings.HasSuffix(docPath, ".hcl")
+
+	editCaps := clientCaps.Workspace.WorkspaceEdit
+	canCreate := editCaps != nil && editCaps.DocumentChanges && slices.Contains(editCaps.ResourceOperations, lsp.Create)
+
+	for _, d := range params.Context.Diagnostics {
+		data, ok := queues.DiagnosticDataOf(d)
+		if !ok {
+			continue
+		}
+		start, err := document.PositionToOffset(text, d.Range.Start, enc)
+		if err != nil {
+			continue
+		}
+		end, err := document.PositionToOffset(text, d.Range.End, enc)
+		if err != nil {
+			continue
+		}
+
+		action := func(title string, preferred bool, edit lsp.WorkspaceEdit) {
+			result = append(result, lsp.CodeAction{
+				Title:       title,
+				Kind:        lsp.QuickFix,
+				Diagnostics: []lsp.Diagnostic{d},
+				IsPreferred: preferred,
+				Edit:        edit,
+			})
+		}
+		textEdit := func(edits ...quickfix.Edit) lsp.WorkspaceEdit {
+			changes := make([]lsp.TextEdit, len(edits))
+			for idx, e := range edits {
+				changes[idx] = lsp.TextEdit{
+					Range: lsp.Range{
+						Start: document.OffsetToPosition(text, e.Start, enc),
+						End:   document.OffsetToPosition(text, e.End, enc),
+					},
+					NewText: e.NewText,
+				}
+			}
+			return lsp.WorkspaceEdit{Changes: map[lsp.DocumentURI][]lsp.TextEdit{uri: changes}}
+		}
+
+		switch data.RuleId {
+		case linting.UselessOverrideRuleID:
+			if edit, ok := quickfix.RemoveBlock(text, start); ok {
+				action("Remove the block", true, textEdit(edit))
+			}
+		case linting.DuplicateNameRuleID:
+			if edit, newName, ok := quickfix.RenameBlock(text, start, end); ok {
+				action(fmt.Sprintf("Rename to %q", newName), true, textEdit(edit))
+			}
+		case linting.AssignmentsAfterRulesRuleID:
+			file := svc.parseSentinelFile(ctx, docPath, text, sv)
+			if edits, ok := quickfix.MoveAboveRules(file, text, start); ok {
+				action("Move the assignment above the rules", true, textEdit(edits...))
+			}
+		case linting.FileErrorRuleID:
+			if data.MissingFile == "" || !canCreate {
+				break
+			}
+			fileUri, err := svc.sessionFS.PathToUri(data.MissingFile)
+			if err != nil {
+				return nil, err
+			}
+			action(fmt.Sprintf("Create %s", svc.sessionFS.BasePath(data.MissingFile)), true, lsp.WorkspaceEdit{
+				DocumentChanges: []lsp.DocumentChanges{{
+					CreateFile: &lsp.CreateFile{
+						Kind:    string(lsp.Create),
+						URI:     fileUri,
+						Options: &lsp.CreateFileOptions{IgnoreIfExists: true},
+					},
+				}},
+			})
+		}
+
+		if canSuppress && data.RuleId != slint.SyntaxErrorRuleID {
+			action(fmt.Sprintf("Ignore %s on this line", data.RuleId), false, textEdit(quickfix.Suppress(text, start, data.RuleId)))
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,59 @@
+package langserver
+
+import (
+	"context"
+	"strings"
+
+	ictx "github.com/glennsarti/sentinel-utils/lib/languageserver/internal/contexts"
+	"github.com/glennsarti/sentinel-utils/lib/languageserver/internal/document"
+	lsp "github.com/glennsarti/sentinel-utils/lib/languageserver/internal/protocol"
+)
+
+func (svc *service) TextDocumentCompletion(ctx context.Context, params lsp.CompletionParams) (*lsp.CompletionList, error) {
+	clientCaps, err := ictx.ClientCapabilities(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sv, err := ictx.SentinelVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	enc, err := ictx.PositionEncoding(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &lsp.CompletionList{Items: make([]lsp.CompletionItem, 0)}
+	docPath, text, err := svc.readDocument(params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	offset, err := document.PositionToOffset(text, params.Position, enc)
+	if err != nil {
+		return result, nil
+	}
+
+	snippets := clientCaps.TextDocument.Completion.CompletionItem.SnippetSupport
+	var items []lsp.CompletionItem
+	start := offset
+	switch {
+	case strings.HasSuffix(docPath, ".sentinel"):
+		items, start = svc.sentinelCompletion(ctx, docPath, text, offset, sv, snippets)
+	case strings.HasSuffix(docPath, ".hcl"):
+		items, start = svc.configCompletion(ctx, docPath, text, offset, sv, snippets)
+	}
+
+	// Replace the text being completed, as clients do not agree on what a word is
+	rng := lsp.Range{Start: document.OffsetToPosition(text, start, enc), End: params.Position}
+	for _, item := range items {
+		if item.InsertText == "" {
+			item.InsertText = item.Label
+		}
+		item.TextEdit = &lsp.TextEdit{Range: rng, NewText: item.InsertText}
+		item.InsertText = ""
+		result.Items = append(result.Items, item)
+	}
+	return result, nil
+}
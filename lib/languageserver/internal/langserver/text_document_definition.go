@@ -0,0 +1,53 @@
+package langserver
+
+import (
+	"context"
+
+	ictx "github.com/glennsarti/sentinel-utils/lib/languageserver/internal/contexts"
+	"github.com/glennsarti/sentinel-utils/lib/languageserver/internal/document"
+	lsp "github.com/glennsarti/sentinel-utils/lib/languageserver/internal/protocol"
+	"github.com/glennsarti/sentinel-utils/lib/languageserver/internal/workspace"
+)
+
+func (svc *service) TextDocumentDefinition(ctx context.Context, params lsp.DefinitionParams) ([]lsp.Location, error) {
+	sv, err := ictx.SentinelVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	enc, err := ictx.PositionEncoding(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	docPath, text, err := svc.readDocument(params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	offset, err := document.PositionToOffset(text, params.Position, enc)
+	if err != nil {
+		return nil, nil
+	}
+
+	locations, err := svc.workspaceIndex.Definition(ctx, sv, docPath, offset)
+	if err != nil {
+		return nil, err
+	}
+	return svc.toLocations(locations, enc), nil
+}
+
+// Converts locations in the workspace index to the locations of documents
+func (svc *service) toLocations(locations []workspace.Location, enc lsp.PositionEncodingKind) []lsp.Location {
+	result := make([]lsp.Location, 0, len(locations))
+	for _, loc := range locations {
+		uri, err := svc.sessionFS.PathToUri(loc.File.Path)
+		if err != nil {
+			continue
+		}
+		result = append(result, lsp.Location{
+			URI:   uri,
+			Range: document.ToRange(loc.File.Text, loc.Range, enc),
+		})
+	}
+	return result
+}
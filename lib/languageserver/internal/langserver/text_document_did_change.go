@@ -24,18 +24,28 @@ func (svc *service) TextDocumentDidChange(ctx context.Context, params lsp.DidCha
 		return err
 	}
 
-	// Process the changes
-	for _, ce := range params.ContentChanges {
-		// No range means the whole document
-		if ce.Range == nil {
-			if err := ds.UpdateDocument(
-				params.TextDocument.URI,
-				int(params.TextDocument.Version),
-				[]byte(ce.Text),
-			); err != nil {
-				return err
-			}
-		}
+	mode, err := ictx.LintMode(ctx)
+	if err != nil {
+		return err
+	}
+
+	enc, err := ictx.PositionEncoding(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := ds.ChangeDocument(
+		params.TextDocument.URI,
+		int(params.TextDocument.Version),
+		params.ContentChanges,
+		enc,
+	); err != nil {
+		return err
+	}
+
+	// The document is linted when it is saved instead
+	if mode == lsp.LintOnSave {
+		return nil
 	}
 
 	req := queues.LintQueueRequest{
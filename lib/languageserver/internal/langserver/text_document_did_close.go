@@ -0,0 +1,41 @@
+package langserver
+
+import (
+	"context"
+
+	ictx "github.com/glennsarti/sentinel-utils/lib/languageserver/internal/contexts"
+	lsp "github.com/glennsarti/sentinel-utils/lib/languageserver/internal/protocol"
+	"github.com/glennsarti/sentinel-utils/lib/languageserver/internal/queues"
+)
+
+func (svc *service) TextDocumentDidClose(ctx context.Context, params lsp.DidCloseTextDocumentParams) error {
+	ds, err := ictx.DocumentStore(ctx)
+	if err != nil {
+		return err
+	}
+
+	lq, err := ictx.LintQueue(ctx)
+	if err != nil {
+		return err
+	}
+
+	sv, err := ictx.SentinelVersion(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Unsaved changes are discarded, so the file on disk is used from now on
+	if err := ds.RemoveDocument(params.TextDocument.URI); err != nil {
+		return err
+	}
+
+	req := queues.LintQueueRequest{
+		DocId:           string(params.TextDocument.URI),
+		SentinelVersion: sv,
+	}
+	if err := lq.Enqueue(req); err != nil {
+		return err
+	}
+
+	return nil
+}
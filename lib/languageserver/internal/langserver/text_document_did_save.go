@@ -0,0 +1,39 @@
+package langserver
+
+import (
+	"context"
+
+	ictx "github.com/glennsarti/sentinel-utils/lib/languageserver/internal/contexts"
+	lsp "github.com/glennsarti/sentinel-utils/lib/languageserver/internal/protocol"
+	"github.com/glennsarti/sentinel-utils/lib/languageserver/internal/queues"
+)
+
+func (svc *service) TextDocumentDidSave(ctx context.Context, params lsp.DidSaveTextDocumentParams) error {
+	ds, err := ictx.DocumentStore(ctx)
+	if err != nil {
+		return err
+	}
+
+	lq, err := ictx.LintQueue(ctx)
+	if err != nil {
+		return err
+	}
+
+	sv, err := ictx.SentinelVersion(ctx)
+	if err != nil {
+		return err
+	}
+
+	req := queues.LintQueueRequest{
+		DocId:           string(params.TextDocument.URI),
+		SentinelVersion: sv,
+	}
+	if doc, err := ds.GetDocument(params.TextDocument.URI); err == nil {
+		req.DocVersion = doc.Version
+	}
+	if err := lq.Enqueue(req); err != nil {
+		return err
+	}
+
+	return nil
+}
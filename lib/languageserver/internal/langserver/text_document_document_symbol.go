@@ -0,0 +1,49 @@
+package langserver
+
+import (
+	"context"
+	"strings"
+
+	ictx "github.com/glennsarti/sentinel-utils/lib/languageserver/internal/contexts"
+	"github.com/glennsarti/sentinel-utils/lib/languageserver/internal/outline"
+	lsp "github.com/glennsarti/sentinel-utils/lib/languageserver/internal/protocol"
+	"github.com/glennsarti/sentinel-utils/lib/languageserver/internal/symbols"
+)
+
+// Returns the outline of the document, or a flat list for clients which do not support
+// the hierarchy
+func (svc *service) TextDocumentDocumentSymbol(ctx context.Context, params lsp.DocumentSymbolParams) (any, error) {
+	clientCaps, err := ictx.ClientCapabilities(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sv, err := ictx.SentinelVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	enc, err := ictx.PositionEncoding(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	docPath, text, err := svc.readDocument(params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	syms := make([]lsp.DocumentSymbol, 0)
+	switch {
+	case strings.HasSuffix(docPath, ".sentinel"):
+		file := svc.parseSentinelFile(ctx, docPath, text, sv)
+		syms = outline.Sentinel(symbols.NewIndex(file, text), text, enc)
+	case strings.HasSuffix(docPath, ".hcl"):
+		syms = outline.Config(svc.parseConfigFile(ctx, docPath, text, sv), text, enc)
+	}
+
+	if clientCaps.TextDocument.DocumentSymbol.HierarchicalDocumentSymbolSupport {
+		return syms, nil
+	}
+	return outline.Flatten(syms, params.TextDocument.URI), nil
+}
@@ -0,0 +1,306 @@
+package langserver
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/glennsarti/sentinel-parser/position"
+	"github.com/glennsarti/sentinel-utils/lib/configuration"
+	"github.com/glennsarti/sentinel-utils/lib/internal/helpers"
+	ictx "github.com/glennsarti/sentinel-utils/lib/languageserver/internal/contexts"
+	"github.com/glennsarti/sentinel-utils/lib/languageserver/internal/document"
+	lsp "github.com/glennsarti/sentinel-utils/lib/languageserver/internal/protocol"
+	"github.com/glennsarti/sentinel-utils/lib/languageserver/internal/symbols"
+	"github.com/glennsarti/sentinel-utils/lib/linting"
+
+	sast "github.com/glennsarti/sentinel-parser/sentinel/ast"
+	scast "github.com/glennsarti/sentinel-parser/sentinel_config/ast"
+	scparser "github.com/glennsarti/sentinel-parser/sentinel_config/parser"
+)
+
+func (svc *service) TextDocumentHover(ctx context.Context, params lsp.HoverParams) (*lsp.Hover, error) {
+	clientCaps, err := ictx.ClientCapabilities(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sv, err := ictx.SentinelVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	enc, err := ictx.PositionEncoding(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	lq, err := ictx.LintQueue(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	docPath, text, err := svc.readDocument(params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	offset, err := document.PositionToOffset(text, params.Position, enc)
+	if err != nil {
+		return nil, nil
+	}
+
+	m := newMarkup(preferredMarkupKind(clientCaps.TextDocument.Hover.ContentFormat))
+	var rng *position.SourceRange
+	switch {
+	case strings.HasSuffix(docPath, ".sentinel"):
+		rng = svc.sentinelHover(ctx, m, docPath, text, offset, sv)
+	case strings.HasSuffix(docPath, ".hcl"):
+		rng = svc.configHover(ctx, m, docPath, text, offset, sv)
+	}
+
+	result := &lsp.Hover{
+		Range: lsp.Range{Start: params.Position, End: params.Position},
+	}
+	if rng != nil {
+		result.Range = document.ToRange(text, *rng, enc)
+	}
+
+	documented := make(map[string]bool)
+	for _, d := range lq.Diagnostics(svc.normaliseUri(params.TextDocument.URI)) {
+		if !rangeContains(d.Range, params.Position) {
+			continue
+		}
+		m.separator()
+		writeDiagnosticHover(m, d, documented)
+		if rng == nil {
+			result.Range = d.Range
+		}
+	}
+
+	if m.isEmpty() {
+		return nil, nil
+	}
+	result.Contents = m.content()
+	return result, nil
+}
+
+// Describes the rule, variable, function, param or import at the offset
+func (svc *service) sentinelHover(ctx context.Context, m *markup, docPath string, text []byte, offset int, sv string) *position.SourceRange {
+	file := svc.parseSentinelFile(ctx, docPath, text, sv)
+	if file == nil {
+		return nil
+	}
+	idx := symbols.NewIndex(file, text)
+
+	var sym *symbols.Symbol
+	var rng position.SourceRange
+	if ref := symbols.ReferenceAt(file, offset); ref != nil {
+		rng = ref.Ident.NodePos
+		if ref.Receiver != nil {
+			sym = svc.moduleMember(ctx, idx, ref, offset, sv)
+		} else {
+			sym = idx.Lookup(ref.Ident.Name, offset)
+		}
+	} else if sym = idx.DeclaredAt(offset); sym != nil {
+		// Imports without an alias do not have an identifier
+		rng = sym.NameRange
+	}
+	if sym == nil {
+		return nil
+	}
+
+	m.code("sentinel", sym.Declaration)
+	m.text(sym.Doc)
+	if sym.Kind == symbols.Import {
+		svc.writeImportHover(ctx, m, sym, sv)
+	}
+	return &rng
+}
+
+// Finds the member of a module import, for example name in module.name
+func (svc *service) moduleMember(ctx context.Context, idx *symbols.Index, ref *symbols.Reference, offset int, sv string) *symbols.Symbol {
+	receiver, ok := ref.Receiver.(*sast.Ident)
+	if !ok {
+		return nil
+	}
+	imp := idx.Lookup(receiver.Name, offset)
+	if imp == nil || imp.Kind != symbols.Import {
+		return nil
+	}
+
+	resolved, err := svc.resolveConfiguration(ctx, sv)
+	if err != nil {
+		return nil
+	}
+	modIdx, _ := svc.moduleIndex(ctx, resolved, imp.ImportName, sv)
+	if modIdx == nil {
+		return nil
+	}
+	for _, member := range modIdx.TopLevel() {
+		if member.Name == ref.Ident.Name && member.Kind != symbols.Import {
+			return member
+		}
+	}
+	return nil
+}
+
+// The symbols of a module in the resolved configuration, and its parsed file
+func (svc *service) moduleIndex(ctx context.Context, resolved *configuration.Resolved, name, sv string) (*symbols.Index, *sast.File) {
+	modPath, ok := resolved.ModulePath(svc.sessionFS, name)
+	if !ok {
+		return nil, nil
+	}
+	modText, err := svc.sessionFS.ReadFile(modPath)
+	if err != nil {
+		return nil, nil
+	}
+	modFile := svc.parseSentinelFile(ctx, modPath, modText, sv)
+	if modFile == nil {
+		return nil, nil
+	}
+	return symbols.NewIndex(modFile, modText), modFile
+}
+
+func (svc *service) writeImportHover(ctx context.Context, m *markup, imp *symbols.Symbol, sv string) {
+	if scparser.IsStdLibName(imp.ImportName) {
+		m.text(fmt.Sprintf("Standard import %s", m.inlineCode(imp.ImportName)))
+		return
+	}
+
+	resolved, err := svc.resolveConfiguration(ctx, sv)
+	if err != nil {
+		return
+	}
+	modPath, ok := resolved.ModulePath(svc.sessionFS, imp.ImportName)
+	if !ok {
+		return
+	}
+	m.text(fmt.Sprintf("Module %s", m.inlineCode(relativePath(resolved, modPath))))
+	if _, modFile := svc.moduleIndex(ctx, resolved, imp.ImportName, sv); modFile != nil {
+		m.text(symbols.CommentText(modFile.Doc))
+	}
+}
+
+// Describes the policy block at the offset
+func (svc *service) configHover(ctx context.Context, m *markup, docPath string, text []byte, offset int, sv string) *position.SourceRange {
+	cfg := svc.parseConfigFile(ctx, docPath, text, sv)
+	if cfg == nil {
+		return nil
+	}
+
+	for _, name := range helpers.SortedKeys(cfg.Policies) {
+		pol := cfg.Policies[name]
+		if pol == nil || pol.PolicyRange == nil {
+			continue
+		}
+		rng := document.BlockRange(text, *pol.PolicyRange)
+		if !containsOffset(rng, offset) {
+			continue
+		}
+
+		m.code("hcl", fmt.Sprintf("policy %q", name))
+		m.text(document.CommentsAbove(text, pol.PolicyRange.Start.Byte))
+		svc.writePolicyHover(ctx, m, pol, docPath, sv)
+		return &rng
+	}
+	return nil
+}
+
+// Describes the enforcement level and source of a policy, after the overrides are applied
+func (svc *service) writePolicyHover(ctx context.Context, m *markup, pol *scast.Policy, docPath string, sv string) {
+	resolved, err := svc.resolveConfiguration(ctx, sv)
+	if err != nil {
+		resolved = nil
+	}
+	if resolved != nil && resolved.File.Policies[pol.Name] != nil {
+		pol = resolved.File.Policies[pol.Name]
+	}
+
+	level := fmt.Sprintf("%s (default)", m.inlineCode(configuration.DefaultEnforcementLevel))
+	if pol.EnforcementLevel != "" {
+		level = m.inlineCode(pol.EnforcementLevel)
+		if resolved != nil {
+			if origin, ok := resolved.Provenance.Lookup("policy." + pol.Name + ".enforcement_level"); ok {
+				level += fmt.Sprintf(" (set in %s)", configuration.Location(origin.Effective().Range, filepath.Dir(resolved.PrimaryPath)))
+			}
+		}
+	}
+	m.text(fmt.Sprintf("%s: %s", m.bold("Enforcement level"), level))
+
+	policyPath := ""
+	if resolved != nil {
+		policyPath, _ = resolved.PolicyPath(svc.sessionFS, pol.Name)
+	} else if strings.HasPrefix(pol.Source, "./") {
+		policyPath = svc.sessionFS.PathJoin(svc.sessionFS.ParentPath(docPath), pol.Source[2:])
+	}
+	m.text(fmt.Sprintf("%s: %s", m.bold("Source"), m.inlineCode(pol.Source)))
+	if policyPath == "" {
+		return
+	}
+
+	policyText, err := svc.sessionFS.ReadFile(policyPath)
+	if err != nil {
+		m.text("The source file does not exist")
+		return
+	}
+	policyFile := svc.parseSentinelFile(ctx, policyPath, policyText, sv)
+	if policyFile == nil {
+		return
+	}
+	m.text(symbols.CommentText(policyFile.Doc))
+
+	names := make(map[symbols.Kind][]string)
+	for _, s := range symbols.NewIndex(policyFile, policyText).TopLevel() {
+		names[s.Kind] = append(names[s.Kind], m.inlineCode(s.Name))
+	}
+	summary := make([]string, 0)
+	for _, kind := range []struct {
+		kind  symbols.Kind
+		title string
+	}{
+		{symbols.Rule, "Rules"},
+		{symbols.Function, "Functions"},
+		{symbols.Param, "Params"},
+		{symbols.Import, "Imports"},
+	} {
+		if len(names[kind.kind]) > 0 {
+			summary = append(summary, fmt.Sprintf("%s: %s", kind.title, strings.Join(names[kind.kind], ", ")))
+		}
+	}
+	m.list(summary)
+}
+
+// Rules are only documented once, as a file can have the same problem many times
+func writeDiagnosticHover(m *markup, d lsp.Diagnostic, documented map[string]bool) {
+	code, _ := d.Code.(string)
+	if code == "" {
+		m.text(d.Message)
+		return
+	}
+	m.text(fmt.Sprintf("%s: %s", m.bold(code), d.Message))
+	if doc, ok := linting.RuleDocumentation(code); ok && !documented[code] {
+		documented[code] = true
+		m.text(doc)
+	}
+}
+
+// The path relative to the directory of the configuration
+func relativePath(resolved *configuration.Resolved, filePath string) string {
+	if rel, err := filepath.Rel(filepath.Dir(resolved.PrimaryPath), filePath); err == nil && !strings.HasPrefix(rel, "..") {
+		return "./" + filepath.ToSlash(rel)
+	}
+	return filePath
+}
+
+func containsOffset(rng position.SourceRange, offset int) bool {
+	return offset >= rng.Start.Byte && offset <= rng.End.Byte
+}
+
+func rangeContains(rng lsp.Range, pos lsp.Position) bool {
+	return !positionBefore(pos, rng.Start) && !positionBefore(rng.End, pos)
+}
+
+func positionBefore(a, b lsp.Position) bool {
+	return a.Line < b.Line || (a.Line == b.Line && a.Character < b.Character)
+}
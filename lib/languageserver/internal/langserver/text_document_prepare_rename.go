@@ -0,0 +1,39 @@
+package langserver
+
+import (
+	"context"
+
+	ictx "github.com/glennsarti/sentinel-utils/lib/languageserver/internal/contexts"
+	"github.com/glennsarti/sentinel-utils/lib/languageserver/internal/document"
+	lsp "github.com/glennsarti/sentinel-utils/lib/languageserver/internal/protocol"
+)
+
+func (svc *service) TextDocumentPrepareRename(ctx context.Context, params lsp.PrepareRenameParams) (*lsp.PrepareRenameResult, error) {
+	sv, err := ictx.SentinelVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	enc, err := ictx.PositionEncoding(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	docPath, text, err := svc.readDocument(params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	offset, err := document.PositionToOffset(text, params.Position, enc)
+	if err != nil {
+		return nil, nil
+	}
+
+	r, err := svc.workspaceIndex.PrepareRename(ctx, sv, docPath, offset)
+	if err != nil || r == nil {
+		return nil, err
+	}
+	return &lsp.PrepareRenameResult{
+		Range:       document.ToRange(text, r.At, enc),
+		Placeholder: r.Name,
+	}, nil
+}
@@ -0,0 +1,36 @@
+package langserver
+
+import (
+	"context"
+
+	ictx "github.com/glennsarti/sentinel-utils/lib/languageserver/internal/contexts"
+	"github.com/glennsarti/sentinel-utils/lib/languageserver/internal/document"
+	lsp "github.com/glennsarti/sentinel-utils/lib/languageserver/internal/protocol"
+)
+
+func (svc *service) TextDocumentReferences(ctx context.Context, params lsp.ReferenceParams) ([]lsp.Location, error) {
+	sv, err := ictx.SentinelVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	enc, err := ictx.PositionEncoding(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	docPath, text, err := svc.readDocument(params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	offset, err := document.PositionToOffset(text, params.Position, enc)
+	if err != nil {
+		return nil, nil
+	}
+
+	locations, err := svc.workspaceIndex.References(ctx, sv, docPath, offset, params.Context.IncludeDeclaration)
+	if err != nil {
+		return nil, err
+	}
+	return svc.toLocations(locations, enc), nil
+}
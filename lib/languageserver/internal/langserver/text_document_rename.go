@@ -0,0 +1,119 @@
+package langserver
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	ictx "github.com/glennsarti/sentinel-utils/lib/languageserver/internal/contexts"
+	"github.com/glennsarti/sentinel-utils/lib/languageserver/internal/document"
+	lsp "github.com/glennsarti/sentinel-utils/lib/languageserver/internal/protocol"
+	"github.com/glennsarti/sentinel-utils/lib/languageserver/internal/workspace"
+)
+
+// Renames a symbol or policy in every file of the policy set. Renaming a policy also
+// renames its test directory, which needs an editor that can rename files.
+func (svc *service) TextDocumentRename(ctx context.Context, params lsp.RenameParams) (*lsp.WorkspaceEdit, error) {
+	clientCaps, err := ictx.ClientCapabilities(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sv, err := ictx.SentinelVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	enc, err := ictx.PositionEncoding(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ds, err := ictx.DocumentStore(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	docPath, text, err := svc.readDocument(params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	offset, err := document.PositionToOffset(text, params.Position, enc)
+	if err != nil {
+		return nil, nil
+	}
+
+	r, err := svc.workspaceIndex.Rename(ctx, sv, docPath, offset, params.NewName)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", requestFailedCode.Err(), err)
+	}
+	if r == nil {
+		return nil, nil
+	}
+
+	uris := make([]lsp.DocumentURI, 0)
+	edits := make(map[lsp.DocumentURI][]lsp.TextEdit)
+	for _, loc := range r.Locations {
+		uri, err := svc.sessionFS.PathToUri(loc.File.Path)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := edits[uri]; !ok {
+			uris = append(uris, uri)
+		}
+		edits[uri] = append(edits[uri], lsp.TextEdit{
+			Range:   document.ToRange(loc.File.Text, loc.Range, enc),
+			NewText: workspace.ReplacementText(loc, params.NewName),
+		})
+	}
+	if len(r.Directories) == 0 || params.NewName == r.Name {
+		return &lsp.WorkspaceEdit{Changes: edits}, nil
+	}
+
+	editCaps := clientCaps.Workspace.WorkspaceEdit
+	if editCaps == nil || !editCaps.DocumentChanges || !slices.Contains(editCaps.ResourceOperations, lsp.Rename) {
+		return nil, fmt.Errorf("%w: the editor can not rename the test directory %s", requestFailedCode.Err(), r.Directories[0])
+	}
+
+	// Renaming onto an existing directory would merge or replace the tests which are there
+	for _, dir := range r.Directories {
+		target := svc.sessionFS.PathJoin(svc.sessionFS.ParentPath(dir), params.NewName)
+		if _, err := svc.sessionFS.Stat(target); err == nil {
+			return nil, fmt.Errorf("%w: the test directory %s already exists", requestFailedCode.Err(), target)
+		}
+	}
+
+	// The files are edited before the test directories are renamed
+	result := &lsp.WorkspaceEdit{DocumentChanges: make([]lsp.DocumentChanges, 0)}
+	for _, uri := range uris {
+		// Documents which are not open do not have a version, so the file on disk is edited
+		var version *int32
+		if doc, err := ds.GetDocument(uri); err == nil {
+			v := int32(doc.Version)
+			version = &v
+		}
+		result.DocumentChanges = append(result.DocumentChanges, lsp.DocumentChanges{
+			TextDocumentEdit: &lsp.TextDocumentEdit{
+				TextDocument: lsp.OptionalVersionedTextDocumentIdentifier{
+					Version:                version,
+					TextDocumentIdentifier: lsp.TextDocumentIdentifier{URI: uri},
+				},
+				Edits: edits[uri],
+			},
+		})
+	}
+	for _, dir := range r.Directories {
+		oldUri, err := svc.sessionFS.PathToUri(dir)
+		if err != nil {
+			return nil, err
+		}
+		newUri, err := svc.sessionFS.PathToUri(svc.sessionFS.PathJoin(svc.sessionFS.ParentPath(dir), params.NewName))
+		if err != nil {
+			return nil, err
+		}
+		result.DocumentChanges = append(result.DocumentChanges, lsp.DocumentChanges{
+			RenameFile: &lsp.RenameFile{Kind: string(lsp.Rename), OldURI: oldUri, NewURI: newUri},
+		})
+	}
+	return result, nil
+}
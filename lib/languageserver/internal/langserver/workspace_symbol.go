@@ -0,0 +1,62 @@
+package langserver
+
+import (
+	"context"
+	"sort"
+
+	ictx "github.com/glennsarti/sentinel-utils/lib/languageserver/internal/contexts"
+	"github.com/glennsarti/sentinel-utils/lib/languageserver/internal/outline"
+	lsp "github.com/glennsarti/sentinel-utils/lib/languageserver/internal/protocol"
+)
+
+// Searches the symbols of every file in the policy set, with the best matches first
+func (svc *service) WorkspaceSymbol(ctx context.Context, params lsp.WorkspaceSymbolParams) ([]lsp.SymbolInformation, error) {
+	sv, err := ictx.SentinelVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	enc, err := ictx.PositionEncoding(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := svc.workspaceIndex.Files(ctx, sv)
+	if err != nil {
+		return nil, err
+	}
+
+	type match struct {
+		symbol lsp.SymbolInformation
+		score  int
+	}
+	matches := make([]match, 0)
+	for _, f := range files {
+		uri, err := svc.sessionFS.PathToUri(f.Path)
+		if err != nil {
+			continue
+		}
+		var syms []lsp.DocumentSymbol
+		switch {
+		case f.Symbols != nil:
+			syms = outline.Sentinel(f.Symbols, f.Text, enc)
+		case f.Config != nil:
+			syms = outline.Config(f.Config, f.Text, enc)
+		}
+		for _, s := range outline.Flatten(syms, uri) {
+			if score, ok := outline.Match(params.Query, s.Name); ok {
+				matches = append(matches, match{symbol: s, score: score})
+			}
+		}
+	}
+
+	// Files are in the order they were walked, so equal matches keep that order
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].score > matches[j].score
+	})
+	result := make([]lsp.SymbolInformation, 0, len(matches))
+	for _, m := range matches {
+		result = append(result, m.symbol)
+	}
+	return result, nil
+}
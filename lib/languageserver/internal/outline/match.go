@@ -0,0 +1,39 @@
+package outline
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Match is whether the characters of the query are in the name in the same order,
+// ignoring case. Higher scores are better matches, for example when the characters are
+// next to each other or start words. An empty query matches every name.
+func Match(query, name string) (int, bool) {
+	q := []rune(strings.ToLower(query))
+	n := []rune(name)
+	score := 0
+	matched := 0
+	previous := -2
+	for i := 0; i < len(n) && matched < len(q); i++ {
+		if unicode.ToLower(n[i]) != q[matched] {
+			continue
+		}
+		switch {
+		case i == previous+1:
+			score += 3
+		case i == 0 || !unicode.IsLetter(n[i-1]) || (unicode.IsUpper(n[i]) && unicode.IsLower(n[i-1])):
+			score += 2
+		default:
+			score++
+		}
+		previous = i
+		matched++
+	}
+	if matched < len(q) {
+		return 0, false
+	}
+	if strings.HasPrefix(strings.ToLower(name), string(q)) {
+		score += len(q)
+	}
+	return score, true
+}
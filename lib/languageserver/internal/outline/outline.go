@@ -0,0 +1,214 @@
+package outline
+
+import (
+	"sort"
+
+	"github.com/glennsarti/sentinel-parser/position"
+	"github.com/glennsarti/sentinel-utils/lib/internal/helpers"
+	"github.com/glennsarti/sentinel-utils/lib/languageserver/internal/document"
+	lsp "github.com/glennsarti/sentinel-utils/lib/languageserver/internal/protocol"
+	"github.com/glennsarti/sentinel-utils/lib/languageserver/internal/symbols"
+
+	scast "github.com/glennsarti/sentinel-parser/sentinel_config/ast"
+)
+
+// Sentinel is the outline of a Sentinel file. The parameters and variables of functions
+// and rules are their children.
+func Sentinel(idx *symbols.Index, text []byte, enc lsp.PositionEncodingKind) []lsp.DocumentSymbol {
+	syms := idx.Symbols()
+	children := make(map[*symbols.Symbol][]*symbols.Symbol)
+	roots := make([]*symbols.Symbol, 0)
+	for _, s := range syms {
+		if parent := container(syms, s); parent != nil {
+			children[parent] = append(children[parent], s)
+		} else {
+			roots = append(roots, s)
+		}
+	}
+
+	var convert func(s *symbols.Symbol) lsp.DocumentSymbol
+	convert = func(s *symbols.Symbol) lsp.DocumentSymbol {
+		rng := s.Range
+		// Function parameters and iterators use the range of what declares them
+		if s.Local && s.Kind == symbols.Variable && s.Range.Start.Byte != s.NameRange.Start.Byte {
+			rng = s.NameRange
+		}
+		result := lsp.DocumentSymbol{
+			Name:           s.Name,
+			Detail:         sentinelDetail(s),
+			Kind:           sentinelKind(s.Kind),
+			Range:          document.ToRange(text, rng, enc),
+			SelectionRange: document.ToRange(text, s.NameRange, enc),
+		}
+		for _, child := range children[s] {
+			result.Children = append(result.Children, convert(child))
+		}
+		return result
+	}
+
+	result := make([]lsp.DocumentSymbol, 0, len(roots))
+	for _, s := range roots {
+		result = append(result, convert(s))
+	}
+	return result
+}
+
+// The smallest function or rule which declares the symbol
+func container(syms []*symbols.Symbol, s *symbols.Symbol) *symbols.Symbol {
+	var result *symbols.Symbol
+	for _, c := range syms {
+		if c == s || (c.Kind != symbols.Function && c.Kind != symbols.Rule) {
+			continue
+		}
+		if s.NameRange.Start.Byte < c.Range.Start.Byte || s.NameRange.End.Byte > c.Range.End.Byte {
+			continue
+		}
+		if result == nil || c.Range.End.Byte-c.Range.Start.Byte < result.Range.End.Byte-result.Range.Start.Byte {
+			result = c
+		}
+	}
+	return result
+}
+
+func sentinelKind(kind symbols.Kind) lsp.SymbolKind {
+	switch kind {
+	case symbols.Import:
+		return lsp.Module
+	case symbols.Param:
+		return lsp.Constant
+	case symbols.Function:
+		return lsp.Function
+	case symbols.Rule:
+		return lsp.Field
+	default:
+		return lsp.Variable
+	}
+}
+
+func sentinelDetail(s *symbols.Symbol) string {
+	if s.Kind == symbols.Import && s.ImportName != s.Name {
+		return s.ImportName
+	}
+	return s.Kind.String()
+}
+
+// Config is the outline of a configuration or test file. The params of policies and the
+// rules of tests are their children.
+func Config(cfg *scast.File, text []byte, enc lsp.PositionEncodingKind) []lsp.DocumentSymbol {
+	result := make([]lsp.DocumentSymbol, 0)
+	if cfg == nil {
+		return result
+	}
+	// The parser only gives the range of the block headers
+	block := func(name, detail string, kind lsp.SymbolKind, header, nameRange *position.SourceRange) *lsp.DocumentSymbol {
+		if header == nil {
+			return nil
+		}
+		if nameRange == nil {
+			nameRange = header
+		}
+		return &lsp.DocumentSymbol{
+			Name:           name,
+			Detail:         detail,
+			Kind:           kind,
+			Range:          document.ToRange(text, document.BlockRange(text, *header), enc),
+			SelectionRange: document.ToRange(text, *nameRange, enc),
+		}
+	}
+	add := func(s *lsp.DocumentSymbol) {
+		if s != nil {
+			result = append(result, *s)
+		}
+	}
+
+	for _, name := range helpers.SortedKeys(cfg.Imports) {
+		switch imp := cfg.Imports[name].(type) {
+		case *scast.V1ModuleImport:
+			add(block(name, "module", lsp.Module, imp.BlockRange, imp.NameRange))
+		case *scast.V1PluginImport:
+			add(block(name, "plugin", lsp.Module, imp.BlockRange, imp.NameRange))
+		case *scast.V2ModuleImport:
+			add(block(name, "module", lsp.Module, imp.BlockRange, imp.NameRange))
+		case *scast.V2PluginImport:
+			add(block(name, "plugin", lsp.Module, imp.BlockRange, imp.NameRange))
+		case *scast.V2StaticImport:
+			add(block(name, "static", lsp.Module, imp.BlockRange, imp.NameRange))
+		}
+	}
+	for _, name := range helpers.SortedKeys(cfg.Params) {
+		if param := cfg.Params[name]; param != nil {
+			add(block(name, "param", lsp.Constant, param.ParameterRange, param.NameRange))
+		}
+	}
+	for _, name := range helpers.SortedKeys(cfg.Globals) {
+		if global := cfg.Globals[name]; global != nil {
+			add(block(name, "global", lsp.Variable, global.GlobalRange, global.NameRange))
+		}
+	}
+	for _, name := range helpers.SortedKeys(cfg.Mocks) {
+		if mock := cfg.Mocks[name]; mock != nil {
+			add(block(name, "mock", lsp.Object, mock.MockRange, mock.NameRange))
+		}
+	}
+	for _, name := range helpers.SortedKeys(cfg.Policies) {
+		pol := cfg.Policies[name]
+		if pol == nil {
+			continue
+		}
+		s := block(name, pol.Source, lsp.Object, pol.PolicyRange, pol.NameRange)
+		if s == nil {
+			continue
+		}
+		for _, paramName := range helpers.SortedKeys(pol.Params) {
+			if param := pol.Params[paramName]; param != nil && param.NameRange != nil {
+				s.Children = append(s.Children, leaf(paramName, "param", lsp.Constant, *param.NameRange, text, enc))
+			}
+		}
+		add(s)
+	}
+	if cfg.Test != nil {
+		if s := block("test", "", lsp.Object, cfg.Test.TestRange, nil); s != nil {
+			for _, rule := range cfg.Test.Rules {
+				if rule != nil && rule.NameRange != nil {
+					s.Children = append(s.Children, leaf(rule.Name, "rule", lsp.Field, *rule.NameRange, text, enc))
+				}
+			}
+			add(s)
+		}
+	}
+
+	// In the order they are written
+	sort.SliceStable(result, func(i, j int) bool {
+		return positionBefore(result[i].Range.Start, result[j].Range.Start)
+	})
+	return result
+}
+
+func leaf(name, detail string, kind lsp.SymbolKind, rng position.SourceRange, text []byte, enc lsp.PositionEncodingKind) lsp.DocumentSymbol {
+	r := document.ToRange(text, rng, enc)
+	return lsp.DocumentSymbol{Name: name, Detail: detail, Kind: kind, Range: r, SelectionRange: r}
+}
+
+func positionBefore(a, b lsp.Position) bool {
+	return a.Line < b.Line || (a.Line == b.Line && a.Character < b.Character)
+}
+
+// Flatten lists the symbols and their children, for clients which do not show the
+// hierarchy
+func Flatten(syms []lsp.DocumentSymbol, uri lsp.DocumentURI) []lsp.SymbolInformation {
+	result := make([]lsp.SymbolInformation, 0, len(syms))
+	var add func(syms []lsp.DocumentSymbol, containerName string)
+	add = func(syms []lsp.DocumentSymbol, containerName string) {
+		for _, s := range syms {
+			result = append(result, lsp.SymbolInformation{
+				Name:          s.Name,
+				Kind:          s.Kind,
+				Location:      lsp.Location{URI: uri, Range: s.Range},
+				ContainerName: containerName,
+			})
+			add(s.Children, s.Name)
+		}
+	}
+	add(syms, "")
+	return result
+}
@@ -0,0 +1,122 @@
+package outline
+
+import (
+	"strings"
+	"testing"
+
+	lsp "github.com/glennsarti/sentinel-utils/lib/languageserver/internal/protocol"
+	"github.com/glennsarti/sentinel-utils/lib/languageserver/internal/symbols"
+
+	sparser "github.com/glennsarti/sentinel-parser/sentinel/parser"
+	scparser "github.com/glennsarti/sentinel-parser/sentinel_config/parser"
+)
+
+// Describes the symbols as name(children), in order
+func describe(syms []lsp.DocumentSymbol) string {
+	parts := make([]string, 0, len(syms))
+	for _, s := range syms {
+		if len(s.Children) > 0 {
+			parts = append(parts, s.Name+"("+describe(s.Children)+")")
+		} else {
+			parts = append(parts, s.Name)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+func TestSentinel(t *testing.T) {
+	src := []byte(`import "strings" as s
+
+param limit default 10
+
+add = func(x) {
+  y = x + 1
+  return y
+}
+
+func double(v) {
+  return v * 2
+}
+
+main = rule { all [1, 2] as i { add(i) < limit } }
+`)
+	file, _, diags, err := sparser.ParseFile("", "policy.sentinel", src)
+	if err != nil || diags.HasErrors() {
+		t.Fatalf("failed to parse: %v %v", err, diags)
+	}
+	syms := Sentinel(symbols.NewIndex(file, src), src, lsp.UTF8)
+
+	expected := "s limit add(x y) double(v) main(i)"
+	if actual := describe(syms); actual != expected {
+		t.Errorf("expected %q but got %q", expected, actual)
+	}
+	if syms[0].Kind != lsp.Module || syms[0].Detail != "strings" {
+		t.Errorf("expected the import to be a module of strings but got %v", syms[0])
+	}
+
+	// The parameter is only the name, so it is inside the function
+	x := syms[2].Children[0]
+	if x.Range != x.SelectionRange {
+		t.Errorf("expected the range of x to be its name but got %v", x.Range)
+	}
+}
+
+func TestConfig(t *testing.T) {
+	src := []byte(`policy "b" {
+  source = "./b.sentinel"
+  params = {
+    limit = 1
+  }
+}
+
+import "module" "helpers" {
+  source = "./helpers.sentinel"
+}
+
+policy "a" {
+  source = "./a.sentinel"
+}
+`)
+	cfg, _, err := scparser.ParseFile("", "sentinel.hcl", src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	syms := Config(cfg, src, lsp.UTF8)
+
+	expected := "b(limit) helpers a"
+	if actual := describe(syms); actual != expected {
+		t.Errorf("expected %q but got %q", expected, actual)
+	}
+	if syms[0].Range.End.Line != 5 {
+		t.Errorf("expected the policy to end on line 5 but got %v", syms[0].Range)
+	}
+
+	flat := Flatten(syms, "file:///sentinel.hcl")
+	if len(flat) != 4 || flat[1].Name != "limit" || flat[1].ContainerName != "b" {
+		t.Errorf("expected the children to be flattened after their container but got %v", flat)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	for _, testcase := range []struct {
+		query string
+		name  string
+		match bool
+	}{
+		{query: "", name: "main", match: true},
+		{query: "mn", name: "main", match: true},
+		{query: "MAIN", name: "main", match: true},
+		{query: "nm", name: "main", match: false},
+		{query: "mains", name: "main", match: false},
+	} {
+		if _, ok := Match(testcase.query, testcase.name); ok != testcase.match {
+			t.Errorf("expected %q matching %q to be %t", testcase.query, testcase.name, testcase.match)
+		}
+	}
+
+	prefix, _ := Match("che", "check_region")
+	scattered, _ := Match("che", "cache_entry")
+	if prefix <= scattered {
+		t.Errorf("expected a prefix to score more than scattered characters, but got %d and %d", prefix, scattered)
+	}
+}
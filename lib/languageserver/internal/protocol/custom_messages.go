@@ -16,3 +16,17 @@ type SentinelSetVersionRequest struct {
 	Version string `json:"version"`
 }
 type SetSentinelVersionResponse SentinelVersionParams
+
+// Initialization Options ( Client --> Server )
+type SentinelInitializationOptions struct {
+	// The Sentinel version to use, which can be "auto" to detect it from the workspace
+	SentinelVersion string `json:"sentinelVersion,omitempty"`
+	// When to lint documents, either "change" or "save". Documents are always linted when opened or closed.
+	LintOn string `json:"lintOn,omitempty"`
+}
+
+// Lint modes
+const (
+	LintOnChange = "change"
+	LintOnSave   = "save"
+)
@@ -3066,7 +3066,7 @@ type OptionalVersionedTextDocumentIdentifier struct { // line 9363
 	 * `null` to indicate that the version is unknown and the content on disk is the
 	 * truth (as specified with document content ownership).
 	 */
-	Version int32 `json:"version"`
+	Version *int32 `json:"version"`
 	TextDocumentIdentifier
 }
 
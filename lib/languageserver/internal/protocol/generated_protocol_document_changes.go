@@ -8,10 +8,11 @@ import (
 	"fmt"
 )
 
-// DocumentChanges is a union of a file edit and directory rename operations
-// for package renaming feature. At most one field of this struct is non-nil.
+// DocumentChanges is a union of a file edit, and file create and rename operations.
+// At most one field of this struct is non-nil.
 type DocumentChanges struct {
 	TextDocumentEdit *TextDocumentEdit
+	CreateFile       *CreateFile
 	RenameFile       *RenameFile
 }
 
@@ -27,6 +28,11 @@ func (d *DocumentChanges) UnmarshalJSON(data []byte) error {
 		return json.Unmarshal(data, d.TextDocumentEdit)
 	}
 
+	if m["kind"] == string(Create) {
+		d.CreateFile = new(CreateFile)
+		return json.Unmarshal(data, d.CreateFile)
+	}
+
 	d.RenameFile = new(RenameFile)
 	return json.Unmarshal(data, d.RenameFile)
 }
@@ -34,6 +40,8 @@ func (d *DocumentChanges) UnmarshalJSON(data []byte) error {
 func (d *DocumentChanges) MarshalJSON() ([]byte, error) {
 	if d.TextDocumentEdit != nil {
 		return json.Marshal(d.TextDocumentEdit)
+	} else if d.CreateFile != nil {
+		return json.Marshal(d.CreateFile)
 	} else if d.RenameFile != nil {
 		return json.Marshal(d.RenameFile)
 	}
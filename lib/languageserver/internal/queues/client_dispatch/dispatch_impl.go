@@ -44,6 +44,6 @@ func (dq *dipatchQueue) Stop()                                {}
 func (dq *dipatchQueue) Name() string                         { return "clientNotifyDisptachQueue" }
 func (dq *dipatchQueue) Logger() *log.Logger                  { return dq.logger }
 
-func (dq *dipatchQueue) process(job queues.ClientNotifyDispatchRequest) error {
-	return dq.disptacher.Notify(context.TODO(), job.Method, job.Params)
+func (dq *dipatchQueue) process(ctx context.Context, job queues.ClientNotifyDispatchRequest) error {
+	return dq.disptacher.Notify(ctx, job.Method, job.Params)
 }
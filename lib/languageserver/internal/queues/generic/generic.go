@@ -9,7 +9,7 @@ import (
 func NewGenericQueue[J any](
 	maxWorkers int,
 	maxJobs int,
-	processor func(job J) error,
+	processor func(ctx context.Context, job J) error,
 ) *GenericQueue[J] {
 	return &GenericQueue[J]{
 		maxWorkers: maxWorkers,
@@ -21,7 +21,7 @@ func NewGenericQueue[J any](
 type GenericQueue[J any] struct {
 	maxWorkers int
 	maxJobs    int
-	processor  func(job J) error
+	processor  func(ctx context.Context, job J) error
 	jobs       chan J
 	running    bool
 }
@@ -34,7 +34,7 @@ func (gq *GenericQueue[J]) StartAsync(ctx context.Context) error {
 	return nil
 }
 
-func (gq *GenericQueue[J]) Start(_ context.Context) error {
+func (gq *GenericQueue[J]) Start(ctx context.Context) error {
 	if gq.running {
 		return errors.New("queue already running")
 	}
@@ -46,7 +46,7 @@ func (gq *GenericQueue[J]) Start(_ context.Context) error {
 
 	for range gq.maxWorkers {
 		wg.Add(1)
-		go gq.executor(gq.jobs, cancelChan)
+		go gq.executor(ctx, gq.jobs, cancelChan)
 	}
 
 	wg.Wait()
@@ -71,15 +71,18 @@ func (gq *GenericQueue[J]) Enqueue(job J) bool {
 	}
 }
 
-func (gq *GenericQueue[J]) executor(jobChan <-chan J, cancelChan <-chan struct{}) {
+func (gq *GenericQueue[J]) executor(ctx context.Context, jobChan <-chan J, cancelChan <-chan struct{}) {
 	for {
 		select {
 		case <-cancelChan:
 			return
 
+		case <-ctx.Done():
+			return
+
 		case job := <-jobChan:
 			// TODO: Should really handle errors here.
-			_ = gq.processor(job)
+			_ = gq.processor(ctx, job)
 		}
 	}
 }
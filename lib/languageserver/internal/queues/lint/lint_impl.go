@@ -6,10 +6,12 @@ import (
 	"log"
 	"sync"
 
+	"github.com/glennsarti/sentinel-parser/position"
+	"github.com/glennsarti/sentinel-utils/lib/languageserver/internal/document"
 	"github.com/glennsarti/sentinel-utils/lib/languageserver/internal/filesystem"
 	lsp "github.com/glennsarti/sentinel-utils/lib/languageserver/internal/protocol"
 
-	parsing "github.com/glennsarti/sentinel-utils/lib/parsing/default"
+	"github.com/glennsarti/sentinel-utils/lib/parsing"
 	cwalker "github.com/glennsarti/sentinel-utils/lib/walkers/sentinel_config"
 
 	slint "github.com/glennsarti/sentinel-lint/lint"
@@ -23,7 +25,9 @@ var _ queues.LintQueue = &lintQueue{}
 func NewLintQueue(
 	queueSize int,
 	rootUri lsp.DocumentURI,
+	positionEncoding lsp.PositionEncodingKind,
 	fsys filesystem.SessionFS,
+	parseFactory parsing.CachingFactory,
 	dispatchQueue queues.ClientNotifyDispatchQueue,
 	logger *log.Logger,
 ) (queues.LintQueue, error) {
@@ -31,9 +35,12 @@ func NewLintQueue(
 		logger:          logger,
 		fsys:            fsys,
 		rootUri:         rootUri,
+		encoding:        positionEncoding,
 		dispatchQueue:   dispatchQueue,
 		issueIndex:      0,
 		filesWithIssues: make(map[string]int, 0),
+		diagnostics:     make(map[string][]lsp.Diagnostic, 0),
+		parseFactory:    parseFactory,
 	}
 	lq.baseq = generic.NewGenericQueue(1, queueSize, lq.process)
 
@@ -45,45 +52,100 @@ type lintQueue struct {
 	baseq           *generic.GenericQueue[queues.LintQueueRequest]
 	fsys            filesystem.SessionFS
 	rootUri         lsp.DocumentURI
+	encoding        lsp.PositionEncodingKind
 	dispatchQueue   queues.ClientNotifyDispatchQueue
 	muWriter        sync.Mutex
 	issueIndex      int
 	filesWithIssues map[string]int
+	diagnostics     map[string][]lsp.Diagnostic
+	parseFactory    parsing.CachingFactory
+
+	// Newer lint jobs make older ones stale, so they are cancelled
+	muJobs      sync.Mutex
+	pendingJobs int
+	cancelJob   context.CancelFunc
 }
 
 type allIssues = map[string]slint.Issues
 
 func (lq *lintQueue) Enqueue(req queues.LintQueueRequest) error {
-	lq.baseq.Enqueue(req)
+	lq.muJobs.Lock()
+	defer lq.muJobs.Unlock()
+
+	if !lq.baseq.Enqueue(req) {
+		return nil
+	}
+	lq.pendingJobs++
+	if lq.cancelJob != nil {
+		lq.cancelJob()
+	}
 	return nil
 }
+
+func (lq *lintQueue) Diagnostics(uri lsp.DocumentURI) []lsp.Diagnostic {
+	lq.muWriter.Lock()
+	defer lq.muWriter.Unlock()
+
+	return lq.diagnostics[string(uri)]
+}
+
+// startJob returns the context to run the job with, or nil if a newer job is already queued
+func (lq *lintQueue) startJob(ctx context.Context) (context.Context, context.CancelFunc) {
+	lq.muJobs.Lock()
+	defer lq.muJobs.Unlock()
+
+	lq.pendingJobs--
+	if lq.pendingJobs > 0 {
+		return nil, nil
+	}
+	jobCtx, cancel := context.WithCancel(ctx)
+	lq.cancelJob = cancel
+	return jobCtx, cancel
+}
 func (lq *lintQueue) Start(ctx context.Context) error      { return lq.baseq.Start(ctx) }
 func (lq *lintQueue) StartAsync(ctx context.Context) error { return lq.baseq.StartAsync(ctx) }
 func (lq *lintQueue) Stop()                                {}
 func (lq *lintQueue) Name() string                         { return "lintQueue" }
 func (lq *lintQueue) Logger() *log.Logger                  { return lq.logger }
 
-func (lq *lintQueue) process(job queues.LintQueueRequest) error {
+func (lq *lintQueue) process(ctx context.Context, job queues.LintQueueRequest) error {
+	jobCtx, cancel := lq.startJob(ctx)
+	if jobCtx == nil {
+		// Stale
+		return nil
+	}
+	defer cancel()
+
 	rootPath, err := lq.fsys.UriToPath(lq.rootUri)
 	if err != nil {
 		return errors.New("failed to convert root URI to path: " + err.Error())
 	}
 
-	// Expensive but fine
-	pf := parsing.NewDefaultParsingFactory(lq.fsys)
+	// The previous content of the changed document will not be used again
+	if job.DocId != "" {
+		if docPath, err := lq.fsys.UriToPath(lsp.DocumentURI(job.DocId)); err == nil {
+			lq.parseFactory.Invalidate(docPath)
+		}
+	}
+
+	pf := lq.parseFactory
 	walker := cwalker.NewSentinelConfigWalker(
 		lq.fsys,
 		rootPath,
 		job.SentinelVersion,
 		pf,
+		cwalker.WithContinueOnError(),
 	)
 	if walker == nil {
 		return errors.New("failed to create walker")
 	}
 
 	issuesList := make(allIssues, 0)
+	// The files which the issues say do not exist, which code actions can create
+	missingFiles := make(map[*slint.Issue]string)
 
-	if err := linting.Lint(walker, pf, func(lintFile slint.File, issues slint.Issues) {
+	// Syntax errors may be from choosing a Sentinel version which is too old, so say when they are
+	if err := linting.Lint(jobCtx, walker, pf, func(lintFile slint.File, issues slint.Issues) {
 		if len(issues) > 0 {
 			if _, ok := issuesList[lintFile.Path()]; ok {
 				issuesList[lintFile.Path()] = append(issuesList[lintFile.Path()], issues...)
@@ -91,23 +153,33 @@ func (lq *lintQueue) process(job queues.LintQueueRequest) error {
 				issuesList[lintFile.Path()] = issues
 			}
 		}
-	}); err != nil {
+	},
+		linting.WithRequiredVersionCheck(""),
+		linting.WithMissingFiles(func(issue *slint.Issue, filePath string) { missingFiles[issue] = filePath }),
+	); err != nil {
+		// The results of a cancelled job are incomplete, and a newer job will send its own
+		if cwalker.IsCancelled(err) {
+			return nil
+		}
 		return err
 	}
 
-	if err := lq.sendIssues(&issuesList); err != nil {
+	if err := lq.sendIssues(&issuesList, missingFiles); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-func (lq *lintQueue) sendIssues(issues *allIssues) error {
+func (lq *lintQueue) sendIssues(issues *allIssues, missingFiles map[*slint.Issue]string) error {
 	lq.muWriter.Lock()
 	defer lq.muWriter.Unlock()
 
 	lq.issueIndex++
 
+	// The content of each file, to convert the issue ranges into the position encoding
+	sources := make(map[string][]byte)
+
 	for filePath, fileIssues := range *issues {
 		fileUri, err := lq.fsys.PathToUri(filePath)
 		if err != nil {
@@ -123,10 +195,12 @@ func (lq *lintQueue) sendIssues(issues *allIssues) error {
 
 		for idx, issue := range fileIssues {
 			if issue != nil {
-				resp.Diagnostics[idx] = lq.toDiagnostic(*issue)
+				resp.Diagnostics[idx] = lq.toDiagnostic(*issue, missingFiles[issue], filePath, sources)
 			}
 		}
 
+		lq.diagnostics[string(fileUri)] = resp.Diagnostics
+
 		if err := lq.dispatchQueue.Enqueue(queues.ClientNotifyDispatchRequest{
 			Method: "textDocument/publishDiagnostics",
 			Params: resp,
@@ -147,29 +221,22 @@ func (lq *lintQueue) sendIssues(issues *allIssues) error {
 				return err
 			}
 			delete(lq.filesWithIssues, uri)
+			delete(lq.diagnostics, uri)
 		}
 	}
 
 	return nil
 }
 
-func (lq *lintQueue) toDiagnostic(issue slint.Issue) lsp.Diagnostic {
+func (lq *lintQueue) toDiagnostic(issue slint.Issue, missingFile string, filePath string, sources map[string][]byte) lsp.Diagnostic {
 	d := lsp.Diagnostic{
-		Range: lsp.Range{
-			Start: lsp.Position{
-				Line:      uint32(issue.Range.Start.Line),
-				Character: uint32(issue.Range.Start.Column),
-			},
-			End: lsp.Position{
-				Line:      uint32(issue.Range.End.Line),
-				Character: uint32(issue.Range.End.Column),
-			},
-		},
+		Range:    lq.toRange(issue.Range, filePath, sources),
 		Message:  issue.Detail,
 		Code:     issue.RuleId,
 		Source:   "sentinel-lint",
 		Severity: lsp.SeverityError,
 	}
+	d.Data = queues.DiagnosticData{RuleId: issue.RuleId, MissingFile: missingFile}
 
 	switch issue.Severity {
 	case slint.Information:
@@ -181,21 +248,16 @@ func (lq *lintQueue) toDiagnostic(issue slint.Issue) lsp.Diagnostic {
 	if issue.Related != nil {
 		d.RelatedInformation = make([]lsp.DiagnosticRelatedInformation, len(*issue.Related))
 		for idx, rv := range *issue.Related {
-			relatedUri, _ := lq.fsys.PathToUri(rv.Range.Filename)
+			relatedPath := filePath
+			if rv.Range != nil && rv.Range.Filename != "" {
+				relatedPath = rv.Range.Filename
+			}
+			relatedUri, _ := lq.fsys.PathToUri(relatedPath)
 			d.RelatedInformation[idx] = lsp.DiagnosticRelatedInformation{
 				Message: rv.Summary,
 				Location: lsp.Location{
-					URI: relatedUri,
-					Range: lsp.Range{
-						Start: lsp.Position{
-							Line:      uint32(rv.Range.Start.Line),
-							Character: uint32(rv.Range.Start.Column),
-						},
-						End: lsp.Position{
-							Line:      uint32(rv.Range.End.Line),
-							Character: uint32(rv.Range.End.Column),
-						},
-					},
+					URI:   relatedUri,
+					Range: lq.toRange(rv.Range, relatedPath, sources),
 				},
 			}
 		}
@@ -203,3 +265,27 @@ func (lq *lintQueue) toDiagnostic(issue slint.Issue) lsp.Diagnostic {
 
 	return d
 }
+
+// Converts a parser range in the file into the position encoding of the client
+func (lq *lintQueue) toRange(rng *position.SourceRange, filePath string, sources map[string][]byte) lsp.Range {
+	if rng == nil {
+		return lsp.Range{}
+	}
+
+	text, ok := sources[filePath]
+	if !ok {
+		var err error
+		if text, err = lq.fsys.ReadFile(filePath); err != nil {
+			lq.logger.Printf("Failed to read %s to convert issue ranges: %s", filePath, err)
+		}
+		sources[filePath] = text
+	}
+	if text == nil {
+		// Without the content, the columns can only be used as they are
+		return lsp.Range{
+			Start: lsp.Position{Line: uint32(max(0, rng.Start.Line)), Character: uint32(max(0, rng.Start.Column))},
+			End:   lsp.Position{Line: uint32(max(0, rng.End.Line)), Character: uint32(max(0, rng.End.Column))},
+		}
+	}
+	return document.ToRange(text, *rng, lq.encoding)
+}
@@ -2,7 +2,10 @@ package queues
 
 import (
 	"context"
+	"encoding/json"
 	"log"
+
+	lsp "github.com/glennsarti/sentinel-utils/lib/languageserver/internal/protocol"
 )
 
 type LintQueueRequest struct {
@@ -11,8 +14,32 @@ type LintQueueRequest struct {
 	SentinelVersion string
 }
 
+// DiagnosticData is the data of the published diagnostics, which the client sends back
+// when it asks for code actions to fix them
+type DiagnosticData struct {
+	RuleId string `json:"ruleId"`
+	// The file which does not exist, for issues about missing files
+	MissingFile string `json:"missingFile,omitempty"`
+}
+
+// DiagnosticDataOf reads the data of a diagnostic. Diagnostics which were not published
+// by the lint queue do not have any.
+func DiagnosticDataOf(d lsp.Diagnostic) (DiagnosticData, bool) {
+	var data DiagnosticData
+	raw, err := json.Marshal(d.Data)
+	if err != nil || d.Data == nil {
+		return data, false
+	}
+	if err := json.Unmarshal(raw, &data); err != nil || data.RuleId == "" {
+		return data, false
+	}
+	return data, true
+}
+
 type LintQueue interface {
 	Enqueue(req LintQueueRequest) error
+	// Diagnostics are the diagnostics which were last published for the document
+	Diagnostics(uri lsp.DocumentURI) []lsp.Diagnostic
 
 	Start(context.Context) error
 	StartAsync(context.Context) error
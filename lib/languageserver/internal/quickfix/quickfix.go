@@ -0,0 +1,143 @@
+package quickfix
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/glennsarti/sentinel-parser/position"
+	"github.com/glennsarti/sentinel-utils/lib/languageserver/internal/document"
+	"github.com/glennsarti/sentinel-utils/lib/linting"
+
+	sast "github.com/glennsarti/sentinel-parser/sentinel/ast"
+)
+
+// Edit replaces the bytes of a document from Start to End with the new text
+type Edit struct {
+	Start   int
+	End     int
+	NewText string
+}
+
+// RemoveBlock removes the configuration block which has its header at the byte offset,
+// and the blank line after it
+func RemoveBlock(text []byte, header int) (Edit, bool) {
+	at := position.SourcePos{Byte: header}
+	rng := document.BlockRange(text, position.SourceRange{Start: at, End: at})
+	if rng.End.Byte == header {
+		return Edit{}, false
+	}
+	end := nextLineStart(text, rng.End.Byte)
+	if next := nextLineStart(text, end); len(bytes.TrimSpace(text[end:next])) == 0 {
+		end = next
+	}
+	return Edit{Start: lineStart(text, header), End: end}, true
+}
+
+// RenameBlock gives the last label of the block header, between the byte offsets, a name
+// which is not used in the document
+func RenameBlock(text []byte, start, end int) (Edit, string, bool) {
+	if start < 0 || end > len(text) || start >= end {
+		return Edit{}, "", false
+	}
+	header := text[start:end]
+	closing := bytes.LastIndexByte(header, '"')
+	if closing <= 0 {
+		return Edit{}, "", false
+	}
+	opening := bytes.LastIndexByte(header[:closing], '"')
+	if opening < 0 {
+		return Edit{}, "", false
+	}
+
+	name := string(header[opening+1 : closing])
+	newName := name
+	for n := 2; bytes.Contains(text, []byte(strconv.Quote(newName))); n++ {
+		newName = fmt.Sprintf("%s_%d", name, n)
+	}
+	return Edit{Start: start + opening + 1, End: start + closing, NewText: newName}, newName, true
+}
+
+// MoveAboveRules moves the assignment at the byte offset above the first rule of the file,
+// and the comments above it
+func MoveAboveRules(file *sast.File, text []byte, offset int) ([]Edit, bool) {
+	if file == nil {
+		return nil, false
+	}
+	var firstRule, stmt *sast.AssignStatement
+	for _, s := range file.Statements {
+		assign, ok := s.(*sast.AssignStatement)
+		if !ok || assign == nil {
+			continue
+		}
+		if _, isRule := assign.RightExpr.(*sast.RuleExpression); isRule && firstRule == nil {
+			firstRule = assign
+			continue
+		}
+		if firstRule != nil && offset >= assign.NodePos.Start.Byte && offset < assign.NodePos.End.Byte {
+			stmt = assign
+			break
+		}
+	}
+	if stmt == nil {
+		return nil, false
+	}
+
+	start, end := lineStart(text, stmt.NodePos.Start.Byte), nextLineStart(text, stmt.NodePos.End.Byte)
+	moved := string(text[start:end])
+	if !strings.HasSuffix(moved, "\n") {
+		moved += "\n"
+	}
+	insertAt := lineStart(text, firstRule.NodePos.Start.Byte)
+	for insertAt > 0 {
+		prev := lineStart(text, insertAt-1)
+		line := strings.TrimSpace(string(text[prev:insertAt]))
+		if !strings.HasPrefix(line, "#") && !strings.HasPrefix(line, "//") {
+			break
+		}
+		insertAt = prev
+	}
+	return []Edit{
+		{Start: insertAt, End: insertAt, NewText: moved},
+		{Start: start, End: end},
+	}, true
+}
+
+// Suppress adds the rule to the suppression comment above the line at the byte offset, or
+// inserts a new comment with the same indentation as the line
+func Suppress(text []byte, offset int, ruleId string) Edit {
+	start := lineStart(text, offset)
+	if start > 0 {
+		prev := lineStart(text, start-1)
+		line := strings.TrimRight(string(text[prev:start-1]), "\r")
+		if _, ok := linting.SuppressedRules(line); ok {
+			end := prev + len(line)
+			return Edit{Start: end, End: end, NewText: ", " + ruleId}
+		}
+	}
+
+	indent := start
+	for indent < len(text) && (text[indent] == ' ' || text[indent] == '\t') {
+		indent++
+	}
+	return Edit{
+		Start:   start,
+		End:     start,
+		NewText: string(text[start:indent]) + linting.SuppressionComment("#", ruleId) + "\n",
+	}
+}
+
+func lineStart(text []byte, offset int) int {
+	offset = min(max(offset, 0), len(text))
+	return bytes.LastIndexByte(text[:offset], '\n') + 1
+}
+
+// The start of the line after the offset, or the end of the text
+func nextLineStart(text []byte, offset int) int {
+	offset = min(max(offset, 0), len(text))
+	if idx := bytes.IndexByte(text[offset:], '\n'); idx >= 0 {
+		return offset + idx + 1
+	}
+	return len(text)
+}
@@ -0,0 +1,93 @@
+package quickfix
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	sparser "github.com/glennsarti/sentinel-parser/sentinel/parser"
+)
+
+// Applies the edits, which must not overlap, to the text
+func apply(text string, edits ...Edit) string {
+	slices.SortFunc(edits, func(a, b Edit) int { return a.Start - b.Start })
+	for i := len(edits) - 1; i >= 0; i-- {
+		e := edits[i]
+		text = text[:e.Start] + e.NewText + text[e.End:]
+	}
+	return text
+}
+
+func TestRemoveBlock(t *testing.T) {
+	src := "# Overrides\npolicy \"a\" {}\n\npolicy \"b\" {\n  enforcement_level = \"advisory\"\n}\n"
+
+	edit, ok := RemoveBlock([]byte(src), strings.Index(src, "policy \"a\""))
+	if !ok {
+		t.Fatal("expected the block to be removed")
+	}
+	expected := "# Overrides\npolicy \"b\" {\n  enforcement_level = \"advisory\"\n}\n"
+	if actual := apply(src, edit); actual != expected {
+		t.Errorf("expected %q but got %q", expected, actual)
+	}
+
+	if _, ok := RemoveBlock([]byte("policy \"a\""), 0); ok {
+		t.Error("expected a block without braces not to be removed")
+	}
+}
+
+func TestRenameBlock(t *testing.T) {
+	src := "policy \"p\" {}\n\nparam \"p_2\" {}\n\nparam \"p\" {}\n"
+	start := strings.LastIndex(src, "param")
+
+	edit, name, ok := RenameBlock([]byte(src), start, start+len("param \"p\""))
+	if !ok {
+		t.Fatal("expected the block to be renamed")
+	}
+	if name != "p_3" {
+		t.Errorf("expected an unused name but got %q", name)
+	}
+	expected := "policy \"p\" {}\n\nparam \"p_2\" {}\n\nparam \"p_3\" {}\n"
+	if actual := apply(src, edit); actual != expected {
+		t.Errorf("expected %q but got %q", expected, actual)
+	}
+}
+
+func TestMoveAboveRules(t *testing.T) {
+	src := "x = 1\n# The main rule\nmain = rule { x is y }\ny = 2\n"
+	file, _, diags, err := sparser.ParseFile("", "policy.sentinel", []byte(src))
+	if err != nil || diags.HasErrors() {
+		t.Fatalf("failed to parse: %v %v", err, diags)
+	}
+
+	edits, ok := MoveAboveRules(file, []byte(src), strings.Index(src, "y = 2"))
+	if !ok {
+		t.Fatal("expected the assignment to be moved")
+	}
+	expected := "x = 1\ny = 2\n# The main rule\nmain = rule { x is y }\n"
+	if actual := apply(src, edits...); actual != expected {
+		t.Errorf("expected %q but got %q", expected, actual)
+	}
+
+	if _, ok := MoveAboveRules(file, []byte(src), 0); ok {
+		t.Error("expected an assignment before the rules not to be moved")
+	}
+}
+
+func TestSuppress(t *testing.T) {
+	src := "policy \"p\" {\n  source = \"./p.sentinel\"\n}\n"
+	offset := strings.Index(src, "source")
+
+	edit := Suppress([]byte(src), offset, "FileSystem/Error")
+	expected := "policy \"p\" {\n  # sentinel-lint:ignore FileSystem/Error\n  source = \"./p.sentinel\"\n}\n"
+	actual := apply(src, edit)
+	if actual != expected {
+		t.Fatalf("expected %q but got %q", expected, actual)
+	}
+
+	// The rule is added to the comment which is already there
+	edit = Suppress([]byte(actual), strings.Index(actual, "source"), "Lint/Other")
+	expected = "policy \"p\" {\n  # sentinel-lint:ignore FileSystem/Error, Lint/Other\n  source = \"./p.sentinel\"\n}\n"
+	if actual := apply(actual, edit); actual != expected {
+		t.Errorf("expected %q but got %q", expected, actual)
+	}
+}
@@ -10,15 +10,21 @@ type Session struct {
 	Ready              bool
 	RootDir            *string
 	SentinelVersion    *string
+	LintMode           *string
+	PositionEncoding   *lsp.PositionEncodingKind
 }
 
 func NewSession() *Session {
 	ver := features.SentinelVersions[0]
 	rootDir := ""
+	lintMode := lsp.LintOnChange
+	positionEncoding := lsp.UTF16
 	return &Session{
 		ClientCapabilities: &lsp.ClientCapabilities{},
 		Ready:              false,
 		RootDir:            &rootDir,
 		SentinelVersion:    &ver,
+		LintMode:           &lintMode,
+		PositionEncoding:   &positionEncoding,
 	}
 }
@@ -1,9 +1,11 @@
 package concrete
 
 import (
+	"fmt"
 	"log"
 	"sync"
 
+	"github.com/glennsarti/sentinel-utils/lib/languageserver/internal/document"
 	lsp "github.com/glennsarti/sentinel-utils/lib/languageserver/internal/protocol"
 	"github.com/glennsarti/sentinel-utils/lib/languageserver/internal/stores"
 )
@@ -26,11 +28,16 @@ type DocumentStore struct {
 	docs       map[string]*stores.Document
 	normaliser stores.UriNormaliserFunc
 	logger     *log.Logger
+
+	muListeners sync.RWMutex
+	listeners   []stores.DocumentChangeFunc
 }
 
 func (ds *DocumentStore) UpdateDocument(rawUri lsp.DocumentURI, version int, text []byte) error {
 	uri := ds.normaliser(rawUri)
 
+	defer ds.changed(uri)
+
 	ds.muWrite.Lock()
 	defer ds.muWrite.Unlock()
 
@@ -52,9 +59,40 @@ func (ds *DocumentStore) UpdateDocument(rawUri lsp.DocumentURI, version int, tex
 	return nil
 }
 
+func (ds *DocumentStore) ChangeDocument(rawUri lsp.DocumentURI, version int, changes []lsp.TextDocumentContentChangeEvent, enc lsp.PositionEncodingKind) error {
+	uri := ds.normaliser(rawUri)
+
+	defer ds.changed(uri)
+
+	ds.muWrite.Lock()
+	defer ds.muWrite.Unlock()
+
+	doc, err := ds.getDocumentUnsafe(uri)
+	if err != nil {
+		return err
+	}
+
+	// Ranged changes only make sense against the version they were made to, so
+	// the versions must always increase
+	if version <= doc.Version {
+		return fmt.Errorf("%w: version %d is not newer than %d", stores.ErrDocumentWrongVersion, version, doc.Version)
+	}
+
+	text, err := document.ApplyChanges(doc.Text, changes, enc)
+	if err != nil {
+		return err
+	}
+
+	doc.Version = version
+	doc.Text = text
+	return nil
+}
+
 func (ds *DocumentStore) SetDocument(rawUri lsp.DocumentURI, languageId string, version int, text []byte) error {
 	uri := ds.normaliser(rawUri)
 
+	defer ds.changed(uri)
+
 	ds.muWrite.Lock()
 	defer ds.muWrite.Unlock()
 
@@ -77,6 +115,21 @@ func (ds *DocumentStore) SetDocument(rawUri lsp.DocumentURI, languageId string,
 	return nil
 }
 
+func (ds *DocumentStore) RemoveDocument(rawUri lsp.DocumentURI) error {
+	uri := ds.normaliser(rawUri)
+
+	defer ds.changed(uri)
+
+	ds.muWrite.Lock()
+	defer ds.muWrite.Unlock()
+
+	if _, err := ds.getDocumentUnsafe(uri); err != nil {
+		return err
+	}
+	delete(ds.docs, string(uri))
+	return nil
+}
+
 func (ds *DocumentStore) GetDocument(rawUri lsp.DocumentURI) (*stores.Document, error) {
 	uri := ds.normaliser(rawUri)
 
@@ -117,6 +170,23 @@ func (ds *DocumentStore) GetDocumentVersion(rawUri lsp.DocumentURI, version int)
 	}
 }
 
+func (ds *DocumentStore) OnChange(fn stores.DocumentChangeFunc) {
+	ds.muListeners.Lock()
+	defer ds.muListeners.Unlock()
+
+	ds.listeners = append(ds.listeners, fn)
+}
+
+// Deferred before the write lock is taken, so that the listeners can read the store
+func (ds *DocumentStore) changed(uri lsp.DocumentURI) {
+	ds.muListeners.RLock()
+	defer ds.muListeners.RUnlock()
+
+	for _, fn := range ds.listeners {
+		fn(uri)
+	}
+}
+
 // Non-threadsafe operations below
 
 func (ds *DocumentStore) getDocumentUnsafe(uri lsp.DocumentURI) (*stores.Document, error) {
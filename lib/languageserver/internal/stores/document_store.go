@@ -8,12 +8,23 @@ import (
 
 type UriNormaliserFunc func(lsp.DocumentURI) lsp.DocumentURI
 
+// DocumentChangeFunc is called with the normalised URI of a document which changed
+type DocumentChangeFunc func(lsp.DocumentURI)
+
 type DocumentStore interface {
 	UpdateDocument(uri lsp.DocumentURI, version int, text []byte) error
+	// ChangeDocument applies the changes in order. The version must be newer than the document,
+	// and the ranges use the position encoding.
+	ChangeDocument(uri lsp.DocumentURI, version int, changes []lsp.TextDocumentContentChangeEvent, enc lsp.PositionEncodingKind) error
 	SetDocument(uri lsp.DocumentURI, languageId string, version int, text []byte) error
+	// RemoveDocument forgets the document, so the file on disk is used instead
+	RemoveDocument(uri lsp.DocumentURI) error
 
 	GetDocument(uri lsp.DocumentURI) (*Document, error)
 	GetDocumentVersion(uri lsp.DocumentURI, version int) (*Document, error)
+
+	// OnChange calls the function after a document is set, updated, changed or removed
+	OnChange(fn DocumentChangeFunc)
 }
 
 type Document struct {
@@ -0,0 +1,109 @@
+package symbols
+
+import (
+	"github.com/glennsarti/sentinel-parser/position"
+
+	sast "github.com/glennsarti/sentinel-parser/sentinel/ast"
+)
+
+// Reference is an identifier used in a Sentinel file
+type Reference struct {
+	Ident *sast.Ident
+	// The expression before the dot when the identifier is a member, for example the
+	// module in module.name
+	Receiver sast.Expression
+}
+
+// ReferenceAt finds the identifier at the byte offset. The end of an identifier is part of
+// it, so that the position after the last character finds it.
+func ReferenceAt(file *sast.File, offset int) *Reference {
+	if file == nil {
+		return nil
+	}
+
+	var result *Reference
+	var visit sast.VisitFunc
+	visit = func(node sast.Node) sast.VisitFunc {
+		// The walker visits nil after the children of a node
+		if node == nil || result != nil || !contains(node.Position(), offset) {
+			return nil
+		}
+		switch n := node.(type) {
+		case *sast.SelectorExpression:
+			if n.Selector != nil && contains(n.Selector.NodePos, offset) {
+				result = &Reference{Ident: n.Selector, Receiver: n.Value}
+				return nil
+			}
+		case *sast.Ident:
+			result = &Reference{Ident: n}
+			return nil
+		}
+		return visit
+	}
+
+	// The file range does not include trailing comments and blank lines, so start with
+	// the declarations in it
+	for _, imp := range file.Imports {
+		if imp != nil {
+			_ = sast.Walk(visit, imp)
+		}
+	}
+	for _, param := range file.Params {
+		if param != nil {
+			_ = sast.Walk(visit, param)
+		}
+	}
+	for _, stmt := range file.Statements {
+		if stmt != nil {
+			_ = sast.Walk(visit, stmt)
+		}
+	}
+	return result
+}
+
+// References finds every identifier used in the file, in the order they appear. The
+// names where symbols are declared are included.
+func References(file *sast.File) []Reference {
+	result := make([]Reference, 0)
+	if file == nil {
+		return result
+	}
+
+	// The selector of a member is found with its receiver, and is not a reference by itself
+	selectors := make(map[*sast.Ident]bool)
+	var visit sast.VisitFunc
+	visit = func(node sast.Node) sast.VisitFunc {
+		switch n := node.(type) {
+		case *sast.SelectorExpression:
+			if n.Selector != nil {
+				selectors[n.Selector] = true
+				result = append(result, Reference{Ident: n.Selector, Receiver: n.Value})
+			}
+		case *sast.Ident:
+			if n != nil && !selectors[n] {
+				result = append(result, Reference{Ident: n})
+			}
+		}
+		return visit
+	}
+	for _, imp := range file.Imports {
+		if imp != nil {
+			_ = sast.Walk(visit, imp)
+		}
+	}
+	for _, param := range file.Params {
+		if param != nil {
+			_ = sast.Walk(visit, param)
+		}
+	}
+	for _, stmt := range file.Statements {
+		if stmt != nil {
+			_ = sast.Walk(visit, stmt)
+		}
+	}
+	return result
+}
+
+func contains(rng position.SourceRange, offset int) bool {
+	return offset >= rng.Start.Byte && offset <= rng.End.Byte
+}
@@ -0,0 +1,353 @@
+package symbols
+
+import (
+	"math"
+	"strings"
+
+	"github.com/glennsarti/sentinel-parser/position"
+	"github.com/glennsarti/sentinel-parser/sentinel/token"
+	"github.com/glennsarti/sentinel-utils/lib/languageserver/internal/document"
+
+	sast "github.com/glennsarti/sentinel-parser/sentinel/ast"
+)
+
+type Kind int
+
+const (
+	Import Kind = iota
+	Param
+	Function
+	Rule
+	Variable
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Import:
+		return "import"
+	case Param:
+		return "param"
+	case Function:
+		return "function"
+	case Rule:
+		return "rule"
+	default:
+		return "variable"
+	}
+}
+
+// Symbol is something declared in a Sentinel file
+type Symbol struct {
+	Name string
+	Kind Kind
+	// The range of the name where it is declared
+	NameRange position.SourceRange
+	// The range of the whole declaration
+	Range position.SourceRange
+	// The source of the declaration, without the body of functions and rules
+	Declaration string
+	Doc         string
+	// The name of the import, which may be different to the alias it is used as
+	ImportName string
+	// Function parameters, and variables declared in functions or by iterators, can only
+	// be used in part of the file
+	Local bool
+
+	// The byte offsets where the symbol can be used
+	scopeStart, scopeEnd int
+}
+
+// InScope is whether the symbol can be used at the byte offset
+func (s *Symbol) InScope(offset int) bool {
+	return offset >= s.scopeStart && offset <= s.scopeEnd
+}
+
+// Index is the symbols declared in a Sentinel file
+type Index struct {
+	// In the order they are declared
+	symbols []*Symbol
+}
+
+// NewIndex finds the symbols declared in the file. The file may be incomplete, for example
+// when it has syntax errors.
+func NewIndex(file *sast.File, src []byte) *Index {
+	b := &builder{src: src, index: &Index{symbols: make([]*Symbol, 0)}}
+	if file == nil {
+		return b.index
+	}
+
+	fileScope := scope{start: 0, end: math.MaxInt}
+	for _, imp := range file.Imports {
+		b.addImport(imp)
+	}
+	for _, param := range file.Params {
+		if param != nil && param.Name != nil {
+			b.add(fileScope, &Symbol{
+				Name:        param.Name.Name,
+				Kind:        Param,
+				NameRange:   param.Name.NodePos,
+				Range:       param.NodePos,
+				Declaration: b.source(param.NodePos.Start.Byte, param.NodePos.End.Byte),
+				Doc:         b.doc(param.Doc, param.NodePos),
+			})
+		}
+	}
+	for _, stmt := range file.Statements {
+		if stmt != nil {
+			_ = sast.Walk(b.visitor(fileScope), stmt)
+		}
+	}
+	return b.index
+}
+
+// Symbols are every symbol in the file, in the order they are declared
+func (idx *Index) Symbols() []*Symbol {
+	return idx.symbols
+}
+
+// TopLevel are the symbols which can be used anywhere in the file, and by files which
+// import it as a module
+func (idx *Index) TopLevel() []*Symbol {
+	result := make([]*Symbol, 0)
+	for _, s := range idx.symbols {
+		if !s.Local {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+// Lookup finds the declaration of the name which can be used at the byte offset. Names
+// declared in functions hide the names declared outside of them.
+func (idx *Index) Lookup(name string, offset int) *Symbol {
+	var result *Symbol
+	for _, s := range idx.symbols {
+		if s.Name != name || !s.InScope(offset) {
+			continue
+		}
+		if result == nil || s.scopeEnd-s.scopeStart < result.scopeEnd-result.scopeStart {
+			result = s
+		}
+	}
+	return result
+}
+
+// InScope are the symbols which can be used at the byte offset. Hidden names are not included.
+func (idx *Index) InScope(offset int) []*Symbol {
+	result := make([]*Symbol, 0)
+	for _, s := range idx.symbols {
+		if s.InScope(offset) && idx.Lookup(s.Name, offset) == s {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+// DeclaredAt finds the symbol whose name is declared at the byte offset
+func (idx *Index) DeclaredAt(offset int) *Symbol {
+	for _, s := range idx.symbols {
+		if offset >= s.NameRange.Start.Byte && offset <= s.NameRange.End.Byte {
+			return s
+		}
+	}
+	return nil
+}
+
+type scope struct {
+	start, end int
+}
+
+type builder struct {
+	src   []byte
+	index *Index
+}
+
+// Only the first declaration of a name in a scope is a symbol. Later assignments change
+// its value.
+func (b *builder) add(sc scope, s *Symbol) {
+	for _, existing := range b.index.symbols {
+		if existing.Name == s.Name && existing.scopeStart == sc.start && existing.scopeEnd == sc.end {
+			return
+		}
+	}
+	s.scopeStart, s.scopeEnd = sc.start, sc.end
+	s.Local = sc.end != math.MaxInt
+	b.index.symbols = append(b.index.symbols, s)
+}
+
+func (b *builder) addImport(imp *sast.ImportDecl) {
+	if imp == nil || imp.Name == nil {
+		return
+	}
+	// The literal value includes the surrounding quotes
+	importName := strings.Trim(imp.Name.Value, `"`)
+	s := &Symbol{
+		Name:        importName,
+		Kind:        Import,
+		NameRange:   imp.Name.NodePos,
+		Range:       imp.NodePos,
+		Declaration: b.source(imp.NodePos.Start.Byte, imp.NodePos.End.Byte),
+		Doc:         b.doc(imp.Doc, imp.NodePos),
+		ImportName:  importName,
+	}
+	if imp.Alias != nil {
+		s.Name = imp.Alias.Name
+		s.NameRange = imp.Alias.NodePos
+	}
+	b.add(scope{start: 0, end: math.MaxInt}, s)
+}
+
+// Functions have their own scope, but other blocks use the scope they are in
+func (b *builder) visitor(sc scope) sast.VisitFunc {
+	var visit sast.VisitFunc
+	visit = func(node sast.Node) sast.VisitFunc {
+		switch n := node.(type) {
+		case *sast.FuncDecl:
+			if n.Name != nil {
+				b.add(sc, &Symbol{
+					Name:        n.Name.Name,
+					Kind:        Function,
+					NameRange:   n.Name.NodePos,
+					Range:       n.NodePos,
+					Declaration: b.beforeBlock(n.NodePos, n.Body),
+					Doc:         b.doc(n.Doc, n.NodePos),
+				})
+			}
+			b.addFunctionParams(n.Params, n.NodePos, b.beforeBlock(n.NodePos, n.Body))
+			return b.visitor(scope{start: n.NodePos.Start.Byte, end: n.NodePos.End.Byte})
+
+		case *sast.FuncLit:
+			b.addFunctionParams(n.Params, n.NodePos, b.beforeBlock(n.NodePos, n.Body))
+			return b.visitor(scope{start: n.NodePos.Start.Byte, end: n.NodePos.End.Byte})
+
+		case *sast.AssignStatement:
+			b.addAssignment(sc, n)
+
+		case *sast.ForStatement:
+			decl := b.beforeBlock(n.NodePos, n.Block)
+			for _, iter := range []*sast.Ident{n.Iterator1, n.Iterator2} {
+				b.addLocal(iter, n.NodePos, decl)
+			}
+
+		case *sast.QuantExpression:
+			decl := b.source(n.NodePos.Start.Byte, n.LeftBrace.Start.Byte)
+			for _, iter := range []*sast.Ident{n.Name1, n.Name2} {
+				b.addLocal(iter, n.NodePos, decl)
+			}
+		}
+		return visit
+	}
+	return visit
+}
+
+func (b *builder) addAssignment(sc scope, n *sast.AssignStatement) {
+	ident, ok := n.LeftExpr.(*sast.Ident)
+	if !ok || ident == nil || n.AssignOp != token.ASSIGN {
+		return
+	}
+
+	s := &Symbol{
+		Name:      ident.Name,
+		Kind:      Variable,
+		NameRange: ident.NodePos,
+		Range:     n.NodePos,
+		Doc:       document.CommentsAbove(b.src, n.NodePos.Start.Byte),
+	}
+	switch rhs := n.RightExpr.(type) {
+	case *sast.RuleExpression:
+		s.Kind = Rule
+		s.Declaration = b.source(n.NodePos.Start.Byte, rhs.LeftBracePos.Start.Byte)
+		if rhs.Doc != nil {
+			s.Doc = b.doc(rhs.Doc, n.NodePos)
+		}
+	case *sast.FuncLit:
+		s.Kind = Function
+		s.Declaration = b.beforeBlock(n.NodePos, rhs.Body)
+	default:
+		s.Declaration = b.firstLine(n.NodePos)
+	}
+	b.add(sc, s)
+}
+
+func (b *builder) addFunctionParams(params *sast.FieldList, rng position.SourceRange, decl string) {
+	if params == nil {
+		return
+	}
+	for _, field := range params.Fields {
+		b.addLocal(field, rng, decl)
+	}
+}
+
+// Adds a variable which can only be used in the range
+func (b *builder) addLocal(ident *sast.Ident, rng position.SourceRange, decl string) {
+	if ident == nil {
+		return
+	}
+	b.add(scope{start: rng.Start.Byte, end: rng.End.Byte}, &Symbol{
+		Name:        ident.Name,
+		Kind:        Variable,
+		NameRange:   ident.NodePos,
+		Range:       rng,
+		Declaration: decl,
+	})
+}
+
+// The doc comment from the parser, or the comments above the declaration
+func (b *builder) doc(comments *sast.Comments, rng position.SourceRange) string {
+	if text := CommentText(comments); text != "" {
+		return text
+	}
+	return document.CommentsAbove(b.src, rng.Start.Byte)
+}
+
+// CommentText is the text of the comments, without the comment prefixes
+func CommentText(comments *sast.Comments) string {
+	if comments == nil {
+		return ""
+	}
+	lines := make([]string, 0, len(comments.List))
+	for _, c := range comments.List {
+		if c == nil {
+			continue
+		}
+		text := c.Text
+		if c.Prefix == "/*" {
+			text = strings.TrimSuffix(strings.TrimSpace(text), "*/")
+			for _, line := range strings.Split(text, "\n") {
+				lines = append(lines, strings.TrimSpace(line))
+			}
+			continue
+		}
+		lines = append(lines, strings.TrimPrefix(text, " "))
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// The source from the start of the range to the start of the block
+func (b *builder) beforeBlock(rng position.SourceRange, block *sast.BlockStatement) string {
+	if block == nil {
+		return b.firstLine(rng)
+	}
+	return b.source(rng.Start.Byte, block.LeftBrace.Start.Byte)
+}
+
+// The first line of the range, and an ellipsis if there is more
+func (b *builder) firstLine(rng position.SourceRange) string {
+	text := b.rawSource(rng.Start.Byte, rng.End.Byte)
+	if idx := strings.IndexAny(text, "\r\n"); idx >= 0 {
+		return strings.TrimSpace(text[:idx]) + " ..."
+	}
+	return text
+}
+
+// The source between the byte offsets, on one line
+func (b *builder) source(start, end int) string {
+	return strings.Join(strings.Fields(b.rawSource(start, end)), " ")
+}
+
+func (b *builder) rawSource(start, end int) string {
+	if start < 0 || end > len(b.src) || end < start {
+		return ""
+	}
+	return strings.TrimSpace(string(b.src[start:end]))
+}
@@ -0,0 +1,180 @@
+package symbols
+
+import (
+	"strings"
+	"testing"
+
+	sparser "github.com/glennsarti/sentinel-parser/sentinel/parser"
+
+	sast "github.com/glennsarti/sentinel-parser/sentinel/ast"
+)
+
+const testPolicy = `import "strings"
+# The helpers
+import "helpers" as h
+
+// The region to check
+param region default "us"
+
+/* Adds one
+   to the value */
+func add(x) {
+  y = x + 1
+  return y
+}
+
+# The limit
+limit = 10
+y = 5
+
+# Checks the limit
+main = rule when region is "us" {
+  all [1, 2] as i { add(i) < limit } and h.check(y)
+}
+`
+
+func parseTestPolicy(t *testing.T) (*sast.File, []byte) {
+	t.Helper()
+	src := []byte(testPolicy)
+	file, _, diags, err := sparser.ParseFile("", "policy.sentinel", src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diags.HasErrors() {
+		t.Fatal(diags)
+	}
+	return file, src
+}
+
+func TestNewIndex(t *testing.T) {
+	file, src := parseTestPolicy(t)
+	idx := NewIndex(file, src)
+
+	for _, testcase := range []struct {
+		name        string
+		kind        Kind
+		declaration string
+		doc         string
+	}{
+		{name: "strings", kind: Import, declaration: `import "strings"`},
+		{name: "h", kind: Import, declaration: `import "helpers" as h`, doc: "The helpers"},
+		{name: "region", kind: Param, declaration: `param region default "us"`, doc: "The region to check"},
+		{name: "add", kind: Function, declaration: "func add(x)", doc: "Adds one\nto the value"},
+		{name: "limit", kind: Variable, declaration: "limit = 10", doc: "The limit"},
+		{name: "main", kind: Rule, declaration: `main = rule when region is "us"`, doc: "Checks the limit"},
+	} {
+		t.Run(testcase.name, func(t *testing.T) {
+			s := idx.Lookup(testcase.name, len(src))
+			if s == nil {
+				t.Fatalf("expected %s to be declared", testcase.name)
+			}
+			if s.Kind != testcase.kind {
+				t.Errorf("expected a %s but got a %s", testcase.kind, s.Kind)
+			}
+			if s.Declaration != testcase.declaration {
+				t.Errorf("expected the declaration %q but got %q", testcase.declaration, s.Declaration)
+			}
+			if s.Doc != testcase.doc {
+				t.Errorf("expected the doc %q but got %q", testcase.doc, s.Doc)
+			}
+		})
+	}
+
+	if s := idx.Lookup("h", 0); s == nil || s.ImportName != "helpers" {
+		t.Errorf("expected the alias h to import helpers but got %v", s)
+	}
+
+	names := make([]string, 0)
+	for _, s := range idx.TopLevel() {
+		names = append(names, s.Name)
+	}
+	if actual := strings.Join(names, ","); actual != "strings,h,region,add,limit,y,main" {
+		t.Errorf("expected the top level symbols in order but got %s", actual)
+	}
+}
+
+func TestLookupScopes(t *testing.T) {
+	file, src := parseTestPolicy(t)
+	idx := NewIndex(file, src)
+
+	// The y in the function is a different variable to the y outside of it
+	inFunction := strings.Index(testPolicy, "return y") + len("return ")
+	if s := idx.Lookup("y", inFunction); s == nil || !s.Local || s.Declaration != "y = x + 1" {
+		t.Errorf("expected the local y in the function but got %v", s)
+	}
+	inRule := strings.Index(testPolicy, "check(y)") + len("check(")
+	if s := idx.Lookup("y", inRule); s == nil || s.Local || s.Declaration != "y = 5" {
+		t.Errorf("expected the top level y in the rule but got %v", s)
+	}
+
+	// Function parameters and iterators can only be used where they are declared
+	if s := idx.Lookup("x", inFunction); s == nil || s.Declaration != "func add(x)" {
+		t.Errorf("expected the function parameter x but got %v", s)
+	}
+	if s := idx.Lookup("x", inRule); s != nil {
+		t.Errorf("expected x to not be in scope in the rule but got %v", s)
+	}
+	inQuantifier := strings.Index(testPolicy, "add(i)") + len("add(")
+	if s := idx.Lookup("i", inQuantifier); s == nil || s.Declaration != "all [1, 2] as i" {
+		t.Errorf("expected the iterator i but got %v", s)
+	}
+
+	for _, s := range idx.InScope(inFunction) {
+		if s.Name == "y" && !s.Local {
+			t.Error("expected the top level y to be hidden in the function")
+		}
+	}
+}
+
+func TestReferenceAt(t *testing.T) {
+	file, _ := parseTestPolicy(t)
+
+	offset := strings.Index(testPolicy, "h.check") + len("h.ch")
+	ref := ReferenceAt(file, offset)
+	if ref == nil || ref.Ident.Name != "check" {
+		t.Fatalf("expected the check member but got %v", ref)
+	}
+	if receiver, ok := ref.Receiver.(*sast.Ident); !ok || receiver.Name != "h" {
+		t.Errorf("expected the receiver to be h but got %v", ref.Receiver)
+	}
+
+	// The position after the last character is part of the identifier
+	offset = strings.Index(testPolicy, "h.check") + len("h")
+	if ref := ReferenceAt(file, offset); ref == nil || ref.Ident.Name != "h" || ref.Receiver != nil {
+		t.Errorf("expected h but got %v", ref)
+	}
+
+	// Imports without an alias do not have an identifier
+	if ref := ReferenceAt(file, strings.Index(testPolicy, `"strings"`)+1); ref != nil {
+		t.Errorf("expected nothing in an import name but got %v", ref)
+	}
+
+	if ref := ReferenceAt(file, strings.Index(testPolicy, "# The limit")); ref != nil {
+		t.Errorf("expected nothing in a comment but got %v", ref)
+	}
+}
+
+func TestReferences(t *testing.T) {
+	file, src := parseTestPolicy(t)
+	idx := NewIndex(file, src)
+
+	y := idx.Lookup("y", len(testPolicy))
+	uses := make([]int, 0)
+	for _, ref := range References(file) {
+		if ref.Receiver != nil {
+			if ref.Ident.Name != "check" {
+				t.Errorf("expected only check to be a member but got %s", ref.Ident.Name)
+			}
+			continue
+		}
+		if idx.Lookup(ref.Ident.Name, ref.Ident.NodePos.Start.Byte) == y {
+			uses = append(uses, ref.Ident.NodePos.Start.Byte)
+		}
+	}
+
+	// The y in the function is a different variable
+	expected := []int{strings.Index(testPolicy, "y = 5"), strings.Index(testPolicy, "check(y)") + len("check(")}
+	if len(uses) != len(expected) || uses[0] != expected[0] || uses[1] != expected[1] {
+		t.Errorf("expected y to be used at %v but got %v", expected, uses)
+	}
+}
@@ -0,0 +1,244 @@
+package workspace
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/glennsarti/sentinel-parser/filetypes"
+	"github.com/glennsarti/sentinel-parser/position"
+	"github.com/glennsarti/sentinel-utils/lib/filesystem"
+	"github.com/glennsarti/sentinel-utils/lib/internal/helpers"
+	"github.com/glennsarti/sentinel-utils/lib/languageserver/internal/symbols"
+	"github.com/glennsarti/sentinel-utils/lib/parsing"
+
+	sast "github.com/glennsarti/sentinel-parser/sentinel/ast"
+	scast "github.com/glennsarti/sentinel-parser/sentinel_config/ast"
+	cwalker "github.com/glennsarti/sentinel-utils/lib/walkers/sentinel_config"
+)
+
+// Index is the symbols of every file in the policy set, and where they are used. It is
+// built by walking the configuration, and is built again the next time it is used after a
+// document changes.
+type Index struct {
+	fsys         filesystem.FS
+	root         string
+	parseFactory parsing.Factory
+
+	mu              sync.Mutex
+	stale           bool
+	sentinelVersion string
+	files           map[string]*File
+	// The paths of the files, in the order they were walked
+	paths []string
+}
+
+// File is a file in the policy set
+type File struct {
+	Path string
+	Type filetypes.FileType
+	Text []byte
+	// The name of the policy or module in the configuration. Test files use the name of
+	// the policy they test.
+	Name string
+
+	// Policies and modules
+	Sentinel   *sast.File
+	Symbols    *symbols.Index
+	References []symbols.Reference
+
+	// Configuration and test files
+	Config *scast.File
+}
+
+// Location is a range in a file of the policy set
+type Location struct {
+	File  *File
+	Range position.SourceRange
+}
+
+func NewIndex(fsys filesystem.FS, root string, pf parsing.Factory) *Index {
+	return &Index{
+		fsys:         fsys,
+		root:         root,
+		parseFactory: pf,
+		stale:        true,
+		files:        make(map[string]*File),
+		paths:        make([]string, 0),
+	}
+}
+
+// Invalidate makes the index walk the policy set again the next time it is used
+func (idx *Index) Invalidate() {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.stale = true
+}
+
+// Files are the files of the policy set, in the order they were walked
+func (idx *Index) Files(ctx context.Context, sentinelVersion string) ([]*File, error) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if err := idx.update(ctx, sentinelVersion); err != nil {
+		return nil, err
+	}
+	return idx.allFiles(nil), nil
+}
+
+// Walks the policy set, if it has changed since the last walk. Files which have the same
+// content are not indexed again. The lock must be held.
+func (idx *Index) update(ctx context.Context, sentinelVersion string) error {
+	if !idx.stale && idx.sentinelVersion == sentinelVersion {
+		return nil
+	}
+
+	previous := idx.files
+	if idx.sentinelVersion != sentinelVersion {
+		previous = make(map[string]*File)
+	}
+	files := make(map[string]*File)
+	paths := make([]string, 0)
+
+	walker := cwalker.NewSentinelConfigWalker(idx.fsys, idx.root, sentinelVersion, idx.parseFactory, cwalker.WithContinueOnError())
+	err := walker.Walk(ctx, func(fileCtx context.Context, file *filesystem.File, _ *position.SourceRange) (bool, error) {
+		// A file can be used by more than one policy
+		if files[file.Path] != nil {
+			return true, nil
+		}
+		text, err := idx.fsys.ReadFile(file.Path)
+		if err != nil {
+			// Missing policies and modules are reported by the linter
+			return true, nil
+		}
+		f := previous[file.Path]
+		if f == nil || f.Type != file.Type || !bytes.Equal(f.Text, text) {
+			f = idx.newFile(fileCtx, file.Path, file.Type, text, sentinelVersion)
+		}
+		files[file.Path] = f
+		paths = append(paths, file.Path)
+		return true, nil
+	})
+	// Configurations with errors index whatever could be walked
+	if err != nil && ctx.Err() != nil {
+		return err
+	}
+
+	nameFiles(idx.fsys, files, paths)
+	idx.files, idx.paths = files, paths
+	idx.sentinelVersion = sentinelVersion
+	idx.stale = false
+	return nil
+}
+
+// Parses and indexes a file. Files with errors index whatever could be parsed.
+func (idx *Index) newFile(ctx context.Context, filePath string, fileType filetypes.FileType, text []byte, sentinelVersion string) *File {
+	f := &File{Path: filePath, Type: fileType, Text: text}
+	file := &filesystem.File{
+		Path:    filePath,
+		Name:    idx.fsys.BasePath(filePath),
+		Type:    fileType,
+		Content: &text,
+	}
+
+	switch fileType {
+	case filetypes.PolicyFileType, filetypes.ModuleFileType:
+		f.Sentinel, _, _ = idx.parseFactory.ParseSentinelFile(ctx, file, sentinelVersion)
+		f.Symbols = symbols.NewIndex(f.Sentinel, text)
+		f.References = symbols.References(f.Sentinel)
+	default:
+		f.Config, _, _ = idx.parseFactory.ParseSentinelConfigFile(ctx, file, sentinelVersion)
+	}
+	return f
+}
+
+// Names the policies and modules in the same way as the walker finds them, which only
+// uses the primary configuration
+func nameFiles(fsys filesystem.FS, files map[string]*File, paths []string) {
+	for _, p := range paths {
+		f := files[p]
+		f.Name = ""
+		if f.Type == filetypes.ConfigTestFileType {
+			f.Name = fsys.BasePath(fsys.ParentPath(f.Path))
+		}
+	}
+
+	for _, p := range paths {
+		primary := files[p]
+		if primary.Type != filetypes.ConfigPrimaryFileType || primary.Config == nil {
+			continue
+		}
+		name := func(source, name string) {
+			if !strings.HasPrefix(source, "./") {
+				return
+			}
+			if f := files[fsys.PathJoin(fsys.ParentPath(primary.Path), source[2:])]; f != nil && f.Name == "" {
+				f.Name = name
+			}
+		}
+		for _, importName := range helpers.SortedKeys(primary.Config.Imports) {
+			switch actual := primary.Config.Imports[importName].(type) {
+			case *scast.V1ModuleImport:
+				name(actual.Source, importName)
+			case *scast.V2ModuleImport:
+				name(actual.Source, importName)
+			}
+		}
+		for _, policyName := range helpers.SortedKeys(primary.Config.Policies) {
+			if pol := primary.Config.Policies[policyName]; pol != nil {
+				name(pol.Source, policyName)
+			}
+		}
+	}
+}
+
+// The file at the path. Files which are not part of the policy set are indexed by
+// themselves. The lock must be held.
+func (idx *Index) file(ctx context.Context, filePath string) *File {
+	if f := idx.files[filePath]; f != nil {
+		return f
+	}
+	// Only the configuration files of the policy set can be navigated from
+	if !strings.HasSuffix(filePath, ".sentinel") {
+		return nil
+	}
+	text, err := idx.fsys.ReadFile(filePath)
+	if err != nil {
+		return nil
+	}
+	return idx.newFile(ctx, filePath, filetypes.PolicyFileType, text, idx.sentinelVersion)
+}
+
+// The files of the policy set in the order they were walked, and the other file when it
+// is not part of the policy set. The lock must be held.
+func (idx *Index) allFiles(other *File) []*File {
+	result := make([]*File, 0, len(idx.paths)+1)
+	for _, p := range idx.paths {
+		result = append(result, idx.files[p])
+	}
+	if other != nil && idx.files[other.Path] != other {
+		result = append(result, other)
+	}
+	return result
+}
+
+// The primary configuration file. The lock must be held.
+func (idx *Index) primary() *File {
+	for _, p := range idx.paths {
+		if f := idx.files[p]; f.Type == filetypes.ConfigPrimaryFileType {
+			return f
+		}
+	}
+	return nil
+}
+
+// The file with the name in the configuration. The lock must be held.
+func (idx *Index) named(fileType filetypes.FileType, name string) *File {
+	for _, p := range idx.paths {
+		if f := idx.files[p]; f.Type == fileType && f.Name == name {
+			return f
+		}
+	}
+	return nil
+}
@@ -0,0 +1,261 @@
+package workspace
+
+import (
+	"context"
+
+	"github.com/glennsarti/sentinel-parser/filetypes"
+	"github.com/glennsarti/sentinel-parser/position"
+	"github.com/glennsarti/sentinel-utils/lib/internal/helpers"
+	"github.com/glennsarti/sentinel-utils/lib/languageserver/internal/symbols"
+
+	sast "github.com/glennsarti/sentinel-parser/sentinel/ast"
+)
+
+// A symbol declared in a Sentinel file, or a policy in the configuration
+type target struct {
+	file   *File
+	symbol *symbols.Symbol
+	policy string
+	// The range of the name the target was found at
+	at position.SourceRange
+}
+
+// Definition finds where the name at the byte offset of the file is declared. Names in
+// Sentinel files are declared in the same file or in a module. Policy names, and the rules
+// and params of policies, in the configuration are declared in the primary configuration
+// and the policy.
+func (idx *Index) Definition(ctx context.Context, sentinelVersion, filePath string, offset int) ([]Location, error) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if err := idx.update(ctx, sentinelVersion); err != nil {
+		return nil, err
+	}
+	f := idx.file(ctx, filePath)
+	if f == nil {
+		return nil, nil
+	}
+	t := idx.targetAt(f, offset)
+	if t == nil {
+		return nil, nil
+	}
+	if decl := idx.declaration(t); decl != nil {
+		return []Location{*decl}, nil
+	}
+	return nil, nil
+}
+
+// References finds where the name at the byte offset of the file is used, in every file
+// of the policy set. The tests of a policy are references to it.
+func (idx *Index) References(ctx context.Context, sentinelVersion, filePath string, offset int, includeDeclaration bool) ([]Location, error) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if err := idx.update(ctx, sentinelVersion); err != nil {
+		return nil, err
+	}
+	f := idx.file(ctx, filePath)
+	if f == nil {
+		return nil, nil
+	}
+	t := idx.targetAt(f, offset)
+	if t == nil {
+		return nil, nil
+	}
+
+	result := make([]Location, 0)
+	if includeDeclaration {
+		if decl := idx.declaration(t); decl != nil {
+			result = append(result, *decl)
+		}
+	}
+	if t.symbol != nil {
+		return append(result, idx.symbolReferences(t, f)...), nil
+	}
+	return append(result, idx.policyReferences(t.policy)...), nil
+}
+
+func (idx *Index) targetAt(f *File, offset int) *target {
+	found := func(t *target, at position.SourceRange) *target {
+		if t != nil {
+			t.at = at
+		}
+		return t
+	}
+
+	switch {
+	case f.Sentinel != nil:
+		if ref := symbols.ReferenceAt(f.Sentinel, offset); ref != nil {
+			if declFile, s := idx.resolve(f, *ref); s != nil {
+				return found(&target{file: declFile, symbol: s}, ref.Ident.NodePos)
+			}
+			return nil
+		}
+		// Imports without an alias do not have an identifier
+		if s := f.Symbols.DeclaredAt(offset); s != nil {
+			return found(&target{file: f, symbol: s}, s.NameRange)
+		}
+	case f.Config != nil && f.Type == filetypes.ConfigTestFileType:
+		pol := idx.named(filetypes.PolicyFileType, f.Name)
+		if f.Config.Test != nil {
+			for _, rule := range f.Config.Test.Rules {
+				if rule != nil && contains(rule.NameRange, offset) {
+					return found(policySymbol(pol, rule.Name, symbols.Rule), *rule.NameRange)
+				}
+			}
+		}
+		for _, name := range helpers.SortedKeys(f.Config.Params) {
+			if param := f.Config.Params[name]; param != nil && contains(param.NameRange, offset) {
+				return found(policySymbol(pol, name, symbols.Param), *param.NameRange)
+			}
+		}
+	case f.Config != nil:
+		for _, name := range helpers.SortedKeys(f.Config.Policies) {
+			p := f.Config.Policies[name]
+			if p == nil {
+				continue
+			}
+			if contains(p.NameRange, offset) {
+				return found(&target{policy: name}, *p.NameRange)
+			}
+			for _, paramName := range helpers.SortedKeys(p.Params) {
+				if param := p.Params[paramName]; param != nil && contains(param.NameRange, offset) {
+					return found(policySymbol(idx.named(filetypes.PolicyFileType, name), paramName, symbols.Param), *param.NameRange)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Finds the declaration a reference uses, which may be in a module
+func (idx *Index) resolve(f *File, ref symbols.Reference) (*File, *symbols.Symbol) {
+	if ref.Receiver == nil {
+		return f, f.Symbols.Lookup(ref.Ident.Name, ref.Ident.NodePos.Start.Byte)
+	}
+
+	receiver, ok := ref.Receiver.(*sast.Ident)
+	if !ok {
+		return nil, nil
+	}
+	imp := f.Symbols.Lookup(receiver.Name, receiver.NodePos.Start.Byte)
+	if imp == nil || imp.Kind != symbols.Import {
+		return nil, nil
+	}
+	mod := idx.named(filetypes.ModuleFileType, imp.ImportName)
+	if mod == nil {
+		return nil, nil
+	}
+	for _, s := range mod.Symbols.TopLevel() {
+		if s.Name == ref.Ident.Name && s.Kind != symbols.Import {
+			return mod, s
+		}
+	}
+	return nil, nil
+}
+
+func (idx *Index) declaration(t *target) *Location {
+	if t.symbol != nil {
+		return &Location{File: t.file, Range: t.symbol.NameRange}
+	}
+	primary := idx.primary()
+	if primary == nil || primary.Config == nil {
+		return nil
+	}
+	if p := primary.Config.Policies[t.policy]; p != nil && p.NameRange != nil {
+		return &Location{File: primary, Range: *p.NameRange}
+	}
+	return nil
+}
+
+// The uses of a symbol in Sentinel files, and the names of rules and params in the
+// configuration
+func (idx *Index) symbolReferences(t *target, other *File) []Location {
+	result := make([]Location, 0)
+	for _, f := range idx.allFiles(other) {
+		if f.Sentinel == nil {
+			continue
+		}
+		for _, ref := range f.References {
+			declFile, s := idx.resolve(f, ref)
+			if s != t.symbol || (declFile == t.file && ref.Ident.NodePos.Start.Byte == s.NameRange.Start.Byte) {
+				continue
+			}
+			result = append(result, Location{File: f, Range: ref.Ident.NodePos})
+		}
+	}
+
+	if t.file.Type != filetypes.PolicyFileType || t.file.Name == "" || t.symbol.Local {
+		return result
+	}
+	add := func(f *File, rng *position.SourceRange) {
+		if rng != nil {
+			result = append(result, Location{File: f, Range: *rng})
+		}
+	}
+	for _, f := range idx.allFiles(nil) {
+		if f.Config == nil {
+			continue
+		}
+		switch {
+		case f.Type == filetypes.ConfigTestFileType && f.Name == t.file.Name:
+			if t.symbol.Kind == symbols.Rule && f.Config.Test != nil {
+				for _, rule := range f.Config.Test.Rules {
+					if rule != nil && rule.Name == t.symbol.Name {
+						add(f, rule.NameRange)
+					}
+				}
+			}
+			if t.symbol.Kind == symbols.Param && f.Config.Params[t.symbol.Name] != nil {
+				add(f, f.Config.Params[t.symbol.Name].NameRange)
+			}
+		case f.Type != filetypes.ConfigTestFileType && t.symbol.Kind == symbols.Param:
+			// Params in the configuration are given to every policy
+			if f.Config.Params[t.symbol.Name] != nil {
+				add(f, f.Config.Params[t.symbol.Name].NameRange)
+			}
+			if p := f.Config.Policies[t.file.Name]; p != nil && p.Params[t.symbol.Name] != nil {
+				add(f, p.Params[t.symbol.Name].NameRange)
+			}
+		}
+	}
+	return result
+}
+
+// The policy blocks in the override files, and the files in the test directory of a policy
+func (idx *Index) policyReferences(name string) []Location {
+	result := make([]Location, 0)
+	for _, f := range idx.allFiles(nil) {
+		if f.Config == nil {
+			continue
+		}
+		switch f.Type {
+		case filetypes.ConfigOverrideFileType:
+			if p := f.Config.Policies[name]; p != nil && p.NameRange != nil {
+				result = append(result, Location{File: f, Range: *p.NameRange})
+			}
+		case filetypes.ConfigTestFileType:
+			if f.Name == name {
+				result = append(result, Location{File: f, Range: position.SourceRange{Filename: f.Path}})
+			}
+		}
+	}
+	return result
+}
+
+// The top level symbol of a policy
+func policySymbol(pol *File, name string, kind symbols.Kind) *target {
+	if pol == nil || pol.Symbols == nil {
+		return nil
+	}
+	for _, s := range pol.Symbols.TopLevel() {
+		if s.Name == name && s.Kind == kind {
+			return &target{file: pol, symbol: s}
+		}
+	}
+	return nil
+}
+
+func contains(rng *position.SourceRange, offset int) bool {
+	return rng != nil && offset >= rng.Start.Byte && offset <= rng.End.Byte
+}
@@ -0,0 +1,118 @@
+package workspace
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/txtar"
+
+	"github.com/glennsarti/sentinel-utils/lib/internal/txtar_fs"
+	parsing "github.com/glennsarti/sentinel-utils/lib/parsing/default"
+)
+
+const testArchive = `
+-- sentinel.hcl --
+import "module" "helpers" {
+  source = "./helpers.sentinel"
+}
+
+policy "policy" {
+  source = "./policy.sentinel"
+}
+-- override.hcl --
+policy "policy" {
+  enforcement_level = "advisory"
+}
+-- policy.sentinel --
+import "helpers"
+
+main = rule { helpers.check(1) }
+-- helpers.sentinel --
+check = func(v) {
+  return v > 0
+}
+-- test/policy/pass.hcl --
+test {
+  rules = {
+    main = true
+  }
+}
+`
+
+func newTestIndex() *Index {
+	fsys := txtar_fs.NewTxtarFileSystem(txtar.Parse([]byte(testArchive)))
+	return NewIndex(fsys, "/", parsing.NewDefaultParsingFactory(fsys))
+}
+
+// Describes the locations as path:offset
+func describe(locations []Location) string {
+	result := make([]string, 0, len(locations))
+	for _, loc := range locations {
+		result = append(result, loc.File.Path+":"+strings.TrimSpace(string(loc.File.Text[loc.Range.Start.Byte:loc.Range.End.Byte])))
+	}
+	return strings.Join(result, ", ")
+}
+
+func offsetOf(t *testing.T, idx *Index, filePath, text string) int {
+	t.Helper()
+	content, err := idx.fsys.ReadFile(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	offset := strings.Index(string(content), text)
+	if offset < 0 {
+		t.Fatalf("%q is not in %s", text, filePath)
+	}
+	return offset
+}
+
+func TestDefinition(t *testing.T) {
+	idx := newTestIndex()
+	for _, testcase := range []struct {
+		name     string
+		path     string
+		text     string
+		expected string
+	}{
+		{name: "module member", path: "/policy.sentinel", text: "check(1)", expected: "/helpers.sentinel:check"},
+		{name: "import", path: "/policy.sentinel", text: "helpers.check", expected: "/policy.sentinel:\"helpers\""},
+		{name: "override policy", path: "/override.hcl", text: "policy\"", expected: "/sentinel.hcl:\"policy\""},
+		{name: "test rule", path: "/test/policy/pass.hcl", text: "main", expected: "/policy.sentinel:main"},
+		{name: "nothing", path: "/policy.sentinel", text: "rule", expected: ""},
+	} {
+		t.Run(testcase.name, func(t *testing.T) {
+			locations, err := idx.Definition(context.Background(), "", testcase.path, offsetOf(t, idx, testcase.path, testcase.text))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if actual := describe(locations); actual != testcase.expected {
+				t.Errorf("expected %q but got %q", testcase.expected, actual)
+			}
+		})
+	}
+}
+
+func TestReferences(t *testing.T) {
+	idx := newTestIndex()
+	for _, testcase := range []struct {
+		name     string
+		path     string
+		text     string
+		expected string
+	}{
+		{name: "module function", path: "/helpers.sentinel", text: "check", expected: "/helpers.sentinel:check, /policy.sentinel:check"},
+		{name: "rule", path: "/policy.sentinel", text: "main", expected: "/policy.sentinel:main, /test/policy/pass.hcl:main"},
+		{name: "policy", path: "/sentinel.hcl", text: "\"policy\"", expected: "/sentinel.hcl:\"policy\", /override.hcl:\"policy\", /test/policy/pass.hcl:"},
+	} {
+		t.Run(testcase.name, func(t *testing.T) {
+			locations, err := idx.References(context.Background(), "", testcase.path, offsetOf(t, idx, testcase.path, testcase.text), true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if actual := describe(locations); actual != testcase.expected {
+				t.Errorf("expected %q but got %q", testcase.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package workspace
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/glennsarti/sentinel-parser/filetypes"
+	"github.com/glennsarti/sentinel-parser/position"
+	"github.com/glennsarti/sentinel-parser/sentinel/token"
+	"github.com/glennsarti/sentinel-utils/lib/languageserver/internal/symbols"
+)
+
+// Renaming is what changes when a symbol or policy is renamed
+type Renaming struct {
+	// The name being renamed, and where it was found
+	Name string
+	At   position.SourceRange
+	// The names to replace, including the declaration
+	Locations []Location
+	// The test directories of a policy, which are named after it
+	Directories []string
+}
+
+// PrepareRename finds what renaming the name at the byte offset of the file changes.
+// Imports, and names which are not declared in the policy set, can not be renamed and
+// return nil.
+func (idx *Index) PrepareRename(ctx context.Context, sentinelVersion, filePath string, offset int) (*Renaming, error) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	r, _, err := idx.renaming(ctx, sentinelVersion, filePath, offset)
+	return r, err
+}
+
+// Rename checks that the name at the byte offset of the file can be renamed to the new
+// name, and finds what renaming it changes
+func (idx *Index) Rename(ctx context.Context, sentinelVersion, filePath string, offset int, newName string) (*Renaming, error) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	r, t, err := idx.renaming(ctx, sentinelVersion, filePath, offset)
+	if r == nil || err != nil || newName == r.Name {
+		return r, err
+	}
+
+	if t.symbol != nil {
+		if !isIdentifier(newName) || token.LookupIdent(sentinelVersion, newName) != token.IDENT {
+			return nil, fmt.Errorf("%q is not a valid name", newName)
+		}
+		if existing := t.file.Symbols.Lookup(newName, t.symbol.NameRange.Start.Byte); existing != nil {
+			return nil, fmt.Errorf("%q is already declared", newName)
+		}
+		return r, nil
+	}
+
+	if newName == "" || newName == "." || newName == ".." || strings.ContainsAny(newName, "/\\\"") {
+		return nil, fmt.Errorf("%q is not a valid policy name", newName)
+	}
+	if primary := idx.primary(); primary != nil && primary.Config != nil && primary.Config.Policies[newName] != nil {
+		return nil, fmt.Errorf("the policy %q already exists", newName)
+	}
+	return r, nil
+}
+
+// The lock must be held
+func (idx *Index) renaming(ctx context.Context, sentinelVersion, filePath string, offset int) (*Renaming, *target, error) {
+	if err := idx.update(ctx, sentinelVersion); err != nil {
+		return nil, nil, err
+	}
+	f := idx.file(ctx, filePath)
+	if f == nil {
+		return nil, nil, nil
+	}
+	t := idx.targetAt(f, offset)
+	if t == nil || (t.symbol != nil && t.symbol.Kind == symbols.Import) {
+		return nil, nil, nil
+	}
+
+	r := &Renaming{At: t.at, Locations: make([]Location, 0), Directories: make([]string, 0)}
+	if decl := idx.declaration(t); decl != nil {
+		r.Locations = append(r.Locations, *decl)
+	}
+	if t.symbol != nil {
+		// The params in the configuration are also given to the other policies which declare them
+		if others := idx.sharedParam(t); len(others) > 0 {
+			return nil, nil, fmt.Errorf("the param %q in the configuration is also used by the policies %s", t.symbol.Name, strings.Join(others, ", "))
+		}
+		r.Name = t.symbol.Name
+		r.Locations = append(r.Locations, idx.symbolReferences(t, f)...)
+		return r, t, nil
+	}
+
+	r.Name = t.policy
+	for _, loc := range idx.policyReferences(t.policy) {
+		if loc.File.Type != filetypes.ConfigTestFileType {
+			r.Locations = append(r.Locations, loc)
+			continue
+		}
+		// The tests refer to the policy by the name of their directory
+		dir := idx.fsys.ParentPath(loc.File.Path)
+		if len(r.Directories) == 0 || r.Directories[len(r.Directories)-1] != dir {
+			r.Directories = append(r.Directories, dir)
+		}
+	}
+	return r, t, nil
+}
+
+// The names of the other policies which declare a param that is set by a param block in
+// the configuration, sorted by name. The lock must be held.
+func (idx *Index) sharedParam(t *target) []string {
+	if t.symbol.Kind != symbols.Param || t.symbol.Local || t.file.Type != filetypes.PolicyFileType {
+		return nil
+	}
+	configured := false
+	others := make([]string, 0)
+	for _, f := range idx.allFiles(nil) {
+		switch {
+		case f.Config != nil && f.Type != filetypes.ConfigTestFileType:
+			configured = configured || f.Config.Params[t.symbol.Name] != nil
+		case f.Type == filetypes.PolicyFileType && f != t.file && f.Symbols != nil:
+			for _, s := range f.Symbols.TopLevel() {
+				if s.Kind == symbols.Param && s.Name == t.symbol.Name {
+					others = append(others, f.Name)
+					break
+				}
+			}
+		}
+	}
+	if !configured {
+		return nil
+	}
+	slices.Sort(others)
+	return others
+}
+
+// ReplacementText is the text which replaces the name at the location. Names written as
+// strings in the configuration stay as strings.
+func ReplacementText(loc Location, newName string) string {
+	start := loc.Range.Start.Byte
+	if start >= 0 && start < len(loc.File.Text) && loc.File.Text[start] == '"' {
+		return fmt.Sprintf("%q", newName)
+	}
+	return newName
+}
+
+func isIdentifier(name string) bool {
+	for i, c := range name {
+		if c != '_' && !(c >= 'a' && c <= 'z') && !(c >= 'A' && c <= 'Z') && !(i > 0 && c >= '0' && c <= '9') {
+			return false
+		}
+	}
+	return name != ""
+}
@@ -0,0 +1,99 @@
+package workspace
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/txtar"
+
+	"github.com/glennsarti/sentinel-utils/lib/internal/txtar_fs"
+	parsing "github.com/glennsarti/sentinel-utils/lib/parsing/default"
+)
+
+const renameArchive = `
+-- sentinel.hcl --
+param "limit" {
+  value = 5
+}
+
+policy "policy" {
+  source = "./policy.sentinel"
+  params = {
+    limit = 10
+  }
+}
+
+policy "other" {
+  source = "./other.sentinel"
+}
+-- policy.sentinel --
+param limit
+
+main = rule { limit > 1 }
+-- other.sentinel --
+main = rule { true }
+-- test/policy/pass.hcl --
+param "limit" {
+  value = 2
+}
+
+test {
+  rules = {
+    main = true
+  }
+}
+`
+
+func TestRename(t *testing.T) {
+	fsys := txtar_fs.NewTxtarFileSystem(txtar.Parse([]byte(renameArchive)))
+	idx := NewIndex(fsys, "/", parsing.NewDefaultParsingFactory(fsys))
+	ctx := context.Background()
+
+	// The name of the param at the start of policy.sentinel
+	offset := len("param ")
+	r, err := idx.Rename(ctx, "", "/policy.sentinel", offset, "maximum")
+	if err != nil || r == nil {
+		t.Fatalf("expected the param to be renamed but got %v", err)
+	}
+	replaced := make([]string, 0)
+	for _, loc := range r.Locations {
+		replaced = append(replaced, loc.File.Path+":"+ReplacementText(loc, "maximum"))
+	}
+	expected := `/policy.sentinel:maximum /policy.sentinel:maximum /sentinel.hcl:"maximum" /sentinel.hcl:maximum /test/policy/pass.hcl:"maximum"`
+	if actual := strings.Join(replaced, " "); actual != expected {
+		t.Errorf("expected %s but got %s", expected, actual)
+	}
+
+	if _, err := idx.Rename(ctx, "", "/policy.sentinel", offset, "main"); err == nil {
+		t.Error("expected a name which is already declared to be an error")
+	}
+
+	offset = strings.Index(renameArchive, `"policy" {`) - strings.Index(renameArchive, "param")
+	r, err = idx.Rename(ctx, "", "/sentinel.hcl", offset, "renamed")
+	if err != nil || r == nil {
+		t.Fatalf("expected the policy to be renamed but got %v", err)
+	}
+	if len(r.Directories) != 1 || r.Directories[0] != "/test/policy" {
+		t.Errorf("expected the test directory to be renamed but got %v", r.Directories)
+	}
+	if _, err := idx.Rename(ctx, "", "/sentinel.hcl", offset, "other"); err == nil {
+		t.Error("expected a policy which already exists to be an error")
+	}
+}
+
+func TestRenameSharedParam(t *testing.T) {
+	archive := strings.Replace(renameArchive, "-- other.sentinel --\n", "-- other.sentinel --\nparam limit\n\n", 1)
+	fsys := txtar_fs.NewTxtarFileSystem(txtar.Parse([]byte(archive)))
+	idx := NewIndex(fsys, "/", parsing.NewDefaultParsingFactory(fsys))
+	ctx := context.Background()
+
+	// The param block in the configuration is also given to other.sentinel
+	offset := len("param ")
+	if _, err := idx.Rename(ctx, "", "/policy.sentinel", offset, "maximum"); err == nil {
+		t.Error("expected a param which is shared with another policy to be an error")
+	}
+	if r, err := idx.PrepareRename(ctx, "", "/other.sentinel", offset); err == nil || r != nil {
+		t.Errorf("expected a param which is shared with another policy to be an error but got %v", r)
+	}
+}
@@ -0,0 +1,181 @@
+package lintcache
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+
+	slint "github.com/glennsarti/sentinel-lint/lint"
+	"github.com/glennsarti/sentinel-utils/lib/linting"
+)
+
+var _ linting.ResultCache = &DiskCache{}
+
+// The format of the cache entries. Changing it invalidates every entry.
+const entryFormatVersion = 1
+
+// Entries are kept in a subdirectory so that cleaning never removes anything else in
+// the cache directory
+const resultsDirName = "lint-results"
+
+const entryExtension = ".json"
+
+type cacheEntry struct {
+	Version int          `json:"version"`
+	Issues  slint.Issues `json:"issues"`
+}
+
+// Stats describes what is in the cache
+type Stats struct {
+	Dir     string
+	Entries int
+	// The total size of the entries in bytes
+	Size int64
+}
+
+// DiskCache stores lint results as files in a directory, so that they can be used by later runs
+type DiskCache struct {
+	dir    string
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+// NewDiskCache opens the cache in the directory, creating it if needed
+func NewDiskCache(dir string) (*DiskCache, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Join(absDir, resultsDirName), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create the cache directory: %w", err)
+	}
+	return &DiskCache{dir: absDir}, nil
+}
+
+func (dc *DiskCache) Get(key string) (slint.Issues, bool) {
+	content, err := os.ReadFile(dc.entryPath(key))
+	if err != nil {
+		dc.misses.Add(1)
+		return nil, false
+	}
+
+	entry := cacheEntry{}
+	if err := json.Unmarshal(content, &entry); err != nil || entry.Version != entryFormatVersion || entry.Issues == nil {
+		dc.misses.Add(1)
+		return nil, false
+	}
+
+	dc.hits.Add(1)
+	return entry.Issues, true
+}
+
+func (dc *DiskCache) Put(key string, issues slint.Issues) error {
+	content, err := json.Marshal(cacheEntry{
+		Version: entryFormatVersion,
+		Issues:  issues,
+	})
+	if err != nil {
+		return err
+	}
+
+	entryPath := dc.entryPath(key)
+	if err := os.MkdirAll(filepath.Dir(entryPath), 0o755); err != nil {
+		return err
+	}
+
+	// Write then rename, so that other runs never read a partial entry
+	f, err := os.CreateTemp(filepath.Dir(entryPath), "tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(content); err != nil {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		_ = os.Remove(f.Name())
+		return err
+	}
+	if err := os.Rename(f.Name(), entryPath); err != nil {
+		_ = os.Remove(f.Name())
+		return err
+	}
+	return nil
+}
+
+// Usage returns how many results were, and were not, found in the cache since it was opened
+func (dc *DiskCache) Usage() (hits, misses int64) {
+	return dc.hits.Load(), dc.misses.Load()
+}
+
+// Stats counts the entries in the cache
+func (dc *DiskCache) Stats() (Stats, error) {
+	stats := Stats{
+		Dir: dc.dir,
+	}
+
+	err := dc.walkEntries(func(_ string, info fs.FileInfo) error {
+		stats.Entries++
+		stats.Size += info.Size()
+		return nil
+	})
+	return stats, err
+}
+
+// Clean removes every entry from the cache, and returns how many were removed
+func (dc *DiskCache) Clean() (int, error) {
+	removed := 0
+	err := dc.walkEntries(func(entryPath string, _ fs.FileInfo) error {
+		if err := os.Remove(entryPath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		removed++
+		return nil
+	})
+	if err != nil {
+		return removed, err
+	}
+
+	// Remove the empty shard directories too
+	shards, err := os.ReadDir(filepath.Join(dc.dir, resultsDirName))
+	if err != nil {
+		return removed, err
+	}
+	for _, shard := range shards {
+		if shard.IsDir() {
+			_ = os.Remove(filepath.Join(dc.dir, resultsDirName, shard.Name()))
+		}
+	}
+	return removed, nil
+}
+
+// Entries are sharded by the start of their key so that no directory gets too large
+func (dc *DiskCache) entryPath(key string) string {
+	shard := key
+	if len(shard) > 2 {
+		shard = shard[:2]
+	}
+	return filepath.Join(dc.dir, resultsDirName, shard, key+entryExtension)
+}
+
+func (dc *DiskCache) walkEntries(fn func(entryPath string, info fs.FileInfo) error) error {
+	return filepath.WalkDir(filepath.Join(dc.dir, resultsDirName), func(entryPath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(d.Name(), entryExtension) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		return fn(entryPath, info)
+	})
+}
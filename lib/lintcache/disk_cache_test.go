@@ -0,0 +1,77 @@
+package lintcache
+
+import (
+	"testing"
+
+	slint "github.com/glennsarti/sentinel-lint/lint"
+	"github.com/glennsarti/sentinel-parser/position"
+)
+
+func TestDiskCache(t *testing.T) {
+	dir := t.TempDir()
+	subject, err := NewDiskCache(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := subject.Get("abcdef"); ok {
+		t.Fatal("expected an empty cache")
+	}
+
+	issues := slint.Issues{
+		&slint.Issue{
+			RuleId:   "Test/Rule",
+			Summary:  "Summary",
+			Severity: slint.Warning,
+			Range: &position.SourceRange{
+				Filename: "/policy.sentinel",
+				Start:    position.SourcePos{Line: 1, Column: 2},
+				End:      position.SourcePos{Line: 1, Column: 5},
+			},
+		},
+	}
+	if err := subject.Put("abcdef", issues); err != nil {
+		t.Fatal(err)
+	}
+	if err := subject.Put("012345", slint.Issues{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A new cache on the same directory sees the same entries
+	reopened, err := NewDiskCache(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual, ok := reopened.Get("abcdef")
+	if !ok {
+		t.Fatal("expected the entry to be cached")
+	}
+	if len(actual) != 1 || actual[0].RuleId != "Test/Rule" || actual[0].Range.Start.Column != 2 {
+		t.Errorf("unexpected cached issues %+v", actual)
+	}
+	if actual, ok := reopened.Get("012345"); !ok || len(actual) != 0 {
+		t.Errorf("expected an empty list of issues but got %v", actual)
+	}
+	if hits, misses := reopened.Usage(); hits != 2 || misses != 0 {
+		t.Errorf("expected 2 hits and 0 misses but got %d and %d", hits, misses)
+	}
+
+	stats, err := reopened.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Entries != 2 || stats.Size == 0 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+
+	removed, err := reopened.Clean()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 2 {
+		t.Errorf("expected 2 entries to be removed but got %d", removed)
+	}
+	if _, ok := reopened.Get("abcdef"); ok {
+		t.Error("expected the entry to be removed")
+	}
+}
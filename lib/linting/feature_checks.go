@@ -0,0 +1,104 @@
+package linting
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	slint "github.com/glennsarti/sentinel-lint/lint"
+	"github.com/glennsarti/sentinel-parser/features"
+	"github.com/glennsarti/sentinel-utils/lib/filesystem"
+	"github.com/glennsarti/sentinel-utils/lib/internal/helpers"
+
+	scast "github.com/glennsarti/sentinel-parser/sentinel_config/ast"
+	scparser "github.com/glennsarti/sentinel-parser/sentinel_config/parser"
+	cwalker "github.com/glennsarti/sentinel-utils/lib/walkers/sentinel_config"
+)
+
+// The rules of the issues about the features of the Sentinel version
+const (
+	deprecatedFeatureRuleID  = "Sentinel/DeprecatedFeature"
+	unsupportedFeatureRuleID = "Sentinel/UnsupportedFeature"
+)
+
+// configFeatureIssues finds configuration which is deprecated in, or not valid for, the
+// Sentinel version. Features which the parser rejects already have syntax errors.
+func configFeatureIssues(cfg *scast.File, sentinelVersion string) slint.Issues {
+	issues := make(slint.Issues, 0)
+	if cfg == nil {
+		return issues
+	}
+
+	for _, name := range helpers.SortedKeys(cfg.Imports) {
+		imp := cfg.Imports[name]
+		if imp == nil {
+			continue
+		}
+
+		switch imp.(type) {
+		case *scast.V1ModuleImport, *scast.V1PluginImport:
+			if features.SupportedVersion(sentinelVersion, features.V2ImportBlockMinimumVersion) {
+				issues = append(issues, &slint.Issue{
+					Severity: slint.Warning,
+					RuleId:   deprecatedFeatureRuleID,
+					Summary:  "Deprecated import syntax",
+					Detail: fmt.Sprintf("The %s block for %q uses the version 1 import syntax, which is deprecated from Sentinel %s. "+
+						"Use an import \"%s\" %q block instead, for example with the upgrade command.",
+						imp.BlockType(), name, features.V2ImportBlockMinimumVersion, v2ImportKind(imp), name),
+					Range: imp.Range(),
+				})
+			}
+		}
+
+		if scparser.IsStdLibName(name) && features.SupportedVersion(sentinelVersion, features.BadStdlibImportNameMinimumVersion) {
+			issues = append(issues, &slint.Issue{
+				Severity: slint.Error,
+				RuleId:   unsupportedFeatureRuleID,
+				Summary:  "Import has the name of a standard import",
+				Detail: fmt.Sprintf("The import %q has the same name as a standard import, which is not allowed from Sentinel %s. Rename the import.",
+					name, features.BadStdlibImportNameMinimumVersion),
+				Range: imp.BlockNameRange(),
+			})
+		}
+	}
+
+	return issues
+}
+
+func v2ImportKind(imp scast.Import) string {
+	if _, ok := imp.(*scast.V1PluginImport); ok {
+		return scast.V2ImportKindPlugin
+	}
+	return scast.V2ImportKindModule
+}
+
+// Override files are not visited on Sentinel versions which do not support them, so they
+// are reported here instead
+func unsupportedOverrideIssues(fsys filesystem.FS, primaryPath, sentinelVersion string) map[string]*slint.Issue {
+	result := make(map[string]*slint.Issue, 0)
+	if features.SupportedVersion(sentinelVersion, features.ConfigurationOverrideMinimumVersion) {
+		return result
+	}
+
+	dir := fsys.ParentPath(primaryPath)
+	entries, err := fsys.ReadDir(dir)
+	if err != nil {
+		return result
+	}
+	ext := path.Ext(primaryPath)
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ext) || !cwalker.IsOverrideFileName(entry.Name()) {
+			continue
+		}
+		filePath := fsys.PathJoin(dir, entry.Name())
+		result[filePath] = &slint.Issue{
+			Severity: slint.Warning,
+			RuleId:   unsupportedFeatureRuleID,
+			Summary:  "Override file is not used",
+			Detail: fmt.Sprintf("Override files are not supported in Sentinel %s (Requires %s+), so the file is ignored.",
+				sentinelVersion, features.ConfigurationOverrideMinimumVersion),
+			Range: startOfFileRange(filePath),
+		}
+	}
+	return result
+}
@@ -0,0 +1,110 @@
+package linting
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	slint "github.com/glennsarti/sentinel-lint/lint"
+)
+
+type lintJobFunc func(ctx context.Context, yield LintIssueYielder) (bool, error)
+
+type yieldedIssues struct {
+	lintFile slint.File
+	issues   slint.Issues
+}
+
+type lintJobResult struct {
+	yielded []yieldedIssues
+	cont    bool
+	err     error
+}
+
+// lintScheduler runs lint jobs concurrently. Results are kept in the order the jobs were
+// scheduled, so the outcome does not depend on which job finishes first.
+type lintScheduler struct {
+	slots   chan struct{}
+	wg      sync.WaitGroup
+	results []*lintJobResult
+}
+
+func newLintScheduler(jobs int) *lintScheduler {
+	return &lintScheduler{
+		slots:   make(chan struct{}, jobs),
+		results: make([]*lintJobResult, 0),
+	}
+}
+
+// schedule waits for a free slot and then runs the job in the background. The job has as
+// long as the file context allows, but waiting for a slot does not count towards it.
+func (ls *lintScheduler) schedule(walkCtx, fileCtx context.Context, job lintJobFunc) (bool, error) {
+	var timeout time.Duration
+	deadline, hasTimeout := fileCtx.Deadline()
+	if hasTimeout {
+		timeout = time.Until(deadline)
+	}
+
+	select {
+	case ls.slots <- struct{}{}:
+	case <-walkCtx.Done():
+		return false, walkCtx.Err()
+	}
+
+	result := &lintJobResult{}
+	ls.results = append(ls.results, result)
+
+	ls.wg.Add(1)
+	go func() {
+		defer ls.wg.Done()
+		defer func() { <-ls.slots }()
+
+		ctx := walkCtx
+		if hasTimeout {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(walkCtx, timeout)
+			defer cancel()
+		}
+
+		result.cont, result.err = job(ctx, func(lintFile slint.File, issues slint.Issues) {
+			result.yielded = append(result.yielded, yieldedIssues{lintFile: lintFile, issues: issues})
+		})
+	}()
+
+	return true, nil
+}
+
+// yield records issues found outside of a job, in between the jobs scheduled before and after it
+func (ls *lintScheduler) yield(lintFile slint.File, issues slint.Issues) {
+	ls.results = append(ls.results, &lintJobResult{
+		yielded: []yieldedIssues{{lintFile: lintFile, issues: issues}},
+		cont:    true,
+	})
+}
+
+// finish waits for all of the jobs, and then yields their issues sorted by file path. As
+// with linting one file at a time, nothing after a failed job is yielded.
+func (ls *lintScheduler) finish(yielder LintIssueYielder) error {
+	ls.wg.Wait()
+
+	var err error
+	yielded := make([]yieldedIssues, 0)
+	for _, result := range ls.results {
+		yielded = append(yielded, result.yielded...)
+		if result.err != nil || !result.cont {
+			err = result.err
+			break
+		}
+	}
+
+	slices.SortStableFunc(yielded, func(a, b yieldedIssues) int {
+		return strings.Compare(a.lintFile.Path(), b.lintFile.Path())
+	})
+	for _, item := range yielded {
+		yielder(item.lintFile, item.issues)
+	}
+
+	return err
+}
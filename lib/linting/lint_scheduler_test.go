@@ -0,0 +1,83 @@
+package linting
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	slint "github.com/glennsarti/sentinel-lint/lint"
+)
+
+// Schedules jobs which yield an issue for the path after the delay
+func scheduleJobs(t *testing.T, ls *lintScheduler, paths []string, delays []time.Duration) {
+	for idx, p := range paths {
+		summary := fmt.Sprintf("job %d", idx)
+		delay := delays[idx%len(delays)]
+		if _, err := ls.schedule(context.Background(), context.Background(), func(_ context.Context, yield LintIssueYielder) (bool, error) {
+			time.Sleep(delay)
+			yield(newUnknownFile(p), slint.Issues{{Summary: summary}})
+			return true, nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func yieldedOrder(ls *lintScheduler) ([]string, error) {
+	order := make([]string, 0)
+	err := ls.finish(func(lintFile slint.File, issues slint.Issues) {
+		for _, issue := range issues {
+			order = append(order, lintFile.Path()+" "+issue.Summary)
+		}
+	})
+	return order, err
+}
+
+func TestLintSchedulerOrder(t *testing.T) {
+	// The jobs which are scheduled first take the longest
+	ls := newLintScheduler(4)
+	scheduleJobs(t, ls,
+		[]string{"/d.sentinel", "/b.sentinel", "/c.sentinel", "/a.sentinel", "/b.sentinel"},
+		[]time.Duration{20 * time.Millisecond, 10 * time.Millisecond, 5 * time.Millisecond, 0},
+	)
+	ls.yield(newUnknownFile("/b.sentinel"), slint.Issues{{Summary: "outside"}})
+
+	order, err := yieldedOrder(ls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"/a.sentinel job 3",
+		"/b.sentinel job 1",
+		"/b.sentinel job 4",
+		"/b.sentinel outside",
+		"/c.sentinel job 2",
+		"/d.sentinel job 0",
+	}
+	if !slices.Equal(order, expected) {
+		t.Errorf("expected %v but got %v", expected, order)
+	}
+}
+
+func TestLintSchedulerFailedJob(t *testing.T) {
+	ls := newLintScheduler(2)
+	scheduleJobs(t, ls, []string{"/b.sentinel"}, []time.Duration{10 * time.Millisecond})
+	failure := errors.New("failed")
+	if _, err := ls.schedule(context.Background(), context.Background(), func(context.Context, LintIssueYielder) (bool, error) {
+		return false, failure
+	}); err != nil {
+		t.Fatal(err)
+	}
+	scheduleJobs(t, ls, []string{"/a.sentinel"}, []time.Duration{0})
+
+	order, err := yieldedOrder(ls)
+	if !errors.Is(err, failure) {
+		t.Errorf("expected the error of the failed job but got %v", err)
+	}
+	if expected := []string{"/b.sentinel job 0"}; !slices.Equal(order, expected) {
+		t.Errorf("expected %v but got %v", expected, order)
+	}
+}
@@ -1,46 +1,59 @@
 package linting
 
 import (
+	"context"
+
 	slint "github.com/glennsarti/sentinel-lint/lint"
 	"github.com/glennsarti/sentinel-lint/rules"
 	"github.com/glennsarti/sentinel-lint/runner"
 
 	"github.com/glennsarti/sentinel-utils/lib/filesystem"
+	"github.com/glennsarti/sentinel-utils/lib/internal/helpers"
 	"github.com/glennsarti/sentinel-utils/lib/parsing"
 	cwalker "github.com/glennsarti/sentinel-utils/lib/walkers/sentinel_config"
 )
 
 type LintIssueYielder func(lintFile slint.File, parsingIssues slint.Issues)
 
-func Lint(walker cwalker.Walker, pf parsing.Factory, yielder LintIssueYielder) error {
+// Lint walks the policy set and lints each file. If the context is cancelled, linting stops
+// and a CancelledError is returned. The issues for files which were already linted will have
+// been yielded. Issues suppressed by a SuppressionDirective comment are not yielded.
+func Lint(ctx context.Context, walker cwalker.Walker, pf parsing.Factory, yielder LintIssueYielder, opts ...LintOption) error {
 	lintRuleSet := rules.NewDefaultRuleSet() // TODO: Parameterise this stuff
 	cfg := slint.Config{
 		SentinelVersion: walker.SentinelVersion(),
 	}
 
-	visitor := func(file *filesystem.File, lintFile slint.File, parsingIssues slint.Issues) (bool, error) {
+	visitor := func(fileCtx context.Context, file *filesystem.File, lintFile slint.File, parsingIssues slint.Issues) (slint.Issues, bool, error) {
 		allIssues := make(slint.Issues, 0)
 		allIssues = append(allIssues, parsingIssues...)
 
 		if parsingIssues.HasErrors() {
-			yielder(lintFile, allIssues)
-			return true, nil // TODO: Should this be false?
+			return allIssues, true, nil // TODO: Should this be false?
 		}
 
 		r, _ := runner.NewRunner(cfg, lintRuleSet, lintFile)
-		if issues, err := r.Run(); err != nil {
-			return false, err
+		// Lints can not be cancelled, so the ones which are stopped waiting for are left to
+		// finish by themselves
+		if issues, err := helpers.RunWithContext(fileCtx, r.Run); err != nil {
+			if !walker.ContinueOnError() || ctx.Err() != nil {
+				return nil, false, err
+			}
+			allIssues = append(allIssues, newFileErrorIssue(lintFile.Path(), err))
 		} else {
 			allIssues = append(allIssues, issues...)
 		}
 
-		yielder(lintFile, allIssues)
-
-		return true, nil
+		return allIssues, true, nil
 	}
 
-	lw := newLintWalker(walker, yielder, pf)
-	err := lw.Walk(visitor)
+	lw := newLintWalker(walker, withSuppressions(walker.FileSystem(), yielder), pf, opts...)
+	err := lw.Walk(ctx, visitor)
+	if err != nil && !cwalker.IsCancelled(err) {
+		if cErr := cwalker.NewCancelledError(ctx); cErr != nil {
+			return cErr
+		}
+	}
 
 	return err
 }
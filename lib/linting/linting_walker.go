@@ -1,35 +1,59 @@
 package linting
 
 import (
+	"context"
+	"crypto/sha256"
 	"fmt"
+	"hash"
 	"io/fs"
 
 	slint "github.com/glennsarti/sentinel-lint/lint"
 	"github.com/glennsarti/sentinel-parser/diagnostics"
+	"github.com/glennsarti/sentinel-parser/features"
 	"github.com/glennsarti/sentinel-parser/filetypes"
 	"github.com/glennsarti/sentinel-parser/position"
+	"github.com/glennsarti/sentinel-utils/lib/configuration"
 	"github.com/glennsarti/sentinel-utils/lib/filesystem"
+	"github.com/glennsarti/sentinel-utils/lib/internal/helpers"
 	"github.com/glennsarti/sentinel-utils/lib/parsing"
 
 	scast "github.com/glennsarti/sentinel-parser/sentinel_config/ast"
-	scparser "github.com/glennsarti/sentinel-parser/sentinel_config/parser"
 	cwalker "github.com/glennsarti/sentinel-utils/lib/walkers/sentinel_config"
 )
 
-type lintFileVisitor func(file *filesystem.File, lintFile slint.File, parsingIssues slint.Issues) (bool, error)
+// lintFileVisitor lints a file and returns the issues to yield for it. Returning nil issues
+// yields nothing.
+type lintFileVisitor func(ctx context.Context, file *filesystem.File, lintFile slint.File, parsingIssues slint.Issues) (slint.Issues, bool, error)
 
 type lintFileSystemWalker interface {
-	Walk(visitor lintFileVisitor) error
+	Walk(ctx context.Context, visitor lintFileVisitor) error
 	FileSystem() filesystem.FS
 	Root() string
 }
 
-func newLintWalker(w cwalker.Walker, iy LintIssueYielder, pf parsing.Factory) lintFileSystemWalker {
-	return &lintWalker{
+// LintOption configures how linting is run
+type LintOption func(*lintWalker)
+
+// WithJobs parses and lints up to the number of files at the same time. The primary
+// configuration and overrides are always resolved first. Issues are yielded once linting
+// has finished, sorted by file path, so the order does not depend on the number of jobs.
+func WithJobs(jobs int) LintOption {
+	return func(w *lintWalker) {
+		w.jobs = jobs
+	}
+}
+
+func newLintWalker(w cwalker.Walker, iy LintIssueYielder, pf parsing.Factory, opts ...LintOption) lintFileSystemWalker {
+	lw := &lintWalker{
 		rootWalker:   w,
 		parseFactory: pf,
 		issueYielder: iy,
+		jobs:         1,
+	}
+	for _, opt := range opts {
+		opt(lw)
 	}
+	return lw
 }
 
 type lintWalker struct {
@@ -39,29 +63,91 @@ type lintWalker struct {
 	primaryFile     *filesystem.File
 	primaryLintFile *slint.ConfigPrimaryFile
 	primaryIssues   slint.Issues
-	issueYielder    LintIssueYielder
+	// Applies the overrides to the resolved configuration
+	merger       *configuration.Merger
+	issueYielder LintIssueYielder
+	// The context for the whole walk
+	ctx context.Context
+
+	jobs      int
+	scheduler *lintScheduler
+
+	resultCache ResultCache
+	// The content of the files which every result depends on
+	resultDependencies  hash.Hash
+	resultDependencySum []byte
+
+	requiredVersionCheck  bool
+	requiredVersionSource string
+
+	missingFileHandler MissingFileHandler
 }
 
-func (w *lintWalker) Walk(visitor lintFileVisitor) error {
+func (w *lintWalker) Walk(ctx context.Context, visitor lintFileVisitor) error {
 	w.visitedPrimary = false
 	w.primaryLintFile = nil
-
-	err := w.rootWalker.Walk(func(file *filesystem.File, p *position.SourceRange) (bool, error) {
-		return w.visit(file, visitor, p)
+	w.merger = nil
+	w.ctx = ctx
+	w.resultDependencies = sha256.New()
+	w.resultDependencySum = nil
+	w.scheduler = newLintScheduler(max(w.jobs, 1))
+
+	err := w.rootWalker.Walk(ctx, func(fileCtx context.Context, file *filesystem.File, p *position.SourceRange) (bool, error) {
+		return w.visit(fileCtx, file, visitor, p)
 	})
 
-	if err != nil {
-		return err
+	// Just incase we never actually visited the primary config ....
+	if err == nil && !w.visitedPrimary && w.primaryLintFile != nil {
+		_, err = w.lintPrimary(ctx, visitor)
 	}
 
-	// Just incase we never actually visited the primary config ....
-	if !w.visitedPrimary && w.primaryLintFile != nil {
-		if _, err := visitor(w.primaryFile, *w.primaryLintFile, w.primaryIssues); err != nil {
-			return err
-		}
+	// Files which were already linted are still yielded if the walk failed
+	if jobErr := w.scheduler.finish(w.issueYielder); err == nil {
+		err = jobErr
+	}
+
+	return err
+}
+
+func (w *lintWalker) lintFile(
+	ctx context.Context,
+	visitor lintFileVisitor,
+	file *filesystem.File,
+	lintFile slint.File,
+	parsingIssues slint.Issues,
+	yield LintIssueYielder,
+) (bool, error) {
+	issues, cont, err := visitor(ctx, file, lintFile, parsingIssues)
+	if issues != nil {
+		yield(lintFile, issues)
 	}
+	return cont, err
+}
+
+// The overrides have all been applied by now, so the primary can be linted at the same time as other files
+func (w *lintWalker) lintPrimary(ctx context.Context, visitor lintFileVisitor) (bool, error) {
+	file, lintFile, issues := w.primaryFile, *w.primaryLintFile, w.primaryIssues
+	return w.schedule(ctx, func(jobCtx context.Context, yield LintIssueYielder) (bool, error) {
+		return w.lintFile(jobCtx, visitor, file, lintFile, issues, yield)
+	})
+}
+
+// Schedules the job to be run once there is a free job
+func (w *lintWalker) schedule(ctx context.Context, job lintJobFunc) (bool, error) {
+	return w.scheduler.schedule(w.ctx, ctx, job)
+}
 
-	return nil
+// Yields the issues in the order they would have been found
+func (w *lintWalker) yield(lintFile slint.File, issues slint.Issues) {
+	w.scheduler.yield(lintFile, issues)
+}
+
+// The Sentinel version being linted against, with "latest" resolved to the actual version
+func (w *lintWalker) sentinelVersion() string {
+	if ver := w.rootWalker.SentinelVersion(); ver != "" && ver != features.LatestSentinelVersion {
+		return ver
+	}
+	return features.SentinelVersions[0]
 }
 
 func (w *lintWalker) FileSystem() filesystem.FS {
@@ -72,12 +158,14 @@ func (w *lintWalker) Root() string {
 	return w.rootWalker.Root()
 }
 
-func (w *lintWalker) visit(file *filesystem.File, visitor lintFileVisitor, from *position.SourceRange) (bool, error) {
+func (w *lintWalker) visit(ctx context.Context, file *filesystem.File, visitor lintFileVisitor, from *position.SourceRange) (bool, error) {
 	if _, err := fs.Stat(w.FileSystem(), file.Path); err != nil {
 		if from != nil && from.Filename != "" {
-			w.issueYielder(newUnknownFile(from.Filename), slint.Issues{
-				newFileNotExistIssue(file.Path, from),
-			})
+			issue := newFileNotExistIssue(file.Path, from)
+			if w.missingFileHandler != nil {
+				w.missingFileHandler(issue, file.Path)
+			}
+			w.yield(newUnknownFile(from.Filename), slint.Issues{issue})
 		}
 		return true, nil
 	}
@@ -86,113 +174,244 @@ func (w *lintWalker) visit(file *filesystem.File, visitor lintFileVisitor, from
 		// Read it
 		content, err := fs.ReadFile(w.FileSystem(), file.Path)
 		if err != nil {
-			return false, err
+			return w.continueOnError(file, err)
 		}
 		file.Content = &content
 	}
+	if w.resultCache != nil {
+		w.addResultDependency(file)
+	}
 
 	if file.Type == filetypes.ConfigPrimaryFileType {
 		w.primaryFile = file
 		w.visitedPrimary = false
-		cfg, d, err := w.parseFactory.ParseSentinelConfigFile(file, w.rootWalker.SentinelVersion())
+		cfg, d, err := w.parseFactory.ParseSentinelConfigFile(ctx, file, w.rootWalker.SentinelVersion())
 		if err != nil {
-			return false, err
+			return w.continueOnError(file, err)
 		}
 
+		resolved := scast.CloneFile(cfg)
+		if resolved == nil && w.rootWalker.ContinueOnError() {
+			// Overrides still need something to apply to
+			resolved = scast.NewFile()
+		}
+
+		w.merger = configuration.NewMerger(resolved, w.rootWalker.SentinelVersion(), w.FileSystem())
 		w.primaryLintFile = &slint.ConfigPrimaryFile{
 			ConfigFile:         cfg,
-			ResolvedConfigFile: scast.CloneFile(cfg),
+			ResolvedConfigFile: resolved,
 			FilePath:           file.Path,
 		}
-		w.primaryIssues = diagsToIssues(d)
+		w.primaryIssues = w.withRequiredVersionIssue(ctx, file, d, diagsToIssues(d))
+		w.primaryIssues = append(w.primaryIssues, configFeatureIssues(cfg, w.sentinelVersion())...)
 
-		return !d.HasErrors(), nil
+		overrides := unsupportedOverrideIssues(w.FileSystem(), file.Path, w.sentinelVersion())
+		for _, overridePath := range helpers.SortedKeys(overrides) {
+			w.yield(newUnknownFile(overridePath), slint.Issues{overrides[overridePath]})
+		}
+
+		return !d.HasErrors() || w.rootWalker.ContinueOnError(), nil
 	}
 
 	if file.Type == filetypes.ConfigOverrideFileType {
 		if w.primaryLintFile == nil || w.primaryLintFile.ResolvedConfigFile == nil {
-			return false, fmt.Errorf("the override file %q has no primary file to override", file.Path)
+			return w.continueOnError(file, fmt.Errorf("the override file %q has no primary file to override", file.Path))
 		}
 		if w.visitedPrimary {
-			return false, fmt.Errorf("the override file %q is in the wrong directory", file.Path)
+			return w.continueOnError(file, fmt.Errorf("the override file %q is in the wrong directory", file.Path))
 		}
 
-		cfg, d, err := w.parseFactory.ParseSentinelConfigFile(file, w.rootWalker.SentinelVersion())
+		cfg, d, err := w.parseFactory.ParseSentinelConfigFile(ctx, file, w.rootWalker.SentinelVersion())
 		if err != nil {
-			return false, err
+			return w.continueOnError(file, err)
 		}
 
+		// Applying the override changes the resolved configuration, which a lint that timed
+		// out may still be reading
 		f := slint.ConfigOverrideFile{
 			ConfigFile:  cfg,
-			PrimaryFile: w.primaryLintFile.ResolvedConfigFile,
+			PrimaryFile: scast.CloneFile(w.primaryLintFile.ResolvedConfigFile),
 			FilePath:    file.Path,
 		}
 
-		if cont, err := visitor(file, f, diagsToIssues(d)); err != nil {
+		// The override is linted before it is applied to the primary, so this can not be scheduled
+		issues := w.withRequiredVersionIssue(ctx, file, d, diagsToIssues(d))
+		issues = append(issues, configFeatureIssues(cfg, w.sentinelVersion())...)
+		// The issues are yielded once the override is applied, with the issues from applying it
+		var linted slint.Issues
+		collect := func(_ slint.File, issues slint.Issues) {
+			linted = append(linted, w.withShadowedDefinitions(cfg, issues)...)
+		}
+		if cont, err := w.lintFile(ctx, visitor, file, f, issues, collect); err != nil {
+			if linted != nil {
+				w.yield(f, linted)
+			}
 			return cont, err
 		}
 
-		diags := scparser.OverrideFileWith(w.primaryLintFile.ResolvedConfigFile, cfg, w.rootWalker.SentinelVersion())
-		if diags.HasErrors() {
+		diags := w.merger.Apply(cfg)
+		// The parts of the override which could be applied, have been applied
+		linted = append(linted, diagsToFileIssues(diags, file.Path)...)
+		if len(linted) > 0 {
+			w.yield(f, linted)
+		}
+		if diags.HasErrors() && !w.rootWalker.ContinueOnError() {
 			return false, diags
 		}
 		return true, nil
 	}
 
+	// Files which are not referenced by the configuration have nothing to lint
+	if file.Type == cwalker.OrphanedFileType {
+		w.yield(newUnknownFile(file.Path), slint.Issues{
+			newOrphanedFileIssue(file.Path, w.FileSystem()),
+		})
+		return true, nil
+	}
+
 	// Visit the primary file, as all the overrides have been processed
 	if !w.visitedPrimary && w.primaryLintFile != nil {
 		w.visitedPrimary = true
-		return visitor(w.primaryFile, *w.primaryLintFile, w.primaryIssues)
+		return w.lintPrimary(ctx, visitor)
 	}
 
-	// Visit everything else
+	// Visit everything else. Nothing changes the configuration from here on, so these
+	// files can be parsed and linted in any order.
+	var resolvedConfig *scast.File
+	if w.primaryLintFile != nil {
+		resolvedConfig = w.primaryLintFile.ResolvedConfigFile
+	}
 	switch file.Type {
-	case filetypes.PolicyFileType:
-		parsed, d, err := w.parseFactory.ParseSentinelFile(file, w.rootWalker.SentinelVersion())
-		if err != nil {
-			return false, err
+	case filetypes.PolicyFileType, filetypes.ModuleFileType, filetypes.ConfigTestFileType:
+		if w.resultCache == nil {
+			return w.schedule(ctx, func(jobCtx context.Context, yield LintIssueYielder) (bool, error) {
+				return w.parseAndLint(jobCtx, file, resolvedConfig, visitor, yield)
+			})
 		}
 
-		f := slint.PolicyFile{
-			File:     parsed,
-			FilePath: file.Path,
+		key := w.resultKey(file)
+		if issues, ok := w.resultCache.Get(key); ok {
+			w.yield(newCachedLintFile(file), issues)
+			return true, nil
 		}
-		if w.primaryLintFile != nil {
-			f.ConfigFile = w.primaryLintFile.ResolvedConfigFile
+		return w.schedule(ctx, func(jobCtx context.Context, yield LintIssueYielder) (bool, error) {
+			return w.parseAndLint(jobCtx, file, resolvedConfig, visitor, func(lintFile slint.File, issues slint.Issues) {
+				if lintFile.Path() == file.Path && isCacheableResult(issues) {
+					// The cache is only an optimisation, so failing to write to it is not an error
+					_ = w.resultCache.Put(key, issues)
+				}
+				yield(lintFile, issues)
+			})
+		})
+
+	default:
+		return true, fmt.Errorf("unknown file %q", file.Path)
+	}
+}
+
+func (w *lintWalker) parseAndLint(
+	ctx context.Context,
+	file *filesystem.File,
+	resolvedConfig *scast.File,
+	visitor lintFileVisitor,
+	yield LintIssueYielder,
+) (bool, error) {
+	var lintFile slint.File
+	var d diagnostics.Diagnostics
+
+	switch file.Type {
+	case filetypes.PolicyFileType:
+		parsed, diags, err := w.parseFactory.ParseSentinelFile(ctx, file, w.rootWalker.SentinelVersion())
+		if err != nil {
+			return w.continueOnErrorWith(file, err, yield)
 		}
-		return visitor(file, f, diagsToIssues(d))
+		lintFile, d = slint.PolicyFile{
+			File:       parsed,
+			ConfigFile: resolvedConfig,
+			FilePath:   file.Path,
+		}, diags
 
 	case filetypes.ModuleFileType:
-		parsed, d, err := w.parseFactory.ParseSentinelFile(file, w.rootWalker.SentinelVersion())
+		parsed, diags, err := w.parseFactory.ParseSentinelFile(ctx, file, w.rootWalker.SentinelVersion())
 		if err != nil {
-			return false, err
+			return w.continueOnErrorWith(file, err, yield)
 		}
-
-		return visitor(file, slint.ModuleFile{
+		lintFile, d = slint.ModuleFile{
 			File:     parsed,
 			FilePath: file.Path,
-		}, diagsToIssues(d))
+		}, diags
 
 	case filetypes.ConfigTestFileType:
-		cfg, d, err := w.parseFactory.ParseSentinelConfigFile(file, w.rootWalker.SentinelVersion())
+		cfg, diags, err := w.parseFactory.ParseSentinelConfigFile(ctx, file, w.rootWalker.SentinelVersion())
 		if err != nil {
-			return false, err
+			return w.continueOnErrorWith(file, err, yield)
 		}
-
-		return visitor(file, slint.ConfigTestFile{
+		lintFile, d = slint.ConfigTestFile{
 			ConfigFile: cfg,
 			FilePath:   file.Path,
-		}, diagsToIssues(d))
+		}, diags
+	}
 
-	default:
-		return true, fmt.Errorf("unknown file %q", file.Path)
+	return w.lintFile(ctx, visitor, file, lintFile, w.withRequiredVersionIssue(ctx, file, d, diagsToIssues(d)), yield)
+}
+
+// Points issues about useless overrides at the definitions they would override
+func (w *lintWalker) withShadowedDefinitions(override *scast.File, issues slint.Issues) slint.Issues {
+	for _, issue := range issues {
+		if issue == nil || issue.RuleId != UselessOverrideRuleID || issue.Related != nil {
+			continue
+		}
+		defs := w.merger.Shadowed(override, issue.Range)
+		if len(defs) == 0 {
+			continue
+		}
+		related := make([]slint.RelatedInformation, len(defs))
+		for idx, def := range defs {
+			related[idx] = slint.RelatedInformation{
+				Summary: "Overrides this definition",
+				Range:   def.Range,
+			}
+		}
+		issue.Related = &related
 	}
+	return issues
+}
+
+// When continuing on errors, the error is converted into an issue on the file instead
+// of stopping the walk. A cancelled walk always stops.
+func (w *lintWalker) continueOnError(file *filesystem.File, err error) (bool, error) {
+	return w.continueOnErrorWith(file, err, w.yield)
+}
+
+func (w *lintWalker) continueOnErrorWith(file *filesystem.File, err error, yield LintIssueYielder) (bool, error) {
+	if !w.rootWalker.ContinueOnError() || (w.ctx != nil && w.ctx.Err() != nil) {
+		return false, err
+	}
+	yield(newUnknownFile(file.Path), slint.Issues{
+		newFileErrorIssue(file.Path, err),
+	})
+	return true, nil
+}
+
+// Diagnostics without a range are placed at the start of the file
+func diagsToFileIssues(diags diagnostics.Diagnostics, filePath string) slint.Issues {
+	list := diagsToIssues(diags)
+	for _, issue := range list {
+		if issue.Range == nil {
+			issue.Range = startOfFileRange(filePath)
+		}
+	}
+	return list
 }
 
 func diagsToIssues(diags diagnostics.Diagnostics) slint.Issues {
 	list := make(slint.Issues, 0)
 	for _, diag := range diags {
-		if diag != nil && diag.Severity == diagnostics.Error {
+		if diag == nil {
+			continue
+		}
+		switch diag.Severity {
+		case diagnostics.Error:
 			list = append(list, &slint.Issue{
 				RuleId:   slint.SyntaxErrorRuleID,
 				Summary:  diag.Summary,
@@ -200,6 +419,14 @@ func diagsToIssues(diags diagnostics.Diagnostics) slint.Issues {
 				Range:    diag.Range,
 				Severity: diagSeverityToIssueSeverity(diag.Severity),
 			})
+		case diagnostics.Warning:
+			list = append(list, &slint.Issue{
+				RuleId:   syntaxWarningRuleID,
+				Summary:  diag.Summary,
+				Detail:   diag.Detail,
+				Range:    diag.Range,
+				Severity: diagSeverityToIssueSeverity(diag.Severity),
+			})
 		}
 	}
 	return list
@@ -0,0 +1,212 @@
+package linting
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	slint "github.com/glennsarti/sentinel-lint/lint"
+	"github.com/glennsarti/sentinel-utils/lib/filesystem"
+	"github.com/glennsarti/sentinel-utils/lib/internal/helpers"
+	"github.com/glennsarti/sentinel-utils/lib/internal/txtar_fs"
+	parsing "github.com/glennsarti/sentinel-utils/lib/parsing/default"
+	cwalker "github.com/glennsarti/sentinel-utils/lib/walkers/sentinel_config"
+	"golang.org/x/tools/txtar"
+)
+
+// Run with -race. A lint of an override which timed out keeps reading the configuration
+// it was given, while the override is applied.
+func TestOverrideLintTimeout(t *testing.T) {
+	arcfs := txtar_fs.NewTxtarFileSystem(txtar.Parse([]byte(`-- sentinel.hcl --
+policy "p" {
+  source = "./p.sentinel"
+  enforcement_level = "advisory"
+}
+-- a_override.hcl --
+policy "p" {
+  enforcement_level = "hard-mandatory"
+}
+-- p.sentinel --
+main = rule { true }
+`)))
+	pf := parsing.NewDefaultParsingFactory(arcfs)
+	w := cwalker.NewSentinelConfigWalker(arcfs, "/", "", pf,
+		cwalker.WithContinueOnError(),
+		cwalker.WithFileTimeout(time.Millisecond),
+	)
+
+	var reading sync.WaitGroup
+	visitor := func(fileCtx context.Context, _ *filesystem.File, lintFile slint.File, issues slint.Issues) (slint.Issues, bool, error) {
+		override, ok := lintFile.(slint.ConfigOverrideFile)
+		if !ok {
+			return issues, true, nil
+		}
+		reading.Add(1)
+		_, err := helpers.RunWithContext(fileCtx, func() (string, error) {
+			defer reading.Done()
+			level := ""
+			for range 20 {
+				level = override.PrimaryFile.Policies["p"].EnforcementLevel
+				time.Sleep(time.Millisecond)
+			}
+			return level, nil
+		})
+		if err == nil {
+			t.Error("expected the lint of the override to time out")
+		}
+		return issues, true, nil
+	}
+
+	lw := newLintWalker(w, func(slint.File, slint.Issues) {}, pf)
+	if err := lw.Walk(context.Background(), visitor); err != nil {
+		t.Fatal(err)
+	}
+	reading.Wait()
+
+	resolved := lw.(*lintWalker).primaryLintFile.ResolvedConfigFile
+	if level := resolved.Policies["p"].EnforcementLevel; level != "hard-mandatory" {
+		t.Errorf("expected the override to be applied but got %q", level)
+	}
+}
+
+func TestUselessOverrideRelated(t *testing.T) {
+	arcfs := txtar_fs.NewTxtarFileSystem(txtar.Parse([]byte(`-- a_override.hcl --
+# Force a lint error
+policy "policy1" {}
+-- b_override.hcl --
+# Force a lint error
+policy "policy1" {}
+-- sentinel.hcl --
+// module doc
+import "module" "utilitie1s" {
+  source = "./modules/module.sentinel"
+}
+// static import doc
+import "static" "utilities2" {
+  source = "./modules/util2.json"
+  format = "json"
+}
+// policy1 doc
+policy "policy1" {
+  source = "./policies/policy1/policy1.sentinel"
+}
+-- modules/module.sentinel --
+# Empty Sentinel module
+-- modules/util2.json --
+{}
+-- policies/policy1/policy1.sentinel --
+main = rule { true }
+`)))
+	pf := parsing.NewDefaultParsingFactory(arcfs)
+	w := cwalker.NewSentinelConfigWalker(arcfs, "/", "", pf)
+
+	// sentinel-lint reports the block, which the walker then points at what it overrides
+	visitor := func(_ context.Context, _ *filesystem.File, lintFile slint.File, issues slint.Issues) (slint.Issues, bool, error) {
+		if override, ok := lintFile.(slint.ConfigOverrideFile); ok {
+			issues = append(issues, &slint.Issue{
+				RuleId:  UselessOverrideRuleID,
+				Summary: "Block has no effect",
+				Range:   override.ConfigFile.Policies["policy1"].PolicyRange,
+			})
+		}
+		return issues, true, nil
+	}
+
+	related := make(map[string][]string)
+	lw := newLintWalker(w, func(lintFile slint.File, issues slint.Issues) {
+		for _, issue := range issues {
+			if issue.RuleId != UselessOverrideRuleID || issue.Related == nil {
+				continue
+			}
+			for _, r := range *issue.Related {
+				related[lintFile.Path()] = append(related[lintFile.Path()],
+					fmt.Sprintf("%s:%d:%d", r.Range.Filename, r.Range.Start.Line+1, r.Range.Start.Column+1))
+			}
+		}
+	}, pf)
+	if err := lw.Walk(context.Background(), visitor); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string][]string{
+		"/a_override.hcl": {"/sentinel.hcl:11:1"},
+		// The second override also overrides the first
+		"/b_override.hcl": {"/sentinel.hcl:11:1", "/a_override.hcl:2:1"},
+	}
+	if !maps.EqualFunc(related, expected, slices.Equal) {
+		t.Errorf("expected %v but got %v", expected, related)
+	}
+}
+
+func TestMissingFiles(t *testing.T) {
+	arcfs := txtar_fs.NewTxtarFileSystem(txtar.Parse([]byte(`-- sentinel.hcl --
+policy "p" {
+  source = "./p.sentinel"
+}
+`)))
+	pf := parsing.NewDefaultParsingFactory(arcfs)
+	w := cwalker.NewSentinelConfigWalker(arcfs, "/", "", pf)
+
+	missing := make(map[*slint.Issue]string)
+	var yielded []*slint.Issue
+	err := Lint(context.Background(), w, pf, func(_ slint.File, issues slint.Issues) {
+		yielded = append(yielded, issues...)
+	}, WithMissingFiles(func(issue *slint.Issue, filePath string) { missing[issue] = filePath }))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(yielded) != 1 || missing[yielded[0]] != "/p.sentinel" {
+		t.Errorf("expected the issue about /p.sentinel to be handled but got %v", missing)
+	}
+}
+
+func TestLintOrderWithJobs(t *testing.T) {
+	arcfs := txtar_fs.NewTxtarFileSystem(txtar.Parse([]byte(`-- sentinel.hcl --
+policy "d" {
+  source = "./policies/d.sentinel"
+}
+
+policy "c" {
+  source = "./policies/c.sentinel"
+}
+
+policy "a" {
+  source = "./policies/a.sentinel"
+}
+
+policy "b" {
+  source = "./policies/missing.sentinel"
+}
+-- policies/d.sentinel --
+main = rule { true }
+-- policies/c.sentinel --
+main = rule { true }
+-- policies/a.sentinel --
+main = rule { true
+`)))
+	order := func(jobs int) []string {
+		pf := parsing.NewDefaultParsingFactory(arcfs)
+		w := cwalker.NewSentinelConfigWalker(arcfs, "/", "", pf, cwalker.WithContinueOnError())
+		paths := make([]string, 0)
+		err := Lint(context.Background(), w, pf, func(lintFile slint.File, _ slint.Issues) {
+			paths = append(paths, lintFile.Path())
+		}, WithJobs(jobs))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return paths
+	}
+
+	sequential := order(1)
+	if !slices.IsSorted(sequential) {
+		t.Errorf("expected the files to be sorted but got %v", sequential)
+	}
+	if concurrent := order(4); !slices.Equal(sequential, concurrent) {
+		t.Errorf("expected the same order with more jobs, %v, but got %v", sequential, concurrent)
+	}
+}
@@ -0,0 +1,118 @@
+package linting
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	slint "github.com/glennsarti/sentinel-lint/lint"
+	"github.com/glennsarti/sentinel-parser/diagnostics"
+	"github.com/glennsarti/sentinel-parser/features"
+	"github.com/glennsarti/sentinel-parser/filetypes"
+	"github.com/glennsarti/sentinel-utils/lib/filesystem"
+	"golang.org/x/mod/semver"
+)
+
+// WithRequiredVersionCheck checks every newer Sentinel version, instead of only the versions
+// which added language features, when a file can not be parsed by the Sentinel version being
+// linted against. The source describes where the Sentinel version came from, for example the
+// version file it was read from, and can be empty.
+func WithRequiredVersionCheck(source string) LintOption {
+	return func(w *lintWalker) {
+		w.requiredVersionCheck = true
+		w.requiredVersionSource = source
+	}
+}
+
+// A feature which is a syntax error in Sentinel versions before it was added
+type gatedFeature struct {
+	description    string
+	minimumVersion string
+}
+
+// The defined operators are not included, as older versions parse them as identifiers
+var sentinelFileFeatures = []gatedFeature{
+	{description: "function declarations", minimumVersion: features.FuncDeclarationsMinimumVerions},
+}
+
+var configFileFeatures = []gatedFeature{
+	{description: "version 2 import blocks", minimumVersion: features.V2ImportBlockMinimumVersion},
+}
+
+// Appends an issue if a newer Sentinel version is needed to parse the file
+func (w *lintWalker) withRequiredVersionIssue(ctx context.Context, file *filesystem.File, d diagnostics.Diagnostics, issues slint.Issues) slint.Issues {
+	if !d.HasErrors() {
+		return issues
+	}
+
+	current := w.sentinelVersion()
+	gated := configFileFeatures
+	if file.Type == filetypes.PolicyFileType || file.Type == filetypes.ModuleFileType {
+		gated = sentinelFileFeatures
+	}
+
+	required := w.requiredVersion(ctx, file, w.candidateVersions(current, gated))
+	if required == "" {
+		return issues
+	}
+
+	// The features which could be the reason
+	possible := make([]string, 0)
+	for _, feature := range gated {
+		if features.UnsupportedVersion(current, feature.minimumVersion) && features.SupportedVersion(required, feature.minimumVersion) {
+			possible = append(possible, fmt.Sprintf("%s (%s)", feature.description, feature.minimumVersion))
+		}
+	}
+
+	return append(issues, newRequiredVersionIssue(file.Path, current, required, w.requiredVersionSource, possible, issues))
+}
+
+// The Sentinel versions to try parsing the file with, oldest first
+func (w *lintWalker) candidateVersions(current string, gated []gatedFeature) []string {
+	result := make([]string, 0)
+	if w.requiredVersionCheck {
+		for idx := len(features.SentinelVersions) - 1; idx >= 0; idx-- {
+			if ver := features.SentinelVersions[idx]; features.UnsupportedVersion(current, ver) {
+				result = append(result, ver)
+			}
+		}
+		return result
+	}
+
+	for _, feature := range gated {
+		if features.UnsupportedVersion(current, feature.minimumVersion) && !slices.Contains(result, feature.minimumVersion) {
+			result = append(result, feature.minimumVersion)
+		}
+	}
+	slices.SortFunc(result, semver.Compare)
+	return result
+}
+
+// The first version which can parse the file without errors. Empty if there is no such version.
+func (w *lintWalker) requiredVersion(ctx context.Context, file *filesystem.File, candidates []string) string {
+	for _, ver := range candidates {
+		var d diagnostics.Diagnostics
+		var err error
+		switch file.Type {
+		case filetypes.PolicyFileType, filetypes.ModuleFileType:
+			_, d, err = w.parseFactory.ParseSentinelFile(ctx, file, ver)
+		default:
+			_, d, err = w.parseFactory.ParseSentinelConfigFile(ctx, file, ver)
+		}
+		if err != nil {
+			return ""
+		}
+		if !d.HasErrors() {
+			return ver
+		}
+	}
+	return ""
+}
+
+func joinFeatures(items []string) string {
+	if len(items) <= 1 {
+		return strings.Join(items, "")
+	}
+	return strings.Join(items[:len(items)-1], ", ") + " or " + items[len(items)-1]
+}
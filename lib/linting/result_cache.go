@@ -0,0 +1,138 @@
+package linting
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"runtime/debug"
+	"strings"
+	"sync"
+
+	slint "github.com/glennsarti/sentinel-lint/lint"
+	"github.com/glennsarti/sentinel-parser/filetypes"
+	scast "github.com/glennsarti/sentinel-parser/sentinel_config/ast"
+	"github.com/glennsarti/sentinel-utils/lib/filesystem"
+	"github.com/glennsarti/sentinel-utils/lib/internal/helpers"
+)
+
+// ResultCache stores the issues found in a file, so that unchanged files do not need to
+// be parsed or linted again. Keys are opaque strings.
+type ResultCache interface {
+	Get(key string) (slint.Issues, bool)
+	Put(key string, issues slint.Issues) error
+}
+
+// WithResultCache uses the cache for the results of policies, modules and tests. A result is
+// only used if the file, the configuration files and the modules are all unchanged, and it was
+// linted by the same version of the tool, rules and Sentinel.
+func WithResultCache(cache ResultCache) LintOption {
+	return func(w *lintWalker) {
+		w.resultCache = cache
+	}
+}
+
+// The name of the rules used by Lint, which always uses the default rule set
+const defaultRuleSetName = "default"
+
+// The versions of this tool and the modules which affect linting
+var toolVersion = sync.OnceValue(func() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	result := info.Main.Path + "@" + info.Main.Version
+	for _, dep := range info.Deps {
+		switch dep.Path {
+		case "github.com/glennsarti/sentinel-lint", "github.com/glennsarti/sentinel-parser":
+			result += " " + dep.Path + "@" + dep.Version
+		}
+	}
+	return result
+})
+
+// The configuration files are visited before any other file. Changing any of them invalidates
+// every cached result.
+func (w *lintWalker) addResultDependency(file *filesystem.File) {
+	switch file.Type {
+	case filetypes.ConfigPrimaryFileType, filetypes.ConfigOverrideFileType:
+		contentHash := sha256.Sum256(*file.Content)
+		fmt.Fprintf(w.resultDependencies, "%s\n%x\n", file.Path, contentHash)
+	}
+}
+
+// Every result also depends on the modules which the resolved configuration imports, which
+// may be visited after the files that use them. They are all hashed before the first key.
+func (w *lintWalker) allResultDependencies() []byte {
+	if w.resultDependencySum != nil {
+		return w.resultDependencySum
+	}
+	if w.primaryLintFile != nil && w.primaryLintFile.ResolvedConfigFile != nil {
+		parentDir := w.FileSystem().ParentPath(w.primaryFile.Path)
+		imports := w.primaryLintFile.ResolvedConfigFile.Imports
+		for _, key := range helpers.SortedKeys(imports) {
+			source := ""
+			switch imp := imports[key].(type) {
+			case *scast.V1ModuleImport:
+				source = imp.Source
+			case *scast.V2ModuleImport:
+				source = imp.Source
+			}
+			if !strings.HasPrefix(source, "./") {
+				continue
+			}
+			modulePath := w.FileSystem().PathJoin(parentDir, source[2:])
+			// A module which can not be read is a dependency which may be added later
+			content, err := fs.ReadFile(w.FileSystem(), modulePath)
+			if err != nil {
+				fmt.Fprintf(w.resultDependencies, "%s\nmissing\n", modulePath)
+				continue
+			}
+			fmt.Fprintf(w.resultDependencies, "%s\n%x\n", modulePath, sha256.Sum256(content))
+		}
+	}
+	w.resultDependencySum = w.resultDependencies.Sum(nil)
+	return w.resultDependencySum
+}
+
+func (w *lintWalker) resultKey(file *filesystem.File) string {
+	contentHash := sha256.Sum256(*file.Content)
+
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n%s\n%s\n%t\n%s\n%s\n%s\n%x\n%x\n",
+		toolVersion(),
+		defaultRuleSetName,
+		w.rootWalker.SentinelVersion(),
+		w.requiredVersionCheck,
+		w.requiredVersionSource,
+		file.Type,
+		file.Path,
+		contentHash,
+		w.allResultDependencies(),
+	)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Errors may not happen next time, so results which contain them are not kept
+func isCacheableResult(issues slint.Issues) bool {
+	for _, issue := range issues {
+		if issue == nil || issue.RuleId == FileErrorRuleID {
+			return false
+		}
+	}
+	return true
+}
+
+// Cached results do not have the parsed file
+func newCachedLintFile(file *filesystem.File) slint.File {
+	switch file.Type {
+	case filetypes.PolicyFileType:
+		return slint.PolicyFile{FilePath: file.Path}
+	case filetypes.ModuleFileType:
+		return slint.ModuleFile{FilePath: file.Path}
+	case filetypes.ConfigTestFileType:
+		return slint.ConfigTestFile{FilePath: file.Path}
+	default:
+		return newUnknownFile(file.Path)
+	}
+}
@@ -0,0 +1,76 @@
+package linting
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	slint "github.com/glennsarti/sentinel-lint/lint"
+	"github.com/glennsarti/sentinel-utils/lib/internal/txtar_fs"
+	parsing "github.com/glennsarti/sentinel-utils/lib/parsing/default"
+	cwalker "github.com/glennsarti/sentinel-utils/lib/walkers/sentinel_config"
+	"golang.org/x/tools/txtar"
+)
+
+type countingResultCache struct {
+	entries map[string]slint.Issues
+	hits    int
+}
+
+func (c *countingResultCache) Get(key string) (slint.Issues, bool) {
+	issues, ok := c.entries[key]
+	if ok {
+		c.hits++
+	}
+	return issues, ok
+}
+
+func (c *countingResultCache) Put(key string, issues slint.Issues) error {
+	c.entries[key] = issues
+	return nil
+}
+
+// The override points the import at a module which the walker does not visit
+const cachedModuleArchive = `-- sentinel.hcl --
+import "module" "helpers" {
+  source = "./modules/helpers.sentinel"
+}
+
+policy "p" {
+  source = "./policies/p.sentinel"
+}
+-- override.hcl --
+import "module" "helpers" {
+  source = "./modules/overridden.sentinel"
+}
+-- modules/helpers.sentinel --
+ok = true
+-- modules/overridden.sentinel --
+ok = %s
+-- policies/p.sentinel --
+import "helpers"
+
+main = rule { helpers.ok }
+`
+
+func TestResultCacheModuleChanged(t *testing.T) {
+	cache := &countingResultCache{entries: make(map[string]slint.Issues)}
+	lint := func(moduleValue string) int {
+		arcfs := txtar_fs.NewTxtarFileSystem(txtar.Parse([]byte(fmt.Sprintf(cachedModuleArchive, moduleValue))))
+		pf := parsing.NewDefaultParsingFactory(arcfs)
+		w := cwalker.NewSentinelConfigWalker(arcfs, "/", "", pf)
+		cache.hits = 0
+		if err := Lint(context.Background(), w, pf, func(slint.File, slint.Issues) {}, WithResultCache(cache)); err != nil {
+			t.Fatal(err)
+		}
+		return cache.hits
+	}
+
+	lint("true")
+	if hits := lint("true"); hits == 0 {
+		t.Fatal("expected the unchanged policy set to use the cache")
+	}
+	if hits := lint("false"); hits != 0 {
+		t.Errorf("expected no results to be used after the module changed but got %d", hits)
+	}
+}
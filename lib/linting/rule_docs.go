@@ -0,0 +1,29 @@
+package linting
+
+import (
+	slint "github.com/glennsarti/sentinel-lint/lint"
+)
+
+var ruleDocumentation = map[string]string{
+	slint.SyntaxErrorRuleID: "The file could not be parsed. Check the Sentinel version, as newer syntax can not be parsed by older versions.",
+	syntaxWarningRuleID:     "The file could be parsed, but the parser found something which may not work as expected.",
+	FileErrorRuleID:         "A file could not be read, or a file which the configuration refers to does not exist.",
+	orphanedFileRuleID: "The file is not used by any policy, module or test in the Sentinel configuration. " +
+		"Test directories must be named after the policy they test, in a test directory next to the policy.",
+	UselessOverrideRuleID: "A block in an override file does not set anything, so it has no effect and can be removed.",
+	deprecatedFeatureRuleID: "The configuration uses a feature which is deprecated in the Sentinel version. " +
+		"It still works, but should be replaced with the newer syntax.",
+	unsupportedFeatureRuleID:   "The configuration uses a feature which the Sentinel version does not support.",
+	requiresNewerVersionRuleID: "The file uses syntax from a newer version of Sentinel. Use a newer version, or remove the newer syntax.",
+	DuplicateNameRuleID: "Two blocks in the configuration use the same name, which makes it unclear which one is meant. " +
+		"Rename one of the blocks.",
+	AssignmentsAfterRulesRuleID: "A variable is assigned after the rules of the policy. " +
+		"Rules are evaluated lazily, so move the assignment above the first rule to make clear which value they use.",
+}
+
+// RuleDocumentation describes the lint rules which are added when linting a policy set, and
+// the sentinel-lint rules
+func RuleDocumentation(ruleId string) (string, bool) {
+	doc, ok := ruleDocumentation[ruleId]
+	return doc, ok
+}
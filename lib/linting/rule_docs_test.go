@@ -0,0 +1,20 @@
+package linting
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRuleDocumentation(t *testing.T) {
+	doc, ok := RuleDocumentation("Lint/DuplicateName")
+	if !ok {
+		t.Fatal("expected a sentinel-lint rule to be documented")
+	}
+	if !strings.Contains(doc, "same name") {
+		t.Errorf("expected the documentation of the rule but got %q", doc)
+	}
+
+	if _, ok := RuleDocumentation("Lint/Unknown"); ok {
+		t.Error("expected an unknown rule not to be documented")
+	}
+}
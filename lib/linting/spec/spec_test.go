@@ -1,14 +1,17 @@
 package spec
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path"
 	"slices"
 	"strings"
+	"sync"
 	"testing"
 
 	slint "github.com/glennsarti/sentinel-lint/lint"
+	"github.com/glennsarti/sentinel-parser/filetypes"
 	"github.com/glennsarti/sentinel-parser/position"
 	"github.com/google/go-cmp/cmp"
 
@@ -65,35 +68,73 @@ func testSpecFile(filename, parentPath, sentinelVersion string, t *testing.T) er
 
 	arcfs := txtar_fs.NewTxtarFileSystem(arc.raw)
 	pf := parsing.NewDefaultParsingFactory(arcfs)
-	w := cwalker.NewSentinelConfigWalker(arcfs, "/", sentinelVersion, pf)
+	opts := make([]cwalker.WalkerOption, 0)
+	if strings.HasPrefix(filename, "resilient_") {
+		opts = append(opts, cwalker.WithContinueOnError())
+	}
+	w := cwalker.NewSentinelConfigWalker(arcfs, "/", sentinelVersion, pf, opts...)
 	if w == nil {
 		return fmt.Errorf("Failed to create walker")
 	}
+	if strings.HasPrefix(filename, "orphans_") {
+		w = cwalker.NewOrphanedFileWalker(w, pf)
+	}
 
-	visited := make(map[string]slint.Issues, 0)
+	lintOpts := make([]subject.LintOption, 0)
+	if strings.HasPrefix(filename, "parallel_") {
+		lintOpts = append(lintOpts, subject.WithJobs(4))
+	}
+	if strings.HasPrefix(filename, "required_version_") {
+		lintOpts = append(lintOpts, subject.WithRequiredVersionCheck(""))
+	}
+	// Cached results must be the same as the original results
+	runs := 1
+	cache := &memoryResultCache{entries: make(map[string]slint.Issues, 0)}
+	if strings.HasPrefix(filename, "cached_") {
+		lintOpts = append(lintOpts, subject.WithResultCache(cache))
+		runs = 2
+	}
 
-	err = subject.Lint(w, pf, func(lintFile slint.File, parsingIssues slint.Issues) {
-		if val, ok := visited[lintFile.Path()]; !ok {
-			visited[lintFile.Path()] = parsingIssues
-		} else {
-			visited[lintFile.Path()] = append(val, parsingIssues...)
+	for run := range runs {
+		visited := make(map[string]slint.Issues, 0)
+		// Files which were linted are yielded once, with all of their issues
+		linted := make(map[string]int, 0)
+
+		err = subject.Lint(context.Background(), w, pf, func(lintFile slint.File, parsingIssues slint.Issues) {
+			if lintFile.Type() != filetypes.UnknownFileType {
+				linted[lintFile.Path()]++
+			}
+			if val, ok := visited[lintFile.Path()]; !ok {
+				visited[lintFile.Path()] = parsingIssues
+			} else {
+				visited[lintFile.Path()] = append(val, parsingIssues...)
+			}
+		}, lintOpts...)
+		if err != nil {
+			return err
 		}
-	})
-	if err != nil {
-		return err
-	}
 
-	inspectedStrings := make([]string, 0)
-	for _, key := range helpers.SortedKeys(visited) {
-		val := visited[key]
-		inspectedStrings = append(inspectedStrings, inspectIssues(key, val)...)
-	}
-	slices.Sort(inspectedStrings)
+		for _, key := range helpers.SortedKeys(linted) {
+			if linted[key] > 1 {
+				t.Errorf("run %d: %s was yielded %d times", run+1, key, linted[key])
+			}
+		}
+
+		inspectedStrings := make([]string, 0)
+		for _, key := range helpers.SortedKeys(visited) {
+			val := visited[key]
+			inspectedStrings = append(inspectedStrings, inspectIssues(key, val)...)
+		}
+		slices.Sort(inspectedStrings)
 
-	expectedString := string(arc.DiagnosticFile.Data)
-	actualString := strings.Join(inspectedStrings, "\n") + "\n"
-	if diff := cmp.Diff(expectedString, actualString); diff != "" {
-		t.Fatal(diff)
+		expectedString := string(arc.DiagnosticFile.Data)
+		actualString := strings.Join(inspectedStrings, "\n") + "\n"
+		if diff := cmp.Diff(expectedString, actualString); diff != "" {
+			t.Fatalf("run %d: %s", run+1, diff)
+		}
+	}
+	if runs > 1 && cache.hits == 0 {
+		t.Fatal("expected results to be used from the cache")
 	}
 
 	return nil
@@ -125,3 +166,26 @@ func rangeToString(r *position.SourceRange) string {
 	}
 	return fmt.Sprintf("%d:%d-%d:%d", r.Start.Line, r.Start.Column, r.End.Line, r.End.Column)
 }
+
+type memoryResultCache struct {
+	mu      sync.Mutex
+	entries map[string]slint.Issues
+	hits    int
+}
+
+func (c *memoryResultCache) Get(key string) (slint.Issues, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	issues, ok := c.entries[key]
+	if ok {
+		c.hits++
+	}
+	return issues, ok
+}
+
+func (c *memoryResultCache) Put(key string, issues slint.Issues) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = issues
+	return nil
+}
@@ -0,0 +1,87 @@
+package linting
+
+import (
+	"bytes"
+	"io/fs"
+	"slices"
+	"strings"
+	"sync"
+
+	slint "github.com/glennsarti/sentinel-lint/lint"
+	"github.com/glennsarti/sentinel-utils/lib/filesystem"
+)
+
+// SuppressionDirective starts a comment which stops the listed rules from reporting issues
+// on the line of the comment, or on the line after it. For example:
+//
+//	# sentinel-lint:ignore Lint/UselessOverride
+const SuppressionDirective = "sentinel-lint:ignore"
+
+// SuppressionComment is the comment which suppresses the rule
+func SuppressionComment(commentMarker, ruleId string) string {
+	return commentMarker + " " + SuppressionDirective + " " + ruleId
+}
+
+// Removes the issues which are suppressed by comments in the files they are yielded for
+func withSuppressions(fsys filesystem.FS, yield LintIssueYielder) LintIssueYielder {
+	var mu sync.Mutex
+	lines := make(map[string][][]byte)
+
+	fileLines := func(filePath string) [][]byte {
+		mu.Lock()
+		defer mu.Unlock()
+		if l, ok := lines[filePath]; ok {
+			return l
+		}
+		// Files which can not be read have nothing suppressed
+		var l [][]byte
+		if content, err := fs.ReadFile(fsys, filePath); err == nil && bytes.Contains(content, []byte(SuppressionDirective)) {
+			l = bytes.Split(content, []byte("\n"))
+		}
+		lines[filePath] = l
+		return l
+	}
+
+	return func(lintFile slint.File, issues slint.Issues) {
+		l := fileLines(lintFile.Path())
+		if l == nil {
+			yield(lintFile, issues)
+			return
+		}
+		kept := make(slint.Issues, 0, len(issues))
+		for _, issue := range issues {
+			if issue == nil || issue.Range == nil || !isSuppressed(l, issue.Range.Start.Line, issue.RuleId) {
+				kept = append(kept, issue)
+			}
+		}
+		yield(lintFile, kept)
+	}
+}
+
+func isSuppressed(lines [][]byte, line int, ruleId string) bool {
+	for _, l := range []int{line, line - 1} {
+		if l < 0 || l >= len(lines) {
+			continue
+		}
+		if rules, ok := SuppressedRules(string(lines[l])); ok && slices.Contains(rules, ruleId) {
+			return true
+		}
+	}
+	return false
+}
+
+// SuppressedRules are the rules listed by the directive in a comment on the line, and
+// whether the line has the directive
+func SuppressedRules(line string) ([]string, bool) {
+	idx := strings.Index(line, SuppressionDirective)
+	if idx < 0 {
+		return nil, false
+	}
+	before := strings.TrimRight(line[:idx], " \t")
+	if !strings.HasSuffix(before, "#") && !strings.HasSuffix(before, "//") {
+		return nil, false
+	}
+	return strings.FieldsFunc(line[idx+len(SuppressionDirective):], func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t' || r == '\r'
+	}), true
+}
@@ -2,14 +2,32 @@ package linting
 
 import (
 	"fmt"
+	"strings"
 
 	slint "github.com/glennsarti/sentinel-lint/lint"
 	"github.com/glennsarti/sentinel-parser/filetypes"
 	"github.com/glennsarti/sentinel-parser/position"
+	"github.com/glennsarti/sentinel-utils/lib/filesystem"
 )
 
 var _ slint.File = unknownFile{}
 
+// The rules of sentinel-lint which are referred to by ID
+// TODO: Should be constantised from sentinel-lint
+const (
+	FileErrorRuleID             = "FileSystem/Error"
+	UselessOverrideRuleID       = "Lint/UselessOverride"
+	DuplicateNameRuleID         = "Lint/DuplicateName"
+	AssignmentsAfterRulesRuleID = "Lint/AssignmentsAfterRules"
+)
+
+// The rules of the issues which are found while walking the policy set
+const (
+	syntaxWarningRuleID        = "Syntax/Warning"
+	orphanedFileRuleID         = "FileSystem/OrphanedFile"
+	requiresNewerVersionRuleID = "Sentinel/RequiresNewerVersion"
+)
+
 func newUnknownFile(path string) slint.File {
 	return unknownFile{path: path}
 }
@@ -29,9 +47,86 @@ func (uf unknownFile) Path() string {
 func newFileNotExistIssue(filePath string, src *position.SourceRange) *slint.Issue {
 	return &slint.Issue{
 		Severity: slint.Error,
-		RuleId:   "FileSystem/Error", // TODO: Should be constantised from sentinel-lint
+		RuleId:   FileErrorRuleID,
 		Summary:  "File does not exist",
 		Detail:   fmt.Sprintf("File %q does not exist", filePath),
 		Range:    src,
 	}
 }
+
+// MissingFileHandler is given each issue about a referenced file which does not exist, and
+// the path of the file
+type MissingFileHandler func(issue *slint.Issue, filePath string)
+
+// WithMissingFiles calls the handler for each issue about a missing file, before the issue
+// is yielded
+func WithMissingFiles(handler MissingFileHandler) LintOption {
+	return func(w *lintWalker) {
+		w.missingFileHandler = handler
+	}
+}
+
+func newOrphanedFileIssue(filePath string, fsys filesystem.FS) *slint.Issue {
+	detail := fmt.Sprintf("File %q is not referenced by the Sentinel configuration", filePath)
+
+	testDir := fsys.ParentPath(filePath)
+	if fsys.BasePath(fsys.ParentPath(testDir)) == "test" {
+		detail = fmt.Sprintf("The test directory %q does not match the name of any policy", testDir)
+	} else if strings.HasSuffix(filePath, ".sentinel") {
+		detail = fmt.Sprintf("File %q is not used by any policy or import in the Sentinel configuration", filePath)
+	}
+
+	return &slint.Issue{
+		Severity: slint.Warning,
+		RuleId:   orphanedFileRuleID,
+		Summary:  "File is not referenced",
+		Detail:   detail,
+		Range:    startOfFileRange(filePath),
+	}
+}
+
+func newFileErrorIssue(filePath string, err error) *slint.Issue {
+	return &slint.Issue{
+		Severity: slint.Error,
+		RuleId:   FileErrorRuleID,
+		Summary:  "File could not be processed",
+		Detail:   err.Error(),
+		Range:    startOfFileRange(filePath),
+	}
+}
+
+func startOfFileRange(filePath string) *position.SourceRange {
+	return &position.SourceRange{
+		Filename: filePath,
+		Start:    position.SourcePos{Line: 0, Column: 0, Byte: 0},
+		End:      position.SourcePos{Line: 0, Column: 0, Byte: 0},
+	}
+}
+
+func newRequiredVersionIssue(filePath, version, required, source string, possible []string, issues slint.Issues) *slint.Issue {
+	detail := fmt.Sprintf("The file can not be parsed by Sentinel %s", version)
+	if source != "" {
+		detail += fmt.Sprintf(" (%s)", source)
+	}
+	detail += fmt.Sprintf(", but can be by Sentinel %s and later.", required)
+	if len(possible) > 0 {
+		detail += fmt.Sprintf(" Check for the use of %s.", joinFeatures(possible))
+	}
+
+	// Point at the first syntax error, as that is where the newer feature is
+	rng := startOfFileRange(filePath)
+	for _, issue := range issues {
+		if issue != nil && issue.RuleId == slint.SyntaxErrorRuleID && issue.Range != nil {
+			rng = issue.Range
+			break
+		}
+	}
+
+	return &slint.Issue{
+		Severity: slint.Error,
+		RuleId:   requiresNewerVersionRuleID,
+		Summary:  fmt.Sprintf("Requires Sentinel %s or later", required),
+		Detail:   detail,
+		Range:    rng,
+	}
+}
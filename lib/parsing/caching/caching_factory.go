@@ -0,0 +1,198 @@
+package parsing
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"io/fs"
+	"sync"
+
+	"github.com/glennsarti/sentinel-parser/diagnostics"
+	sast "github.com/glennsarti/sentinel-parser/sentinel/ast"
+	scast "github.com/glennsarti/sentinel-parser/sentinel_config/ast"
+	"github.com/glennsarti/sentinel-utils/lib/filesystem"
+
+	"github.com/glennsarti/sentinel-utils/lib/parsing"
+)
+
+// DefaultMaxSize is the default total size, in bytes, of the source of the files in the cache
+const DefaultMaxSize = 64 * 1024 * 1024
+
+var _ parsing.CachingFactory = &cachingParsingFactory{}
+
+type CachingOption func(*cachingParsingFactory)
+
+// WithMaxSize limits the total size, in bytes, of the source of the files in the cache.
+// The least recently used files are removed first.
+func WithMaxSize(size int) CachingOption {
+	return func(cpf *cachingParsingFactory) {
+		cpf.maxSize = size
+	}
+}
+
+// NewCachingParsingFactory wraps a factory so that files are only parsed again when their path,
+// content or Sentinel version changes. The parsed files are shared, and must not be modified.
+func NewCachingParsingFactory(fsys filesystem.FS, pf parsing.Factory, opts ...CachingOption) parsing.CachingFactory {
+	cpf := &cachingParsingFactory{
+		fsys:    fsys,
+		parsing: pf,
+		maxSize: DefaultMaxSize,
+		entries: make(map[cacheKey]*list.Element, 0),
+		lru:     list.New(),
+	}
+	for _, opt := range opts {
+		opt(cpf)
+	}
+	return cpf
+}
+
+type cacheKind int
+
+const (
+	sentinelFileKind cacheKind = iota
+	sentinelConfigFileKind
+)
+
+type cacheKey struct {
+	kind            cacheKind
+	path            string
+	contentHash     [sha256.Size]byte
+	sentinelVersion string
+}
+
+type cacheEntry struct {
+	key   cacheKey
+	size  int
+	file  any
+	diags diagnostics.Diagnostics
+}
+
+type cachingParsingFactory struct {
+	fsys    filesystem.FS
+	parsing parsing.Factory
+	maxSize int
+
+	mu      sync.Mutex
+	size    int
+	entries map[cacheKey]*list.Element
+	// Most recently used at the front
+	lru *list.List
+}
+
+func (cpf *cachingParsingFactory) ParseSentinelFile(ctx context.Context, file *filesystem.File, sentinelVersion string) (*sast.File, diagnostics.Diagnostics, error) {
+	key, err := cpf.keyFor(sentinelFileKind, file, sentinelVersion)
+	if err != nil {
+		return nil, nil, err
+	}
+	if entry := cpf.get(key); entry != nil {
+		parsed, _ := entry.file.(*sast.File)
+		return parsed, entry.diags, nil
+	}
+
+	parsed, diags, err := cpf.parsing.ParseSentinelFile(ctx, file, sentinelVersion)
+	if err != nil {
+		return parsed, diags, err
+	}
+	cpf.add(key, len(*file.Content), parsed, diags)
+	return parsed, diags, nil
+}
+
+func (cpf *cachingParsingFactory) ParseSentinelConfigFile(ctx context.Context, file *filesystem.File, sentinelVersion string) (*scast.File, diagnostics.Diagnostics, error) {
+	key, err := cpf.keyFor(sentinelConfigFileKind, file, sentinelVersion)
+	if err != nil {
+		return nil, nil, err
+	}
+	if entry := cpf.get(key); entry != nil {
+		cfg, _ := entry.file.(*scast.File)
+		return cfg, entry.diags, nil
+	}
+
+	cfg, diags, err := cpf.parsing.ParseSentinelConfigFile(ctx, file, sentinelVersion)
+	if err != nil {
+		return cfg, diags, err
+	}
+	cpf.add(key, len(*file.Content), cfg, diags)
+	return cfg, diags, nil
+}
+
+func (cpf *cachingParsingFactory) Invalidate(path string) {
+	cpf.mu.Lock()
+	defer cpf.mu.Unlock()
+
+	for key, item := range cpf.entries {
+		if key.path == path {
+			cpf.remove(item)
+		}
+	}
+}
+
+func (cpf *cachingParsingFactory) Clear() {
+	cpf.mu.Lock()
+	defer cpf.mu.Unlock()
+
+	cpf.entries = make(map[cacheKey]*list.Element, 0)
+	cpf.lru.Init()
+	cpf.size = 0
+}
+
+func (cpf *cachingParsingFactory) keyFor(kind cacheKind, file *filesystem.File, sentinelVersion string) (cacheKey, error) {
+	if file.Content == nil {
+		// Read it
+		content, err := fs.ReadFile(cpf.fsys, file.Path)
+		if err != nil {
+			return cacheKey{}, err
+		}
+		file.Content = &content
+	}
+
+	return cacheKey{
+		kind:            kind,
+		path:            file.Path,
+		contentHash:     sha256.Sum256(*file.Content),
+		sentinelVersion: sentinelVersion,
+	}, nil
+}
+
+func (cpf *cachingParsingFactory) get(key cacheKey) *cacheEntry {
+	cpf.mu.Lock()
+	defer cpf.mu.Unlock()
+
+	item, ok := cpf.entries[key]
+	if !ok {
+		return nil
+	}
+	cpf.lru.MoveToFront(item)
+	return item.Value.(*cacheEntry)
+}
+
+func (cpf *cachingParsingFactory) add(key cacheKey, size int, file any, diags diagnostics.Diagnostics) {
+	cpf.mu.Lock()
+	defer cpf.mu.Unlock()
+
+	// Files larger than the cache are never kept
+	if size > cpf.maxSize {
+		return
+	}
+	if item, ok := cpf.entries[key]; ok {
+		cpf.remove(item)
+	}
+
+	cpf.entries[key] = cpf.lru.PushFront(&cacheEntry{
+		key:   key,
+		size:  size,
+		file:  file,
+		diags: diags,
+	})
+	cpf.size += size
+
+	for cpf.size > cpf.maxSize {
+		cpf.remove(cpf.lru.Back())
+	}
+}
+
+// Must be called with the lock held
+func (cpf *cachingParsingFactory) remove(item *list.Element) {
+	entry := cpf.lru.Remove(item).(*cacheEntry)
+	delete(cpf.entries, entry.key)
+	cpf.size -= entry.size
+}
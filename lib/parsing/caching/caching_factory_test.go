@@ -0,0 +1,110 @@
+package parsing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/glennsarti/sentinel-parser/diagnostics"
+	"github.com/glennsarti/sentinel-parser/features"
+	sast "github.com/glennsarti/sentinel-parser/sentinel/ast"
+	scast "github.com/glennsarti/sentinel-parser/sentinel_config/ast"
+	"golang.org/x/tools/txtar"
+
+	"github.com/glennsarti/sentinel-utils/lib/filesystem"
+	"github.com/glennsarti/sentinel-utils/lib/internal/txtar_fs"
+	"github.com/glennsarti/sentinel-utils/lib/parsing"
+	defaultParsing "github.com/glennsarti/sentinel-utils/lib/parsing/default"
+)
+
+type countingFactory struct {
+	parsing.Factory
+	parsed int
+}
+
+func (cf *countingFactory) ParseSentinelFile(ctx context.Context, file *filesystem.File, sentinelVersion string) (*sast.File, diagnostics.Diagnostics, error) {
+	cf.parsed++
+	return cf.Factory.ParseSentinelFile(ctx, file, sentinelVersion)
+}
+
+func (cf *countingFactory) ParseSentinelConfigFile(ctx context.Context, file *filesystem.File, sentinelVersion string) (*scast.File, diagnostics.Diagnostics, error) {
+	cf.parsed++
+	return cf.Factory.ParseSentinelConfigFile(ctx, file, sentinelVersion)
+}
+
+func TestCachingParsingFactory(t *testing.T) {
+	fsys := txtar_fs.NewTxtarFileSystem(txtar.Parse([]byte(`
+-- policy.sentinel --
+main = rule { true }
+-- sentinel.hcl --
+policy "policy" {
+  source = "./policy.sentinel"
+}
+`)))
+	ctx := context.Background()
+
+	newFile := func(path string, content string) *filesystem.File {
+		f := &filesystem.File{Path: path}
+		if content != "" {
+			b := []byte(content)
+			f.Content = &b
+		}
+		return f
+	}
+
+	t.Run("parses unchanged files once", func(t *testing.T) {
+		counter := &countingFactory{Factory: defaultParsing.NewDefaultParsingFactory(fsys)}
+		subject := NewCachingParsingFactory(fsys, counter)
+
+		for range 3 {
+			if _, _, err := subject.ParseSentinelFile(ctx, newFile("/policy.sentinel", ""), features.LatestSentinelVersion); err != nil {
+				t.Fatal(err)
+			}
+			if _, _, err := subject.ParseSentinelConfigFile(ctx, newFile("/sentinel.hcl", ""), features.LatestSentinelVersion); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if counter.parsed != 2 {
+			t.Errorf("expected 2 parses but got %d", counter.parsed)
+		}
+	})
+
+	t.Run("parses again when the content or version changes", func(t *testing.T) {
+		counter := &countingFactory{Factory: defaultParsing.NewDefaultParsingFactory(fsys)}
+		subject := NewCachingParsingFactory(fsys, counter)
+
+		_, _, _ = subject.ParseSentinelFile(ctx, newFile("/policy.sentinel", ""), features.LatestSentinelVersion)
+		_, _, _ = subject.ParseSentinelFile(ctx, newFile("/policy.sentinel", "main = rule { false }"), features.LatestSentinelVersion)
+		_, _, _ = subject.ParseSentinelFile(ctx, newFile("/policy.sentinel", ""), features.SentinelVersions[1])
+		if counter.parsed != 3 {
+			t.Errorf("expected 3 parses but got %d", counter.parsed)
+		}
+	})
+
+	t.Run("parses again after invalidating", func(t *testing.T) {
+		counter := &countingFactory{Factory: defaultParsing.NewDefaultParsingFactory(fsys)}
+		subject := NewCachingParsingFactory(fsys, counter)
+
+		_, _, _ = subject.ParseSentinelFile(ctx, newFile("/policy.sentinel", ""), features.LatestSentinelVersion)
+		subject.Invalidate("/policy.sentinel")
+		_, _, _ = subject.ParseSentinelFile(ctx, newFile("/policy.sentinel", ""), features.LatestSentinelVersion)
+		subject.Clear()
+		_, _, _ = subject.ParseSentinelFile(ctx, newFile("/policy.sentinel", ""), features.LatestSentinelVersion)
+		if counter.parsed != 3 {
+			t.Errorf("expected 3 parses but got %d", counter.parsed)
+		}
+	})
+
+	t.Run("removes the least recently used files", func(t *testing.T) {
+		counter := &countingFactory{Factory: defaultParsing.NewDefaultParsingFactory(fsys)}
+		// Only big enough for one of the files
+		subject := NewCachingParsingFactory(fsys, counter, WithMaxSize(60))
+
+		_, _, _ = subject.ParseSentinelFile(ctx, newFile("/policy.sentinel", ""), features.LatestSentinelVersion)
+		_, _, _ = subject.ParseSentinelConfigFile(ctx, newFile("/sentinel.hcl", ""), features.LatestSentinelVersion)
+		_, _, _ = subject.ParseSentinelConfigFile(ctx, newFile("/sentinel.hcl", ""), features.LatestSentinelVersion)
+		_, _, _ = subject.ParseSentinelFile(ctx, newFile("/policy.sentinel", ""), features.LatestSentinelVersion)
+		if counter.parsed != 3 {
+			t.Errorf("expected 3 parses but got %d", counter.parsed)
+		}
+	})
+}
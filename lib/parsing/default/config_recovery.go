@@ -0,0 +1,101 @@
+package parsing
+
+import (
+	"strings"
+
+	scast "github.com/glennsarti/sentinel-parser/sentinel_config/ast"
+	scparser "github.com/glennsarti/sentinel-parser/sentinel_config/parser"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+)
+
+// recoverConfigFile attempts to salvage a configuration file which failed to parse by
+// parsing each top level block on its own. Blocks which are valid are kept and the rest
+// are discarded. Returns nil if nothing could be recovered.
+func recoverConfigFile(filename string, src []byte, sentinelVersion string) *scast.File {
+	// Only HCL syntax can be split into blocks
+	if strings.HasSuffix(filename, ".json") {
+		return nil
+	}
+
+	f, _ := hclsyntax.ParseConfig(src, filename, hcl.InitialPos)
+	if f == nil {
+		return nil
+	}
+	body, ok := f.Body.(*hclsyntax.Body)
+	if !ok || len(body.Blocks) == 0 {
+		return nil
+	}
+
+	result := scast.NewFile()
+	recovered := false
+	for _, block := range body.Blocks {
+		// Parsers cache files by name, so each block needs a new parser
+		p, err := scparser.New(sentinelVersion)
+		if err != nil {
+			return nil
+		}
+
+		r := block.Range()
+		cfg, diags := p.ParseFile(filename, isolateRange(src, r.Start.Byte, r.End.Byte))
+		if cfg == nil || diags.HasErrors() {
+			continue
+		}
+		mergeRecoveredFile(result, cfg)
+		recovered = true
+	}
+
+	if !recovered {
+		return nil
+	}
+	return result
+}
+
+// isolateRange blanks out everything except the range, keeping line endings so that
+// positions within the range are the same as in the original source.
+func isolateRange(src []byte, start, end int) []byte {
+	result := make([]byte, len(src))
+	for idx, b := range src {
+		if (idx >= start && idx < end) || b == '\n' || b == '\r' {
+			result[idx] = b
+		} else {
+			result[idx] = ' '
+		}
+	}
+	return result
+}
+
+// The first definition of a block wins
+func mergeRecoveredFile(this, other *scast.File) {
+	for name, item := range other.Globals {
+		if _, ok := this.Globals[name]; !ok {
+			this.Globals[name] = item
+		}
+	}
+	for name, item := range other.Imports {
+		if _, ok := this.Imports[name]; !ok {
+			this.Imports[name] = item
+		}
+	}
+	for name, item := range other.Mocks {
+		if _, ok := this.Mocks[name]; !ok {
+			this.Mocks[name] = item
+		}
+	}
+	for name, item := range other.Params {
+		if _, ok := this.Params[name]; !ok {
+			this.Params[name] = item
+		}
+	}
+	for name, item := range other.Policies {
+		if _, ok := this.Policies[name]; !ok {
+			this.Policies[name] = item
+		}
+	}
+	if this.SentinelOptions == nil {
+		this.SentinelOptions = other.SentinelOptions
+	}
+	if this.Test == nil {
+		this.Test = other.Test
+	}
+}
@@ -1,6 +1,7 @@
 package parsing
 
 import (
+	"context"
 	"io/fs"
 
 	"github.com/glennsarti/sentinel-parser/diagnostics"
@@ -9,6 +10,7 @@ import (
 	scast "github.com/glennsarti/sentinel-parser/sentinel_config/ast"
 	scparser "github.com/glennsarti/sentinel-parser/sentinel_config/parser"
 	"github.com/glennsarti/sentinel-utils/lib/filesystem"
+	"github.com/glennsarti/sentinel-utils/lib/internal/helpers"
 
 	"github.com/glennsarti/sentinel-utils/lib/parsing"
 )
@@ -25,7 +27,15 @@ type defaultParsingFactory struct {
 	fsys filesystem.FS
 }
 
-func (dpf defaultParsingFactory) ParseSentinelFile(file *filesystem.File, sentinelVersion string) (*sast.File, diagnostics.Diagnostics, error) {
+type parseResult[T any] struct {
+	file  T
+	diags diagnostics.Diagnostics
+}
+
+func (dpf defaultParsingFactory) ParseSentinelFile(ctx context.Context, file *filesystem.File, sentinelVersion string) (*sast.File, diagnostics.Diagnostics, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
 	if file.Content == nil {
 		// Read it
 		content, err := fs.ReadFile(dpf.fsys, file.Path)
@@ -40,11 +50,18 @@ func (dpf defaultParsingFactory) ParseSentinelFile(file *filesystem.File, sentin
 	// }
 
 	// Parse it
-	parsed, _, diags, err := sparser.ParseFile(sentinelVersion, file.Path, *file.Content)
-	return parsed, diags, err
+	content := *file.Content
+	r, err := helpers.RunWithContext(ctx, func() (parseResult[*sast.File], error) {
+		parsed, _, diags, err := sparser.ParseFile(sentinelVersion, file.Path, content)
+		return parseResult[*sast.File]{file: parsed, diags: diags}, err
+	})
+	return r.file, r.diags, err
 }
 
-func (dpf defaultParsingFactory) ParseSentinelConfigFile(file *filesystem.File, sentinelVersion string) (*scast.File, diagnostics.Diagnostics, error) {
+func (dpf defaultParsingFactory) ParseSentinelConfigFile(ctx context.Context, file *filesystem.File, sentinelVersion string) (*scast.File, diagnostics.Diagnostics, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
 	if file.Content == nil {
 		// Read it
 		content, err := fs.ReadFile(dpf.fsys, file.Path)
@@ -70,6 +87,13 @@ func (dpf defaultParsingFactory) ParseSentinelConfigFile(file *filesystem.File,
 		return nil, nil, err
 	}
 
-	cfg, diags := p.ParseFile(file.Path, *file.Content)
-	return cfg, diags, nil
+	content := *file.Content
+	r, err := helpers.RunWithContext(ctx, func() (parseResult[*scast.File], error) {
+		cfg, diags := p.ParseFile(file.Path, content)
+		if cfg == nil && diags.HasErrors() {
+			cfg = recoverConfigFile(file.Path, content, sentinelVersion)
+		}
+		return parseResult[*scast.File]{file: cfg, diags: diags}, nil
+	})
+	return r.file, r.diags, err
 }
@@ -1,13 +1,27 @@
 package parsing
 
 import (
+	"context"
+
 	"github.com/glennsarti/sentinel-parser/diagnostics"
 	sast "github.com/glennsarti/sentinel-parser/sentinel/ast"
 	scast "github.com/glennsarti/sentinel-parser/sentinel_config/ast"
 	"github.com/glennsarti/sentinel-utils/lib/filesystem"
 )
 
+// Factory parses files. If the context is cancelled, or its deadline passes, parsing is
+// abandoned and the context error is returned.
 type Factory interface {
-	ParseSentinelFile(file *filesystem.File, sentinelVersion string) (*sast.File, diagnostics.Diagnostics, error)
-	ParseSentinelConfigFile(file *filesystem.File, sentinelVersion string) (*scast.File, diagnostics.Diagnostics, error)
+	ParseSentinelFile(ctx context.Context, file *filesystem.File, sentinelVersion string) (*sast.File, diagnostics.Diagnostics, error)
+	// If the configuration file has errors, the returned file may contain the parts which could be recovered
+	ParseSentinelConfigFile(ctx context.Context, file *filesystem.File, sentinelVersion string) (*scast.File, diagnostics.Diagnostics, error)
+}
+
+// CachingFactory is a Factory which keeps the files it has parsed
+type CachingFactory interface {
+	Factory
+	// Invalidate removes every cached file for the path
+	Invalidate(path string)
+	// Clear removes every cached file
+	Clear()
 }
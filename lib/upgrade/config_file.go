@@ -0,0 +1,174 @@
+package upgrade
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	slint "github.com/glennsarti/sentinel-lint/lint"
+	"github.com/glennsarti/sentinel-parser/features"
+	"github.com/glennsarti/sentinel-utils/lib/internal/helpers"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+
+	scast "github.com/glennsarti/sentinel-parser/sentinel_config/ast"
+	scparser "github.com/glennsarti/sentinel-parser/sentinel_config/parser"
+)
+
+// upgradeConfigFile rewrites the version 1 module and plugin import blocks as version 2
+// import blocks. A file can not mix both versions, so either every version 1 block is
+// rewritten or none of them are.
+func upgradeConfigFile(filePath string, src []byte, cfg *scast.File, from, to string) ([]byte, slint.Issues) {
+	issues := make(slint.Issues, 0)
+	if features.UnsupportedVersion(to, features.V2ImportBlockMinimumVersion) {
+		return src, issues
+	}
+
+	v1Imports := make([]scast.Import, 0)
+	for _, name := range helpers.SortedKeys(cfg.Imports) {
+		imp := cfg.Imports[name]
+		if features.UnsupportedVersion(from, features.BadStdlibImportNameMinimumVersion) && scparser.IsStdLibName(name) {
+			issues = append(issues, newManualMigrationIssue(filePath, imp.BlockNameRange(),
+				fmt.Sprintf("Import %q has the same name as a standard import", name),
+				fmt.Sprintf("Imports can not use the name of a standard import from Sentinel %s. Rename the import, and update the policies which use it.",
+					features.BadStdlibImportNameMinimumVersion),
+			))
+		}
+
+		switch imp.(type) {
+		case *scast.V1ModuleImport, *scast.V1PluginImport:
+			v1Imports = append(v1Imports, imp)
+		}
+	}
+	if len(v1Imports) == 0 {
+		return src, issues
+	}
+
+	if strings.HasSuffix(filePath, ".json") {
+		for _, imp := range v1Imports {
+			issues = append(issues, newManualMigrationIssue(filePath, imp.Range(),
+				fmt.Sprintf("Import %q uses the version 1 import syntax", imp.BlockName()),
+				"JSON configuration files are not rewritten. Move the import into the \"import\" object, under the \"module\" or \"plugin\" kind.",
+			))
+		}
+		return src, issues
+	}
+
+	// Version 2 plugin imports set environment variables with a map
+	env := make(map[string]map[string]string, 0)
+	convertible := true
+	for _, imp := range v1Imports {
+		plugin, ok := imp.(*scast.V1PluginImport)
+		if !ok {
+			continue
+		}
+		values, invalid := envToMap(plugin.Env)
+		if invalid != "" {
+			convertible = false
+			issues = append(issues, newManualMigrationIssue(filePath, plugin.EnvRange,
+				fmt.Sprintf("Import %q has environment variables which can not be converted", plugin.Name),
+				fmt.Sprintf("The env entry %q is not in the KEY=VALUE form. Change the import to an import \"plugin\" %q block, with env as a map.", invalid, plugin.Name),
+			))
+		}
+		env[plugin.Name] = values
+	}
+	if !convertible {
+		return src, issues
+	}
+
+	// Only the parts which change are replaced, so that the rest of the file keeps its formatting
+	f, d := hclsyntax.ParseConfig(src, filePath, hcl.InitialPos)
+	if d.HasErrors() {
+		return src, append(issues, newManualMigrationIssue(filePath, nil, "File could not be upgraded", d.Error()))
+	}
+	body, ok := f.Body.(*hclsyntax.Body)
+	if !ok {
+		return src, issues
+	}
+
+	edits := make([]textEdit, 0)
+	for _, block := range body.Blocks {
+		if len(block.Labels) != 1 {
+			continue
+		}
+		header := hcl.RangeBetween(block.TypeRange, block.LabelRanges[0])
+
+		switch block.Type {
+		case "module":
+			edits = append(edits, textEdit{header, fmt.Sprintf("import %q %q", scast.V2ImportKindModule, block.Labels[0])})
+		case "import":
+			edits = append(edits, textEdit{header, fmt.Sprintf("import %q %q", scast.V2ImportKindPlugin, block.Labels[0])})
+			if attr, ok := block.Body.Attributes["path"]; ok {
+				edits = append(edits, textEdit{attr.NameRange, "source"})
+			}
+			if attr, ok := block.Body.Attributes["env"]; ok {
+				edits = append(edits, textEdit{attr.Expr.Range(), envExpression(env[block.Labels[0]])})
+			}
+		}
+	}
+	upgraded := applyEdits(src, edits)
+
+	// Only keep the rewrite if the target version can parse it
+	if p, err := scparser.New(to); err != nil {
+		return src, append(issues, newManualMigrationIssue(filePath, nil, "File could not be upgraded", err.Error()))
+	} else if _, d := p.ParseFile(filePath, upgraded); d.HasErrors() {
+		return src, append(issues, newManualMigrationIssue(filePath, nil,
+			"File could not be upgraded",
+			fmt.Sprintf("The rewritten file has errors in Sentinel %s: %s", to, d.Error()),
+		))
+	}
+
+	return upgraded, issues
+}
+
+// Returns the first entry which is not in the KEY=VALUE form, if there is one
+func envToMap(entries []string) (map[string]string, string) {
+	result := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		key, value, ok := strings.Cut(entry, "=")
+		if !ok || key == "" {
+			return nil, entry
+		}
+		result[key] = value
+	}
+	return result, ""
+}
+
+// Renders the environment variables as an HCL object
+func envExpression(values map[string]string) string {
+	items := make([]string, 0, len(values))
+	for _, key := range helpers.SortedKeys(values) {
+		name := key
+		if !hclsyntax.ValidIdentifier(key) {
+			name = string(hclwrite.TokensForValue(cty.StringVal(key)).Bytes())
+		}
+		items = append(items, fmt.Sprintf("%s = %s", name, hclwrite.TokensForValue(cty.StringVal(values[key])).Bytes()))
+	}
+	if len(items) == 0 {
+		return "{}"
+	}
+	return "{ " + strings.Join(items, ", ") + " }"
+}
+
+type textEdit struct {
+	rng  hcl.Range
+	text string
+}
+
+// The edits must not overlap
+func applyEdits(src []byte, edits []textEdit) []byte {
+	slices.SortFunc(edits, func(a, b textEdit) int {
+		return a.rng.Start.Byte - b.rng.Start.Byte
+	})
+
+	result := make([]byte, 0, len(src))
+	last := 0
+	for _, edit := range edits {
+		result = append(result, src[last:edit.rng.Start.Byte]...)
+		result = append(result, edit.text...)
+		last = edit.rng.End.Byte
+	}
+	return append(result, src[last:]...)
+}
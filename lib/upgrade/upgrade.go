@@ -0,0 +1,131 @@
+package upgrade
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+
+	slint "github.com/glennsarti/sentinel-lint/lint"
+	"github.com/glennsarti/sentinel-parser/features"
+	"github.com/glennsarti/sentinel-parser/filetypes"
+	"github.com/glennsarti/sentinel-parser/position"
+	"github.com/glennsarti/sentinel-utils/lib/filesystem"
+	"github.com/glennsarti/sentinel-utils/lib/parsing"
+
+	cwalker "github.com/glennsarti/sentinel-utils/lib/walkers/sentinel_config"
+)
+
+var _ slint.File = FileResult{}
+
+// FileResult is the upgrade of a single file. Issues are the parts of the file which need
+// to be migrated by hand.
+type FileResult struct {
+	FilePath string
+	FileType filetypes.FileType
+	Original []byte
+	Upgraded []byte
+	Issues   slint.Issues
+}
+
+func (fr FileResult) Path() string {
+	return fr.FilePath
+}
+
+func (fr FileResult) Type() filetypes.FileType {
+	return fr.FileType
+}
+
+// Changed is whether the file was rewritten
+func (fr FileResult) Changed() bool {
+	return string(fr.Original) != string(fr.Upgraded)
+}
+
+type Result struct {
+	From string
+	To   string
+	// Only files which changed, or have issues, in the order they were found
+	Files []FileResult
+}
+
+// Upgrade finds the changes needed to use the policy set with a newer Sentinel version. The
+// walker's Sentinel version is the version the policy set currently uses. Nothing is written,
+// the caller decides what to do with the upgraded content.
+func Upgrade(ctx context.Context, walker cwalker.Walker, pf parsing.Factory, target string) (*Result, error) {
+	from := walker.SentinelVersion()
+	ok, to := features.ValidateSentinelVersion(target)
+	if !ok {
+		return nil, fmt.Errorf("%q is not a valid Sentinel version", target)
+	}
+	if to == features.LatestSentinelVersion {
+		to = features.SentinelVersions[0]
+	}
+	if features.UnsupportedVersion(to, from) {
+		return nil, fmt.Errorf("can not upgrade from Sentinel %s to the older version %s", from, to)
+	}
+
+	result := &Result{
+		From:  from,
+		To:    to,
+		Files: make([]FileResult, 0),
+	}
+
+	err := walker.Walk(ctx, func(fileCtx context.Context, file *filesystem.File, _ *position.SourceRange) (bool, error) {
+		switch file.Type {
+		case filetypes.ConfigPrimaryFileType, filetypes.ConfigOverrideFileType, filetypes.ConfigTestFileType:
+		default:
+			// Nothing in the Sentinel language needs to be rewritten
+			return true, nil
+		}
+
+		if file.Content == nil {
+			content, err := fs.ReadFile(walker.FileSystem(), file.Path)
+			if err != nil {
+				return false, err
+			}
+			file.Content = &content
+		}
+
+		cfg, d, err := pf.ParseSentinelConfigFile(fileCtx, file, from)
+		if err != nil {
+			return false, err
+		}
+
+		fr := FileResult{
+			FilePath: file.Path,
+			FileType: file.Type,
+			Original: *file.Content,
+			Upgraded: *file.Content,
+		}
+		if d.HasErrors() || cfg == nil {
+			fr.Issues = slint.Issues{newManualMigrationIssue(file.Path, nil,
+				"File could not be upgraded",
+				fmt.Sprintf("The file has errors in Sentinel %s: %s", from, d.Error()),
+			)}
+		} else {
+			fr.Upgraded, fr.Issues = upgradeConfigFile(file.Path, *file.Content, cfg, from, to)
+		}
+
+		if fr.Changed() || len(fr.Issues) > 0 {
+			result.Files = append(result.Files, fr)
+		}
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func newManualMigrationIssue(filePath string, rng *position.SourceRange, summary, detail string) *slint.Issue {
+	if rng == nil {
+		rng = &position.SourceRange{Filename: filePath}
+	}
+	return &slint.Issue{
+		Severity: slint.Warning,
+		RuleId:   "Upgrade/ManualMigration",
+		Summary:  summary,
+		Detail:   detail,
+		Range:    rng,
+	}
+}
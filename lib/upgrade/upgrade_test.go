@@ -0,0 +1,156 @@
+package upgrade
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/tools/txtar"
+
+	"github.com/glennsarti/sentinel-utils/lib/internal/txtar_fs"
+	parsing "github.com/glennsarti/sentinel-utils/lib/parsing/default"
+	cwalker "github.com/glennsarti/sentinel-utils/lib/walkers/sentinel_config"
+)
+
+func upgradeArchive(t *testing.T, archive, from, to string) *Result {
+	t.Helper()
+
+	fsys := txtar_fs.NewTxtarFileSystem(txtar.Parse([]byte(archive)))
+	pf := parsing.NewDefaultParsingFactory(fsys)
+	walker := cwalker.NewSentinelConfigWalker(fsys, "/", from, pf)
+
+	result, err := Upgrade(context.Background(), walker, pf, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func TestUpgradeV1Imports(t *testing.T) {
+	result := upgradeArchive(t, `
+-- sentinel.hcl --
+# Shared helpers
+module "helpers" {
+  source = "./helpers.sentinel"
+}
+
+import "tfplan" {
+  path = "/plugins/tfplan"
+  args = ["-v"]
+  env  = ["MODE=strict"]
+}
+
+policy "policy" {
+  source            = "./policy.sentinel"
+  enforcement_level = "advisory"
+}
+-- helpers.sentinel --
+ok = true
+-- policy.sentinel --
+main = rule { true }
+`, "v0.18.13", "v0.19.0")
+
+	if len(result.Files) != 1 {
+		t.Fatalf("expected 1 upgraded file but got %d", len(result.Files))
+	}
+	fr := result.Files[0]
+	if len(fr.Issues) != 0 {
+		t.Errorf("expected no issues but got %v", fr.Issues)
+	}
+
+	expected := `# Shared helpers
+import "module" "helpers" {
+  source = "./helpers.sentinel"
+}
+
+import "plugin" "tfplan" {
+  source = "/plugins/tfplan"
+  args = ["-v"]
+  env  = { MODE = "strict" }
+}
+
+policy "policy" {
+  source            = "./policy.sentinel"
+  enforcement_level = "advisory"
+}
+`
+	if diff := cmp.Diff(expected, string(fr.Upgraded)); diff != "" {
+		t.Error(diff)
+	}
+
+	expectedDiff := `--- /sentinel.hcl
++++ /sentinel.hcl
+@@ -1,12 +1,12 @@
+ # Shared helpers
+-module "helpers" {
++import "module" "helpers" {
+   source = "./helpers.sentinel"
+ }
+ 
+-import "tfplan" {
+-  path = "/plugins/tfplan"
++import "plugin" "tfplan" {
++  source = "/plugins/tfplan"
+   args = ["-v"]
+-  env  = ["MODE=strict"]
++  env  = { MODE = "strict" }
+ }
+ 
+ policy "policy" {
+`
+	if diff := cmp.Diff(expectedDiff, fr.Diff()); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestUpgradeManualMigration(t *testing.T) {
+	result := upgradeArchive(t, `
+-- sentinel.hcl --
+import "time" {
+  path = "/plugins/time"
+  env  = ["NOT_A_PAIR"]
+}
+`, "v0.18.13", "v0.19.0")
+
+	if len(result.Files) != 1 {
+		t.Fatalf("expected 1 file but got %d", len(result.Files))
+	}
+	fr := result.Files[0]
+	if fr.Changed() {
+		t.Errorf("expected the file to be unchanged but got %s", fr.Upgraded)
+	}
+
+	summaries := make([]string, 0)
+	for _, issue := range fr.Issues {
+		summaries = append(summaries, issue.Summary)
+	}
+	expected := []string{
+		`Import "time" has the same name as a standard import`,
+		`Import "time" has environment variables which can not be converted`,
+	}
+	if diff := cmp.Diff(expected, summaries); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestUpgradeVersions(t *testing.T) {
+	archive := `
+-- sentinel.hcl --
+module "helpers" {
+  source = "./helpers.sentinel"
+}
+-- helpers.sentinel --
+ok = true
+`
+	// The version 2 import syntax is not available yet
+	if result := upgradeArchive(t, archive, "v0.18.12", "v0.18.13"); len(result.Files) != 0 {
+		t.Errorf("expected no changes but got %d", len(result.Files))
+	}
+
+	fsys := txtar_fs.NewTxtarFileSystem(txtar.Parse([]byte(archive)))
+	pf := parsing.NewDefaultParsingFactory(fsys)
+	walker := cwalker.NewSentinelConfigWalker(fsys, "/", "v0.20.0", pf)
+	if _, err := Upgrade(context.Background(), walker, pf, "v0.19.0"); err == nil {
+		t.Error("expected an error when upgrading to an older version")
+	}
+}
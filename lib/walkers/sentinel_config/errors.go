@@ -0,0 +1,36 @@
+package walkers
+
+import (
+	"context"
+	"errors"
+)
+
+var _ error = &CancelledError{}
+
+// CancelledError is returned when a walk was stopped early because its context was
+// cancelled, or its deadline passed. Any files visited before then have already been visited.
+type CancelledError struct {
+	Err error
+}
+
+func (e *CancelledError) Error() string {
+	return "the walk was cancelled: " + e.Err.Error()
+}
+
+func (e *CancelledError) Unwrap() error {
+	return e.Err
+}
+
+// NewCancelledError returns a CancelledError if the context is done, otherwise nil.
+func NewCancelledError(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return &CancelledError{Err: err}
+	}
+	return nil
+}
+
+// IsCancelled returns whether the error was caused by a cancelled walk
+func IsCancelled(err error) bool {
+	var ce *CancelledError
+	return errors.As(err, &ce)
+}
@@ -0,0 +1,178 @@
+package walkers
+
+import (
+	"context"
+	"io/fs"
+	"strings"
+
+	"github.com/glennsarti/sentinel-parser/filetypes"
+	"github.com/glennsarti/sentinel-parser/position"
+	"github.com/glennsarti/sentinel-utils/lib/filesystem"
+	"github.com/glennsarti/sentinel-utils/lib/internal/helpers"
+	"github.com/glennsarti/sentinel-utils/lib/parsing"
+
+	"github.com/glennsarti/sentinel-parser/sentinel_config/ast"
+)
+
+// OrphanedFileType is the file type used when visiting files which are not referenced
+// by the Sentinel configuration
+const OrphanedFileType = filetypes.UnknownFileType
+
+type orphanedFileWalker struct {
+	walker  Walker
+	parsing parsing.Factory
+}
+
+// NewOrphanedFileWalker wraps a walker so that, once the wrapped walker has finished, every
+// Sentinel, HCL and JSON file under the root which could not be reached from the configuration
+// is also visited, with the OrphanedFileType file type.
+func NewOrphanedFileWalker(w Walker, pf parsing.Factory) Walker {
+	return &orphanedFileWalker{
+		walker:  w,
+		parsing: pf,
+	}
+}
+
+func (ow *orphanedFileWalker) SentinelVersion() string {
+	return ow.walker.SentinelVersion()
+}
+
+func (ow *orphanedFileWalker) FileSystem() filesystem.FS {
+	return ow.walker.FileSystem()
+}
+
+func (ow *orphanedFileWalker) Root() string {
+	return ow.walker.Root()
+}
+
+func (ow *orphanedFileWalker) ContinueOnError() bool {
+	return ow.walker.ContinueOnError()
+}
+
+func (ow *orphanedFileWalker) Walk(ctx context.Context, visitor Visitor) error {
+	referenced := make(map[string]struct{}, 0)
+	stopped := false
+
+	err := ow.walker.Walk(ctx, func(fileCtx context.Context, file *filesystem.File, from *position.SourceRange) (bool, error) {
+		referenced[file.Path] = struct{}{}
+		ow.addReferences(fileCtx, file, referenced)
+
+		cont, err := visitor(fileCtx, file, from)
+		stopped = !cont
+		return cont, err
+	})
+	if err != nil || stopped {
+		return err
+	}
+
+	rootDir := ow.Root()
+	if i, err := fs.Stat(ow.FileSystem(), rootDir); err != nil {
+		return err
+	} else if !fs.FileInfoToDirEntry(i).IsDir() {
+		rootDir = ow.FileSystem().ParentPath(rootDir)
+	}
+
+	if _, err = ow.visitOrphans(ctx, rootDir, true, referenced, visitor); err != nil {
+		return err
+	}
+	return NewCancelledError(ctx)
+}
+
+// Files which are only referenced from within configuration files, and are never visited by
+// the configuration walker.
+func (ow *orphanedFileWalker) addReferences(ctx context.Context, file *filesystem.File, referenced map[string]struct{}) {
+	switch file.Type {
+	case filetypes.ConfigPrimaryFileType, filetypes.ConfigOverrideFileType:
+		cfg, _, err := ow.parsing.ParseSentinelConfigFile(ctx, file, ow.SentinelVersion())
+		if err != nil || cfg == nil {
+			return
+		}
+		parentDir := ow.FileSystem().ParentPath(file.Path)
+		for _, key := range helpers.SortedKeys(cfg.Imports) {
+			if imp, ok := cfg.Imports[key].(*ast.V2StaticImport); ok && strings.HasPrefix(imp.Source, "./") {
+				referenced[ow.FileSystem().PathJoin(parentDir, imp.Source[2:])] = struct{}{}
+			}
+		}
+
+	case filetypes.ConfigTestFileType:
+		cfg, _, err := ow.parsing.ParseSentinelConfigFile(ctx, file, ow.SentinelVersion())
+		if err != nil || cfg == nil {
+			return
+		}
+		// Mock modules are relative to the test file
+		parentDir := ow.FileSystem().ParentPath(file.Path)
+		for _, key := range helpers.SortedKeys(cfg.Mocks) {
+			if mock := cfg.Mocks[key]; mock != nil && mock.Module != nil && mock.Module.Source != "" {
+				referenced[ow.FileSystem().PathJoin(parentDir, mock.Module.Source)] = struct{}{}
+			}
+		}
+	}
+}
+
+func (ow *orphanedFileWalker) isCandidate(name string) bool {
+	return strings.HasSuffix(name, ".sentinel") ||
+		strings.HasSuffix(name, ".hcl") ||
+		strings.HasSuffix(name, ".json")
+}
+
+func (ow *orphanedFileWalker) visitOrphans(
+	ctx context.Context,
+	dir string,
+	isRoot bool,
+	referenced map[string]struct{},
+	visitor Visitor,
+) (bool, error) {
+	entries, err := ow.FileSystem().ReadDir(dir)
+	if err != nil {
+		return false, err
+	}
+
+	if !isRoot {
+		// Directories with their own configuration are a different policy set
+		for _, entry := range entries {
+			if !entry.IsDir() && (entry.Name() == defaultConfigHCL || entry.Name() == defaultConfigJSON) {
+				return true, nil
+			}
+		}
+	}
+
+	for _, entry := range entries {
+		// Ignore hidden files and directories e.g. .git
+		if strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		itemPath := ow.FileSystem().PathJoin(dir, entry.Name())
+
+		if entry.IsDir() {
+			if cont, err := ow.visitOrphans(ctx, itemPath, false, referenced, visitor); err != nil || !cont {
+				return cont, err
+			}
+			continue
+		}
+
+		if !ow.isCandidate(entry.Name()) {
+			continue
+		}
+		if _, ok := referenced[itemPath]; ok {
+			continue
+		}
+		// Overrides are only visited on Sentinel versions which support them,
+		// and the root configuration file choice is made by the configuration walker.
+		if isRoot && (IsOverrideFileName(entry.Name()) || entry.Name() == defaultConfigHCL || entry.Name() == defaultConfigJSON) {
+			continue
+		}
+
+		if err := NewCancelledError(ctx); err != nil {
+			return false, err
+		}
+		if cont, err := visitor(ctx, &filesystem.File{
+			Path: itemPath,
+			Name: entry.Name(),
+			Type: OrphanedFileType,
+		}, nil); err != nil || !cont {
+			return cont, err
+		}
+	}
+
+	return true, nil
+}
@@ -1,10 +1,12 @@
 package walkers
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io/fs"
 	"strings"
+	"time"
 
 	"github.com/glennsarti/sentinel-parser/features"
 	"github.com/glennsarti/sentinel-parser/filetypes"
@@ -16,13 +18,36 @@ import (
 	"github.com/glennsarti/sentinel-parser/sentinel_config/ast"
 )
 
-type Visitor func(*filesystem.File, *position.SourceRange) (bool, error)
+// Visitor is called for each file in the walk. The context is cancelled when the walk is
+// cancelled, or the time allowed for the file has passed.
+type Visitor func(context.Context, *filesystem.File, *position.SourceRange) (bool, error)
 
 type Walker interface {
-	Walk(visitor Visitor) error
+	// Walk visits each file in the policy set. If the context is cancelled the walk stops and
+	// a CancelledError is returned.
+	Walk(ctx context.Context, visitor Visitor) error
 	SentinelVersion() string
 	FileSystem() filesystem.FS
 	Root() string
+	// Whether errors in the configuration are skipped, instead of stopping the walk
+	ContinueOnError() bool
+}
+
+type WalkerOption func(*sentinelConfigWalker)
+
+// WithContinueOnError makes the walker skip over errors in the configuration and
+// walk whatever could be recovered from it.
+func WithContinueOnError() WalkerOption {
+	return func(dw *sentinelConfigWalker) {
+		dw.continueOnError = true
+	}
+}
+
+// WithFileTimeout limits how long the visitor can spend on each file
+func WithFileTimeout(timeout time.Duration) WalkerOption {
+	return func(dw *sentinelConfigWalker) {
+		dw.fileTimeout = timeout
+	}
 }
 
 // defaultConfigHCL is the default Sentinel configuration HCL file.
@@ -36,15 +61,20 @@ type sentinelConfigWalker struct {
 	sentinelVersion string
 	fsys            filesystem.FS
 	parsing         parsing.Factory
+	continueOnError bool
+	fileTimeout     time.Duration
 }
 
-func NewSentinelConfigWalker(fsys filesystem.FS, root, sentinelVersion string, pf parsing.Factory) Walker {
+func NewSentinelConfigWalker(fsys filesystem.FS, root, sentinelVersion string, pf parsing.Factory, opts ...WalkerOption) Walker {
 	walker := sentinelConfigWalker{
 		root:            root,
 		fsys:            fsys,
 		sentinelVersion: sentinelVersion,
 		parsing:         pf,
 	}
+	for _, opt := range opts {
+		opt(&walker)
+	}
 
 	return &walker
 }
@@ -66,7 +96,11 @@ func (dw *sentinelConfigWalker) Root() string {
 	return dw.root
 }
 
-func (dw *sentinelConfigWalker) Walk(visitor Visitor) error {
+func (dw *sentinelConfigWalker) ContinueOnError() bool {
+	return dw.continueOnError
+}
+
+func (dw *sentinelConfigWalker) Walk(ctx context.Context, visitor Visitor) error {
 	cfgPath, cfgName, err := dw.getRootConfig()
 	if err != nil {
 		return err
@@ -79,19 +113,50 @@ func (dw *sentinelConfigWalker) Walk(visitor Visitor) error {
 
 	// Order is important.
 	// First visit the root file
-	if cont, err := visitor(rootFile, nil); err != nil || !cont {
+	if cont, err := dw.visit(ctx, visitor, rootFile, nil); err != nil || !cont {
 		return err
 	}
 
 	if features.SupportedVersion(dw.sentinelVersion, features.ConfigurationOverrideMinimumVersion) {
 		// Then visit the overrides
-		if err := dw.visitOverrideFiles(rootFile, visitor); err != nil {
+		if err := dw.visitOverrideFiles(ctx, rootFile, visitor); err != nil {
 			return err
 		}
 	}
 
 	// Then visit items defined in the root file
-	return dw.recurseRootConfig(rootFile, dw.sentinelVersion, visitor)
+	if err := dw.recurseRootConfig(ctx, rootFile, dw.sentinelVersion, visitor); err != nil {
+		return err
+	}
+	return NewCancelledError(ctx)
+}
+
+// Calls the visitor, unless the walk has been cancelled
+func (dw *sentinelConfigWalker) visit(
+	ctx context.Context,
+	visitor Visitor,
+	file *filesystem.File,
+	from *position.SourceRange,
+) (bool, error) {
+	if err := NewCancelledError(ctx); err != nil {
+		return false, err
+	}
+
+	fileCtx := ctx
+	if dw.fileTimeout > 0 {
+		var cancel context.CancelFunc
+		fileCtx, cancel = context.WithTimeout(ctx, dw.fileTimeout)
+		defer cancel()
+	}
+
+	cont, err := visitor(fileCtx, file, from)
+	if err != nil || !cont {
+		// The visitor may have stopped because the walk was cancelled
+		if cErr := NewCancelledError(ctx); cErr != nil {
+			return false, cErr
+		}
+	}
+	return cont, err
 }
 
 // Returns the path and filename of the root configuration file
@@ -124,13 +189,18 @@ func (dw *sentinelConfigWalker) getRootConfig() (string, string, error) {
 }
 
 func (dw *sentinelConfigWalker) isOverride(item fs.DirEntry) bool {
-	return item.Name() == "override.hcl" ||
-		strings.HasSuffix(item.Name(), "_override.hcl") ||
-		item.Name() == "override.json" ||
-		strings.HasSuffix(item.Name(), "_override.json")
+	return IsOverrideFileName(item.Name())
 }
 
-func (dw *sentinelConfigWalker) visitOverrideFiles(rootFile *filesystem.File, visitor Visitor) error {
+// IsOverrideFileName is whether the file name is used for configuration override files
+func IsOverrideFileName(name string) bool {
+	return name == "override.hcl" ||
+		strings.HasSuffix(name, "_override.hcl") ||
+		name == "override.json" ||
+		strings.HasSuffix(name, "_override.json")
+}
+
+func (dw *sentinelConfigWalker) visitOverrideFiles(ctx context.Context, rootFile *filesystem.File, visitor Visitor) error {
 	rootExt := ""
 	if strings.HasSuffix(rootFile.Name, ".hcl") {
 		rootExt = ".hcl"
@@ -144,6 +214,9 @@ func (dw *sentinelConfigWalker) visitOverrideFiles(rootFile *filesystem.File, vi
 
 	items, err := dw.fsys.ReadDir(dw.root)
 	if err != nil {
+		if dw.continueOnError {
+			return nil
+		}
 		return err
 	}
 	for _, item := range items {
@@ -160,23 +233,37 @@ func (dw *sentinelConfigWalker) visitOverrideFiles(rootFile *filesystem.File, vi
 			Name: item.Name(),
 			Type: filetypes.ConfigOverrideFileType,
 		}
-		if cont, err := visitor(override, nil); err != nil || !cont {
+		if cont, err := dw.visit(ctx, visitor, override, nil); err != nil || !cont {
 			return err
 		}
 	}
 	return nil
 }
 
-func (dw *sentinelConfigWalker) recurseRootConfig(rootFile *filesystem.File,
+func (dw *sentinelConfigWalker) recurseRootConfig(
+	ctx context.Context,
+	rootFile *filesystem.File,
 	sentinelVersion string,
 	visitor Visitor,
 ) error {
-	cfg, diags, err := dw.parsing.ParseSentinelConfigFile(rootFile, sentinelVersion)
+	cfg, diags, err := dw.parsing.ParseSentinelConfigFile(ctx, rootFile, sentinelVersion)
 	if err != nil {
+		if cErr := NewCancelledError(ctx); cErr != nil {
+			return cErr
+		}
+		if dw.continueOnError {
+			return nil
+		}
 		return err
 	}
 	if diags.HasErrors() {
-		return diags
+		if !dw.continueOnError {
+			return diags
+		}
+		// Walk whatever the parser could recover
+		if cfg == nil {
+			return nil
+		}
 	}
 	parentDir := dw.fsys.ParentPath(rootFile.Path)
 
@@ -204,7 +291,7 @@ func (dw *sentinelConfigWalker) recurseRootConfig(rootFile *filesystem.File,
 			modSourceRange = actual.SourceRange
 		}
 		if strings.HasPrefix(modSource, "./") {
-			_, cont, err := dw.visitFilePath(&filesystem.File{
+			_, cont, err := dw.visitFilePath(ctx, &filesystem.File{
 				Path: dw.fsys.PathJoin(parentDir, modSource[2:]),
 				Type: filetypes.ModuleFileType,
 				ID:   nodeDocumentID(imp),
@@ -229,7 +316,7 @@ func (dw *sentinelConfigWalker) recurseRootConfig(rootFile *filesystem.File,
 		if strings.HasPrefix(pol.Source, "./") {
 			policyPath := dw.fsys.PathJoin(parentDir, pol.Source[2:])
 
-			policyFile, cont, err := dw.visitFilePath(&filesystem.File{
+			policyFile, cont, err := dw.visitFilePath(ctx, &filesystem.File{
 				Path: policyPath,
 				Type: filetypes.PolicyFileType,
 				ID:   nodeDocumentID(pol),
@@ -241,7 +328,7 @@ func (dw *sentinelConfigWalker) recurseRootConfig(rootFile *filesystem.File,
 				return nil
 			}
 
-			if err := dw.findPolicyTests(pol, policyFile, visitor); err != nil {
+			if err := dw.findPolicyTests(ctx, pol, policyFile, visitor); err != nil {
 				return err
 			}
 		}
@@ -251,22 +338,28 @@ func (dw *sentinelConfigWalker) recurseRootConfig(rootFile *filesystem.File,
 }
 
 func (dw *sentinelConfigWalker) visitFilePath(
+	ctx context.Context,
 	file *filesystem.File,
 	visitFrom *position.SourceRange,
 	visitor Visitor,
 ) (*filesystem.File, bool, error) {
 	file.Name = dw.fsys.BasePath(file.Path)
-	cont, err := visitor(file, visitFrom)
+	cont, err := dw.visit(ctx, visitor, file, visitFrom)
 	return file, cont, err
 }
 
-func (dw *sentinelConfigWalker) findPolicyTests(policy *ast.Policy, policyFile *filesystem.File, visitor Visitor) error {
+func (dw *sentinelConfigWalker) findPolicyTests(
+	ctx context.Context,
+	policy *ast.Policy,
+	policyFile *filesystem.File,
+	visitor Visitor,
+) error {
 	// Get the parent dir of the policyPath
 	parent := dw.fsys.ParentPath(policyFile.Path)
 	// See if <parent>/test/<policy name>/ dir exists
 	testPath := dw.fsys.PathJoin(parent, "test", policy.Name)
 	if _, err := fs.Stat(dw.fsys, testPath); err != nil {
-		if errors.Is(err, fs.ErrNotExist) {
+		if errors.Is(err, fs.ErrNotExist) || dw.continueOnError {
 			return nil
 		} else {
 			return err
@@ -274,6 +367,9 @@ func (dw *sentinelConfigWalker) findPolicyTests(policy *ast.Policy, policyFile *
 	}
 
 	if entries, err := fs.ReadDir(dw.fsys, testPath); err != nil {
+		if dw.continueOnError {
+			return nil
+		}
 		return err
 	} else {
 		for _, entry := range entries {
@@ -283,7 +379,7 @@ func (dw *sentinelConfigWalker) findPolicyTests(policy *ast.Policy, policyFile *
 
 			if strings.HasSuffix(entry.Name(), ".hcl") || strings.HasSuffix(entry.Name(), ".json") {
 				testFilePath := dw.fsys.PathJoin(testPath, entry.Name())
-				if cont, err := visitor(&filesystem.File{
+				if cont, err := dw.visit(ctx, visitor, &filesystem.File{
 					Path: testFilePath,
 					Name: entry.Name(),
 					Type: filetypes.ConfigTestFileType,
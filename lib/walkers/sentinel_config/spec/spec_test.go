@@ -1,6 +1,7 @@
 package spec
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path"
@@ -63,20 +64,37 @@ func testSpecFile(filename, parentPath, sentinelVersion string, t *testing.T) er
 
 	arcfs := txtar_fs.NewTxtarFileSystem(arc.raw)
 	pf := parsing.NewDefaultParsingFactory(arcfs)
-	w := subject.NewSentinelConfigWalker(arcfs, "/", sentinelVersion, pf)
+	opts := make([]subject.WalkerOption, 0)
+	if strings.HasPrefix(filename, "resilient_") {
+		opts = append(opts, subject.WithContinueOnError())
+	}
+	w := subject.NewSentinelConfigWalker(arcfs, "/", sentinelVersion, pf, opts...)
 	if w == nil {
 		return fmt.Errorf("Failed to create walker")
 	}
+	if strings.HasPrefix(filename, "orphans_") {
+		w = subject.NewOrphanedFileWalker(w, pf)
+	}
 
 	visited := make([]string, 0)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 
-	err = w.Walk(
-		func(file *filesystem.File, p *position.SourceRange) (bool, error) {
+	err = w.Walk(ctx,
+		func(_ context.Context, file *filesystem.File, p *position.SourceRange) (bool, error) {
 			visited = append(visited, inspectFile(file, p))
+			if strings.HasPrefix(filename, "cancelled_") {
+				// Cancel the walk after the first file
+				cancel()
+			}
 			return true, nil
 		},
 	)
-	if err != nil {
+	if strings.HasPrefix(filename, "cancelled_") {
+		if !subject.IsCancelled(err) {
+			return fmt.Errorf("expected the walk to be cancelled but got %v", err)
+		}
+	} else if err != nil {
 		return err
 	}
 